@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	decodeName             = "decode"
+	decodeShortDescription = "Decode a customData payload into the files it writes on the node"
+	decodeLongDescription  = "Decodes a customData payload, as a string, a file or an instance metadata service dump, and writes every cloud-init write_files entry under the output directory with its path, permissions and decompressed content. For Windows the customData script and the files of its agent functions archive are written"
+
+	defaultDecodedFilePermissions = 0644
+)
+
+type decodeCmd struct {
+	customData      string
+	inputPath       string
+	outputDirectory string
+}
+
+func newDecodeCmd() *cobra.Command {
+	dc := decodeCmd{}
+
+	decodeCmd := &cobra.Command{
+		Use:   decodeName,
+		Short: decodeShortDescription,
+		Long:  decodeLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dc.validate(); err != nil {
+				return errors.Wrap(err, "validating decodeCmd")
+			}
+			return dc.run()
+		},
+	}
+
+	f := decodeCmd.Flags()
+	f.StringVar(&dc.customData, "custom-data", "", "the customData string, base64 encoded or plain")
+	f.StringVarP(&dc.inputPath, "input", "i", "", "path of a file with the customData or an instance metadata service dump, - reads stdin")
+	f.StringVarP(&dc.outputDirectory, "output-directory", "o", "", "directory the decoded files are written to under their path on the node (required)")
+	return decodeCmd
+}
+
+func (dc *decodeCmd) validate() error {
+	if (dc.customData == "") == (dc.inputPath == "") {
+		return errors.New("exactly one of --custom-data or --input must be specified")
+	}
+	if dc.outputDirectory == "" {
+		return errors.New("--output-directory must be specified")
+	}
+	return nil
+}
+
+func (dc *decodeCmd) run() error {
+	customData, err := dc.readCustomData()
+	if err != nil {
+		return err
+	}
+	files, err := agent.DecodeCustomData(customData)
+	if err != nil {
+		return errors.Wrap(err, "decoding customData")
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dc.outputDirectory, filepath.FromSlash(f.RelativePath()))
+		perm := os.FileMode(defaultDecodedFilePermissions)
+		if f.Permissions != "" {
+			p, err := strconv.ParseUint(f.Permissions, 8, 32)
+			if err != nil {
+				return errors.Wrapf(err, "parsing permissions %s of %s", f.Permissions, f.Path)
+			}
+			perm = os.FileMode(p)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return errors.Wrapf(err, "creating the directory of %s", path)
+		}
+		if err := ioutil.WriteFile(path, f.Content, perm); err != nil {
+			return errors.Wrapf(err, "writing %s", path)
+		}
+		// WriteFile applies the umask, set the permissions cloud-init would
+		if err := os.Chmod(path, perm); err != nil {
+			return errors.Wrapf(err, "setting the permissions of %s", path)
+		}
+		log.Infof("%s %s %s -> %s", f.Path, perm, f.Owner, path)
+	}
+	log.Infof("Decoded %d files into %s", len(files), dc.outputDirectory)
+	return nil
+}
+
+func (dc *decodeCmd) readCustomData() ([]byte, error) {
+	if dc.customData != "" {
+		return []byte(dc.customData), nil
+	}
+	if dc.inputPath == "-" {
+		b, err := ioutil.ReadAll(os.Stdin)
+		return b, errors.Wrap(err, "reading customData from stdin")
+	}
+	b, err := ioutil.ReadFile(dc.inputPath)
+	return b, errors.Wrapf(err, "reading customData from %s", dc.inputPath)
+}
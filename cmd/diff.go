@@ -0,0 +1,346 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	diffName             = "diff"
+	diffShortDescription = "Compare the node bootstrapping output of two inputs"
+	diffLongDescription  = "Renders both inputs, decodes the files written by the customData and the variables of the CSE command, and prints the files and variables that differ for every agent pool. " +
+		"An input is an api model, a node bootstrapping configuration or a directory generated with --output-format plain, which compares the output of two baker versions. " +
+		"Secrets are replaced with fingerprints. Node bootstrapping inputs missing from the api models and flags are set to the same placeholder on both sides. " +
+		"Exits with 2 if the inputs differ"
+)
+
+// diffPlaceholderInputs are used for node bootstrapping inputs that are neither in the api model nor passed as flags,
+// they only need to be valid and the same for both inputs
+var diffPlaceholderInputs = nodeBootstrappingInputs{
+	tenantID:                     "00000000-0000-0000-0000-000000000000",
+	subscriptionID:               "00000000-0000-0000-0000-000000000000",
+	resourceGroupName:            "resourcegroup",
+	userAssignedIdentityClientID: "00000000-0000-0000-0000-000000000000",
+	apiServerFQDN:                "apiserver.fqdn",
+	vnetCIDR:                     "10.0.0.0/8",
+	vnetSubnetID:                 "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/resourcegroup/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet",
+}
+
+type diffCmd struct {
+	beforePath string
+	afterPath  string
+	pools      []string
+
+	nodeBootstrappingInputs
+
+	// derived
+	templateGenerator *agent.TemplateGenerator
+	// certificateProfile holds the certificates generated for the first api model, they are reused for the second
+	// so generated certificates don't show up as changes
+	certificateProfile *api.CertificateProfile
+}
+
+func newDiffCmd() *cobra.Command {
+	dc := diffCmd{}
+
+	diffCmd := &cobra.Command{
+		Use:   diffName + " <before> <after>",
+		Short: diffShortDescription,
+		Long:  diffLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating diffCmd")
+			}
+			differences, err := dc.run()
+			if err != nil {
+				return err
+			}
+			if differences > 0 {
+				return differencesFound(cmd, "%d agent pools differ", differences)
+			}
+			return nil
+		},
+	}
+
+	f := diffCmd.Flags()
+	f.StringSliceVar(&dc.pools, "pool", []string{}, "only compare these agent pools (can specify multiple or separate values with commas: pool1,pool2)")
+	dc.addFlags(f)
+	return diffCmd
+}
+
+func (dc *diffCmd) validate(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		cmd.Usage()
+		return errors.New("diff takes the before and after inputs as arguments")
+	}
+	dc.beforePath, dc.afterPath = args[0], args[1]
+	for _, p := range args {
+		if _, err := os.Stat(p); err != nil {
+			return errors.Wrapf(err, "reading input %s", p)
+		}
+	}
+	return nil
+}
+
+// run prints the differences of the inputs and returns the number of agent pools that differ
+func (dc *diffCmd) run() (int, error) {
+	dc.templateGenerator = agent.InitializeTemplateGenerator()
+	before, err := dc.load(dc.beforePath)
+	if err != nil {
+		return 0, errors.Wrapf(err, "loading %s", dc.beforePath)
+	}
+	after, err := dc.load(dc.afterPath)
+	if err != nil {
+		return 0, errors.Wrapf(err, "loading %s", dc.afterPath)
+	}
+
+	// two single pool inputs are compared whatever the pool names
+	if len(before) == 1 && len(after) == 1 && len(dc.pools) == 0 {
+		beforeName, afterName := dc.poolNames(before, nil)[0], dc.poolNames(after, nil)[0]
+		if beforeName != afterName {
+			log.Infof("Comparing agent pool %s with agent pool %s", beforeName, afterName)
+			after = map[string]*agent.NodeBootstrappingContent{beforeName: after[afterName]}
+		}
+	}
+
+	names := dc.poolNames(before, after)
+	if len(names) == 0 {
+		return 0, errors.New("no agent pools to compare")
+	}
+	differences := 0
+	for _, name := range names {
+		b, inBefore := before[name]
+		a, inAfter := after[name]
+		switch {
+		case !inBefore && !inAfter:
+			return 0, errors.Errorf("--pool %s does not match any agent pool of the inputs", name)
+		case !inBefore:
+			fmt.Printf("agent pool %s: only in %s\n", name, dc.afterPath)
+			differences++
+		case !inAfter:
+			fmt.Printf("agent pool %s: only in %s\n", name, dc.beforePath)
+			differences++
+		default:
+			d := agent.DiffNodeBootstrappingContent(b, a)
+			if d.Empty() {
+				continue
+			}
+			fmt.Printf("agent pool %s:\n%s", name, d)
+			differences++
+		}
+	}
+	if differences == 0 {
+		log.Infof("No differences between %s and %s", dc.beforePath, dc.afterPath)
+	}
+	return differences, nil
+}
+
+// poolNames returns the sorted names of the agent pools to compare
+func (dc *diffCmd) poolNames(before, after map[string]*agent.NodeBootstrappingContent) []string {
+	if len(dc.pools) > 0 {
+		return dc.pools
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, m := range []map[string]*agent.NodeBootstrappingContent{before, after} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// load returns the redacted node bootstrapping content of every agent pool of an input keyed by the pool name
+func (dc *diffCmd) load(path string) (map[string]*agent.NodeBootstrappingContent, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadGeneratedDirectory(path)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	probe := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, errors.Wrap(err, "parsing input")
+	}
+	if _, ok := probe["agentPoolProfile"]; ok {
+		config := &agent.NodeBootstrappingConfiguration{}
+		if err := json.Unmarshal(b, config); err != nil {
+			return nil, errors.Wrap(err, "parsing node bootstrapping configuration")
+		}
+		if err := config.Validate(); err != nil {
+			return nil, errors.Wrap(err, "validating node bootstrapping configuration")
+		}
+		content, err := dc.render(config)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]*agent.NodeBootstrappingContent{config.AgentPoolProfile.Name: content}, nil
+	}
+	if _, ok := probe["properties"]; ok {
+		return dc.loadAPIModel(path)
+	}
+	return nil, errors.New("input is neither an api model nor a node bootstrapping configuration")
+}
+
+func (dc *diffCmd) loadAPIModel(path string) (map[string]*agent.NodeBootstrappingContent, error) {
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{},
+	}
+	cs, _, err := apiloader.LoadContainerServiceFromFile(path, false, false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the api model")
+	}
+	properties := cs.Properties
+	if dc.certificateProfile != nil && (properties.CertificateProfile == nil || properties.CertificateProfile.CaCertificate == "") {
+		properties.CertificateProfile = dc.certificateProfile
+	}
+	if _, err = cs.SetPropertiesDefaults(api.PropertiesDefaultsParams{
+		IsScale:    false,
+		IsUpgrade:  false,
+		PkiKeySize: helpers.DefaultPkiKeySize,
+	}); err != nil {
+		return nil, errors.Wrap(err, "setting api model defaults")
+	}
+	if dc.certificateProfile == nil {
+		dc.certificateProfile = properties.CertificateProfile
+	}
+
+	// each api model fills the inputs it has, the rest get the same placeholders
+	inputs := dc.nodeBootstrappingInputs
+	inputs.fillFromAPIModel(properties)
+	for _, input := range []struct{ value, placeholder *string }{
+		{&inputs.tenantID, &diffPlaceholderInputs.tenantID},
+		{&inputs.subscriptionID, &diffPlaceholderInputs.subscriptionID},
+		{&inputs.resourceGroupName, &diffPlaceholderInputs.resourceGroupName},
+		{&inputs.apiServerFQDN, &diffPlaceholderInputs.apiServerFQDN},
+		{&inputs.vnetCIDR, &diffPlaceholderInputs.vnetCIDR},
+		{&inputs.vnetSubnetID, &diffPlaceholderInputs.vnetSubnetID},
+	} {
+		if *input.value == "" {
+			*input.value = *input.placeholder
+		}
+	}
+	k8sConfig := properties.OrchestratorProfile.KubernetesConfig
+	if inputs.userAssignedIdentityClientID == "" && k8sConfig != nil && k8sConfig.UseManagedIdentity {
+		inputs.userAssignedIdentityClientID = diffPlaceholderInputs.userAssignedIdentityClientID
+	}
+	if err = inputs.apply(properties); err != nil {
+		return nil, err
+	}
+
+	contents := map[string]*agent.NodeBootstrappingContent{}
+	for _, profile := range properties.AgentPoolProfiles {
+		config, err := agent.ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile,
+			inputs.tenantID, inputs.subscriptionID, inputs.resourceGroupName, inputs.userAssignedIdentityClientID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting the api model to a node bootstrapping configuration for agent pool %s", profile.Name)
+		}
+		config.VnetCIDR = inputs.vnetCIDR
+		if contents[profile.Name], err = dc.render(config); err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+func (dc *diffCmd) render(config *agent.NodeBootstrappingConfiguration) (*agent.NodeBootstrappingContent, error) {
+	nodeBootstrapping, err := dc.templateGenerator.GetNodeBootstrapping(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "generating node bootstrapping artifacts for agent pool %s", config.AgentPoolProfile.Name)
+	}
+	content, err := agent.DecodeNodeBootstrapping(nodeBootstrapping)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding node bootstrapping artifacts for agent pool %s", config.AgentPoolProfile.Name)
+	}
+	content.Redact(agent.NewRedactor(agent.GetNodeBootstrappingSecrets(config)...))
+	return content, nil
+}
+
+// loadGeneratedDirectory loads the output of generate --output-format plain, either the directory of an agent pool
+// or the output directory with a subdirectory for every agent pool
+func loadGeneratedDirectory(dir string) (map[string]*agent.NodeBootstrappingContent, error) {
+	contents := map[string]*agent.NodeBootstrappingContent{}
+	if _, err := os.Stat(filepath.Join(dir, "cse.json")); err == nil {
+		content, err := loadGeneratedPool(dir)
+		if err != nil {
+			return nil, err
+		}
+		contents[filepath.Base(filepath.Clean(dir))] = content
+		return contents, nil
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		poolDir := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(poolDir, "cse.json")); !e.IsDir() || err != nil {
+			continue
+		}
+		if contents[e.Name()], err = loadGeneratedPool(poolDir); err != nil {
+			return nil, err
+		}
+	}
+	if len(contents) == 0 {
+		return nil, errors.Errorf("%s has no cse.json, generate it with --output-format plain", dir)
+	}
+	return contents, nil
+}
+
+func loadGeneratedPool(dir string) (*agent.NodeBootstrappingContent, error) {
+	nodeBootstrapping := &agent.NodeBootstrapping{}
+	cse, err := ioutil.ReadFile(filepath.Join(dir, "cse.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(cse, &nodeBootstrapping.CSE); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", filepath.Join(dir, "cse.json"))
+	}
+	for _, name := range []string{"cloud-init.yml", "customdata.ps1"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodeBootstrapping.CustomData = string(b)
+	}
+	if nodeBootstrapping.CustomData == "" {
+		return nil, errors.Errorf("%s has neither cloud-init.yml nor customdata.ps1", dir)
+	}
+
+	content, err := agent.DecodeNodeBootstrapping(nodeBootstrapping)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", dir)
+	}
+	// the configuration is not known, redact the secret variables and their values
+	content.Redact(agent.NewRedactor())
+	return content, nil
+}
@@ -0,0 +1,32 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// ExitCodeDifferences is the exit code of diff and release-notes diff when the compared inputs differ, other errors
+// exit with 1
+const ExitCodeDifferences = 2
+
+// ExitError is returned by a command that ran successfully but must exit with Code, e.g. a diff finding differences
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+// differencesFound returns the ExitError of a comparison finding differences. The command already printed them,
+// so cobra prints neither the error nor the usage
+func differencesFound(cmd *cobra.Command, format string, args ...interface{}) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: ExitCodeDifferences, Message: fmt.Sprintf(format, args...)}
+}
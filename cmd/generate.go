@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"time"
 
 	"github.com/Azure/agentbaker/pkg/agent"
 	"github.com/Azure/aks-engine/pkg/api"
@@ -28,6 +29,13 @@ const (
 	generateLongDescription  = "Generates an Azure Resource Manager template, parameters file and other assets for a cluster"
 )
 
+const (
+	// outputFormatARM writes the customData and CSE command as ARM template expressions
+	outputFormatARM = "arm"
+	// outputFormatPlain writes the raw customData document and the structured CSE command
+	outputFormatPlain = "plain"
+)
+
 type generateCmd struct {
 	apimodelPath      string
 	outputDirectory   string // can be auto-determined from clusterDefinition
@@ -35,8 +43,19 @@ type generateCmd struct {
 	caPrivateKeyPath  string
 	noPrettyPrint     bool
 	parametersOnly    bool
+	outputFormat      string
+	redact            bool
+	protectedSettings bool
+	strict            bool
+	extensionSource   string
+	extensionCacheDir string
+	extensionTimeout  time.Duration
+	extensionChecksum map[string]string
+	pools             []string
 	set               []string
 
+	nodeBootstrappingInputs
+
 	// derived
 	containerService *api.ContainerService
 	apiVersion       string
@@ -86,8 +105,18 @@ func newGenerateCmd() *cobra.Command {
 	f.StringArrayVar(&gc.set, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	f.BoolVar(&gc.noPrettyPrint, "no-pretty-print", false, "skip pretty printing the output")
 	f.BoolVar(&gc.parametersOnly, "parameters-only", false, "only output parameters files")
+	f.StringSliceVar(&gc.pools, "pool", []string{}, "only generate artifacts for these agent pools (can specify multiple or separate values with commas: pool1,pool2)")
+	f.StringVar(&gc.outputFormat, "output-format", outputFormatARM, "format of the node bootstrapping artifacts, one of arm or plain")
+	f.BoolVar(&gc.protectedSettings, "protected-settings", false, "move the secrets of the CSE command to the protectedSettings of cse.json, requires --output-format plain")
+	f.BoolVar(&gc.strict, "strict", false, "fail when a template looks up a parameter, variable or property that does not exist instead of rendering an empty string")
+	f.StringVar(&gc.extensionSource, "extension-source", "", "directory or zip bundle holding extensions/<name>/<version>/<file>, the preprovision extension script is read from it and embedded in the customData so neither generate nor the node downloads it")
+	f.StringVar(&gc.extensionCacheDir, "extension-cache-dir", "", "download the preprovision extension script from the extension rootURL once, cache it in this directory and embed it in the customData, the directory can later be passed to --extension-source")
+	f.DurationVar(&gc.extensionTimeout, "extension-timeout", agent.DefaultExtensionTimeout, "timeout of an extension download with --extension-cache-dir")
+	f.StringToStringVar(&gc.extensionChecksum, "extension-checksum", map[string]string{}, "SHA-256 checksum of a downloaded extension file with --extension-cache-dir (can specify multiple or separate values with commas: <name>/<version>/<file>=<sha256>)")
+	f.BoolVar(&gc.redact, "redact", false, "replace secrets with stable fingerprints in the artifacts and logs, to share them in support tickets")
 	f.StringVar(&gc.rawClientID, "client-id", "", "client id")
 	f.StringVar(&gc.ClientSecret, "client-secret", "", "client secret")
+	gc.addFlags(f)
 	return generateCmd
 }
 
@@ -115,6 +144,18 @@ func (gc *generateCmd) validate(cmd *cobra.Command, args []string) error {
 		return errors.Errorf("specified api model does not exist (%s)", gc.apimodelPath)
 	}
 
+	if gc.outputFormat != outputFormatARM && gc.outputFormat != outputFormatPlain {
+		return errors.Errorf("--output-format must be %s or %s, got %s", outputFormatARM, outputFormatPlain, gc.outputFormat)
+	}
+
+	if gc.protectedSettings && gc.outputFormat != outputFormatPlain {
+		return errors.Errorf("--protected-settings requires --output-format %s", outputFormatPlain)
+	}
+
+	if gc.extensionSource != "" && gc.extensionCacheDir != "" {
+		return errors.New("--extension-source and --extension-cache-dir are mutually exclusive")
+	}
+
 	gc.ClientID, _ = uuid.Parse(gc.rawClientID)
 
 	return nil
@@ -221,29 +262,185 @@ func (gc *generateCmd) run() error {
 		return errors.Wrapf(err, "in SetPropertiesDefaults template %s", gc.apimodelPath)
 	}
 
-	templateGenerator := agent.InitializeTemplateGenerator()
-
-	//extra parameters
-	gc.containerService.Properties.HostedMasterProfile = &api.HostedMasterProfile{
-		FQDN: "abc.aks.com",
+	if err = gc.apply(gc.containerService.Properties); err != nil {
+		return err
 	}
-	gc.containerService.Properties.MasterProfile.VnetCidr = "vnetcidr"
-	gc.containerService.Properties.MasterProfile.VnetSubnetID = "VnetSubnetID"
-	fmt.Printf("Cs%++v", gc.containerService.Properties.MasterProfile)
-	fmt.Printf("Cs%++v", gc.containerService.Properties)
 
-	customDataStr := templateGenerator.GetNodeBootstrappingPayload(gc.containerService, gc.containerService.Properties.AgentPoolProfiles[0])
+	profiles, err := gc.selectAgentPools()
+	if err != nil {
+		return err
+	}
 
-	cseCmdStr := templateGenerator.GetNodeBootstrappingCmd(gc.containerService, gc.containerService.Properties.AgentPoolProfiles[0], "<tenantid>", "<subid>", "rgname", "msiid")
+	if gc.redact {
+		if err = gc.redactSecrets(profiles); err != nil {
+			return err
+		}
+	}
 
+	templateGenerator := agent.InitializeTemplateGenerator()
+	templateGenerator.Strict = gc.strict
+	if templateGenerator.ExtensionSource, err = gc.getExtensionSource(); err != nil {
+		return err
+	}
 	writer := &engine.ArtifactWriter{
 		Translator: &i18n.Translator{
 			Locale: gc.locale,
 		},
 	}
-	if err = writer.WriteTLSArtifacts(gc.containerService, gc.apiVersion, customDataStr, cseCmdStr, gc.outputDirectory, certsGenerated, gc.parametersOnly); err != nil {
-		return errors.Wrap(err, "writing artifacts")
+	for _, profile := range profiles {
+		// every agent pool gets its own subdirectory named after the pool
+		poolDirectory := path.Join(gc.outputDirectory, profile.Name)
+		config, err := agent.ConvertContainerServiceToNodeBootstrappingConfiguration(gc.containerService, profile,
+			gc.tenantID, gc.subscriptionID, gc.resourceGroupName, gc.userAssignedIdentityClientID)
+		if err != nil {
+			return errors.Wrapf(err, "converting the api model to a node bootstrapping configuration for agent pool %s", profile.Name)
+		}
+		config.VnetCIDR = gc.vnetCIDR
+		if err = config.Validate(); err != nil {
+			return errors.Wrapf(err, "validating the node bootstrapping configuration of agent pool %s", profile.Name)
+		}
+
+		if gc.outputFormat == outputFormatPlain {
+			if err = gc.writePlainArtifacts(templateGenerator, config, poolDirectory); err != nil {
+				return errors.Wrapf(err, "agent pool %s", profile.Name)
+			}
+			continue
+		}
+
+		customDataStr, err := templateGenerator.GetNodeBootstrappingPayloadFromConfig(config)
+		if err != nil {
+			return errors.Wrapf(err, "generating node bootstrapping payload for agent pool %s", profile.Name)
+		}
+
+		cseCmdStr, err := templateGenerator.GetNodeBootstrappingCmdFromConfig(config)
+		if err != nil {
+			return errors.Wrapf(err, "generating node bootstrapping command for agent pool %s", profile.Name)
+		}
+
+		if err = writer.WriteTLSArtifacts(gc.containerService, gc.apiVersion, customDataStr, cseCmdStr, poolDirectory, certsGenerated, gc.parametersOnly); err != nil {
+			return errors.Wrapf(err, "writing artifacts for agent pool %s", profile.Name)
+		}
+	}
+
+	return nil
+}
+
+// getExtensionSource returns the extension source of the --extension-source or --extension-cache-dir flag,
+// nil if neither is set
+func (gc *generateCmd) getExtensionSource() (agent.ExtensionSource, error) {
+	if gc.extensionCacheDir != "" {
+		return &agent.HTTPExtensionSource{
+			Timeout:   gc.extensionTimeout,
+			CacheDir:  gc.extensionCacheDir,
+			Checksums: gc.extensionChecksum,
+		}, nil
+	}
+	if gc.extensionSource == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(gc.extensionSource)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading --extension-source")
+	}
+	if info.IsDir() {
+		return &agent.DirExtensionSource{Root: gc.extensionSource}, nil
+	}
+	b, err := ioutil.ReadFile(gc.extensionSource)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading --extension-source")
+	}
+	source, err := agent.NewBundleExtensionSource(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading --extension-source %s", gc.extensionSource)
+	}
+	return source, nil
+}
+
+// redactSecrets replaces the secrets of the api model with their fingerprints and redacts them from the logs
+func (gc *generateCmd) redactSecrets(profiles []*api.AgentPoolProfile) error {
+	redactor := agent.NewRedactor(gc.ClientSecret)
+	for _, profile := range profiles {
+		config, err := agent.ConvertContainerServiceToNodeBootstrappingConfiguration(gc.containerService, profile,
+			gc.tenantID, gc.subscriptionID, gc.resourceGroupName, gc.userAssignedIdentityClientID)
+		if err != nil {
+			return errors.Wrapf(err, "converting the api model to a node bootstrapping configuration for agent pool %s", profile.Name)
+		}
+		for _, secret := range agent.GetNodeBootstrappingSecrets(config) {
+			redactor.AddSecret(secret)
+		}
+	}
+	log.AddHook(redactor)
+	agent.RedactContainerService(gc.containerService)
+	return nil
+}
+
+// selectAgentPools returns the agent pools named by --pool, or all agent pools if --pool was not given
+func (gc *generateCmd) selectAgentPools() ([]*api.AgentPoolProfile, error) {
+	profiles := gc.containerService.Properties.AgentPoolProfiles
+	if len(gc.pools) == 0 {
+		if len(profiles) == 0 {
+			return nil, errors.New("the api model has no agent pools")
+		}
+		return profiles, nil
+	}
+
+	byName := map[string]*api.AgentPoolProfile{}
+	for _, profile := range profiles {
+		byName[profile.Name] = profile
+	}
+	selected := []*api.AgentPoolProfile{}
+	seen := map[string]bool{}
+	for _, name := range gc.pools {
+		profile, ok := byName[name]
+		if !ok {
+			return nil, errors.Errorf("--pool %s does not match any agent pool in the api model", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			selected = append(selected, profile)
+		}
+	}
+	return selected, nil
+}
+
+// writePlainArtifacts writes the customData document, the structured CSE command and their content hash
+// of an agent pool without ARM template expressions
+func (gc *generateCmd) writePlainArtifacts(templateGenerator *agent.TemplateGenerator, config *agent.NodeBootstrappingConfiguration, directory string) error {
+	nodeBootstrapping, err := templateGenerator.GetNodeBootstrapping(config)
+	if err != nil {
+		return errors.Wrap(err, "generating node bootstrapping artifacts")
+	}
+	if gc.protectedSettings {
+		nodeBootstrapping.CSE, err = templateGenerator.GetNodeBootstrappingCmdWithProtectedSettings(config)
+		if err != nil {
+			return errors.Wrap(err, "generating the CSE command with protected settings")
+		}
+		if nodeBootstrapping.ContentHash, err = nodeBootstrapping.Hash(); err != nil {
+			return err
+		}
+	}
+
+	customDataFile := "cloud-init.yml"
+	if config.AgentPoolProfile.IsWindows() {
+		customDataFile = "customdata.ps1"
+	}
+	cseJSON, err := helpers.JSONMarshalIndent(nodeBootstrapping.CSE, "", "  ", false)
+	if err != nil {
+		return errors.Wrap(err, "marshalling the CSE command")
 	}
 
+	if err = os.MkdirAll(directory, 0700); err != nil {
+		return errors.Wrapf(err, "creating output directory %s", directory)
+	}
+	if err = ioutil.WriteFile(path.Join(directory, customDataFile), []byte(nodeBootstrapping.CustomData), 0600); err != nil {
+		return errors.Wrapf(err, "writing %s", customDataFile)
+	}
+	if err = ioutil.WriteFile(path.Join(directory, "cse.json"), cseJSON, 0600); err != nil {
+		return errors.Wrap(err, "writing cse.json")
+	}
+	// identical configurations write an identical content.sha256, compare it to detect changed artifacts
+	if err = ioutil.WriteFile(path.Join(directory, "content.sha256"), []byte(nodeBootstrapping.ContentHash+"\n"), 0600); err != nil {
+		return errors.Wrap(err, "writing content.sha256")
+	}
 	return nil
 }
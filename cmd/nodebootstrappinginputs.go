@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+var vnetSubnetIDRe = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Network/virtualNetworks/[^/]+/subnets/[^/]+$`)
+
+// nodeBootstrappingInputs are the node bootstrapping inputs that are not part of the api model
+type nodeBootstrappingInputs struct {
+	tenantID                     string
+	subscriptionID               string
+	resourceGroupName            string
+	userAssignedIdentityClientID string
+	apiServerFQDN                string
+	vnetCIDR                     string
+	vnetSubnetID                 string
+}
+
+func (in *nodeBootstrappingInputs) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&in.tenantID, "tenant-id", "", "Azure tenant id of the cluster")
+	f.StringVar(&in.subscriptionID, "subscription-id", "", "Azure subscription id of the cluster")
+	f.StringVar(&in.resourceGroupName, "resource-group", "", "resource group of the cluster nodes")
+	f.StringVar(&in.userAssignedIdentityClientID, "user-assigned-identity-id", "", "client id of the user assigned identity, required with managed identity (defaults to kubernetesConfig.userAssignedClientID)")
+	f.StringVar(&in.apiServerFQDN, "apiserver-fqdn", "", "FQDN of the API server (defaults to hostedMasterProfile.fqdn)")
+	f.StringVar(&in.vnetCIDR, "vnet-cidr", "", "CIDR of the cluster VNet (defaults to masterProfile.vnetCidr)")
+	f.StringVar(&in.vnetSubnetID, "vnet-subnet-id", "", "resource id of the agent pool subnet (defaults to agentPoolProfiles[].vnetSubnetID)")
+}
+
+// fillFromAPIModel fills the inputs that have no flag from the api model
+func (in *nodeBootstrappingInputs) fillFromAPIModel(properties *api.Properties) {
+	if in.apiServerFQDN == "" && properties.HostedMasterProfile != nil {
+		in.apiServerFQDN = properties.HostedMasterProfile.FQDN
+	}
+	if in.vnetCIDR == "" && properties.MasterProfile != nil {
+		in.vnetCIDR = properties.MasterProfile.VnetCidr
+	}
+	if in.vnetSubnetID == "" && len(properties.AgentPoolProfiles) > 0 {
+		in.vnetSubnetID = properties.AgentPoolProfiles[0].VnetSubnetID
+	}
+	k8sConfig := properties.OrchestratorProfile.KubernetesConfig
+	if in.userAssignedIdentityClientID == "" && k8sConfig != nil && k8sConfig.UseManagedIdentity {
+		in.userAssignedIdentityClientID = k8sConfig.UserAssignedClientID
+	}
+}
+
+// apply fills the node bootstrapping inputs from the api model where no flag was given,
+// sets them on the api model and fails if any of them is still missing
+func (in *nodeBootstrappingInputs) apply(properties *api.Properties) error {
+	in.fillFromAPIModel(properties)
+	k8sConfig := properties.OrchestratorProfile.KubernetesConfig
+	useManagedIdentity := k8sConfig != nil && k8sConfig.UseManagedIdentity
+
+	var missing []string
+	requireInput := func(flag, value string) {
+		if value == "" {
+			missing = append(missing, flag)
+		}
+	}
+	requireInput("--tenant-id", in.tenantID)
+	requireInput("--subscription-id", in.subscriptionID)
+	requireInput("--resource-group", in.resourceGroupName)
+	requireInput("--apiserver-fqdn", in.apiServerFQDN)
+	requireInput("--vnet-cidr", in.vnetCIDR)
+	requireInput("--vnet-subnet-id", in.vnetSubnetID)
+	if useManagedIdentity {
+		requireInput("--user-assigned-identity-id", in.userAssignedIdentityClientID)
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("missing node bootstrapping inputs, set them in the api model or pass %s", strings.Join(missing, ", "))
+	}
+
+	if _, _, err := net.ParseCIDR(in.vnetCIDR); err != nil {
+		return errors.Wrapf(err, "invalid --vnet-cidr %s", in.vnetCIDR)
+	}
+	if !vnetSubnetIDRe.MatchString(in.vnetSubnetID) {
+		return errors.Errorf("invalid --vnet-subnet-id %s, expected /subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.Network/virtualNetworks/<vnet>/subnets/<subnet>", in.vnetSubnetID)
+	}
+
+	if properties.HostedMasterProfile == nil {
+		properties.HostedMasterProfile = &api.HostedMasterProfile{}
+	}
+	properties.HostedMasterProfile.FQDN = in.apiServerFQDN
+	for _, profile := range properties.AgentPoolProfiles {
+		if profile.VnetSubnetID == "" {
+			profile.VnetSubnetID = in.vnetSubnetID
+		}
+	}
+	return nil
+}
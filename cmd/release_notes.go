@@ -0,0 +1,238 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/agentbaker/pkg/vhd"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	releaseNotesName             = "release-notes"
+	releaseNotesShortDescription = "Work with the release notes of VHD builds"
+	releaseNotesLongDescription  = "Work with the release notes of VHD builds, generated from the inventory of the build and kept in vhdbuilder/release-notes for every published VHD"
+
+	releaseNotesGenerateName             = "generate"
+	releaseNotesGenerateShortDescription = "Generate the release notes of a VHD build from its inventory"
+	releaseNotesGenerateLongDescription  = "Generates the release notes of a Linux or Windows VHD build from the inventory the VHD builder writes on the VM, " +
+		"vhd-inventory.jsonl downloaded by packer, and writes them as release-notes.txt and as release-notes.json, which the publishing step consumes"
+
+	releaseNotesDiffName             = "diff"
+	releaseNotesDiffShortDescription = "Compare the components of two VHD builds"
+	releaseNotesDiffLongDescription  = "Parses the release notes of two VHD builds and prints the components, container images, Kubernetes binaries and kernel added, removed, upgraded or downgraded from the old build to the new one. " +
+		"A component whose newest version did not change but which gained or lost other versions is changed. Exits with 2 if there are changes"
+)
+
+func newReleaseNotesCmd() *cobra.Command {
+	releaseNotesCmd := &cobra.Command{
+		Use:   releaseNotesName,
+		Short: releaseNotesShortDescription,
+		Long:  releaseNotesLongDescription,
+	}
+	releaseNotesCmd.AddCommand(newReleaseNotesGenerateCmd())
+	releaseNotesCmd.AddCommand(newReleaseNotesDiffCmd())
+	return releaseNotesCmd
+}
+
+type releaseNotesGenerateCmd struct {
+	inventoryPath   string
+	outputDirectory string
+}
+
+func newReleaseNotesGenerateCmd() *cobra.Command {
+	rc := releaseNotesGenerateCmd{}
+
+	releaseNotesGenerateCmd := &cobra.Command{
+		Use:   releaseNotesGenerateName + " <inventory>",
+		Short: releaseNotesGenerateShortDescription,
+		Long:  releaseNotesGenerateLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := rc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating releaseNotesGenerateCmd")
+			}
+			return rc.run()
+		},
+	}
+
+	f := releaseNotesGenerateCmd.Flags()
+	f.StringVarP(&rc.outputDirectory, "output-directory", "o", ".", "output directory of release-notes.txt and release-notes.json")
+	return releaseNotesGenerateCmd
+}
+
+func (rc *releaseNotesGenerateCmd) validate(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Usage()
+		return errors.New("release-notes generate takes the inventory as argument")
+	}
+	rc.inventoryPath = args[0]
+	return nil
+}
+
+func (rc *releaseNotesGenerateCmd) run() error {
+	b, err := ioutil.ReadFile(rc.inventoryPath)
+	if err != nil {
+		return errors.Wrap(err, "reading inventory")
+	}
+	r, err := vhd.ReleaseNotesFromInventory(b)
+	if err != nil {
+		return errors.Wrapf(err, "generating release notes from %s", rc.inventoryPath)
+	}
+	data, err := helpers.JSONMarshalIndent(r, "", "  ", false)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(rc.outputDirectory, 0755); err != nil {
+		return errors.Wrap(err, "creating output directory")
+	}
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{
+		{"release-notes.txt", r.Text()},
+		{"release-notes.json", data},
+	} {
+		path := filepath.Join(rc.outputDirectory, file.name)
+		if err := ioutil.WriteFile(path, file.content, 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", path)
+		}
+		log.Infof("wrote %s", path)
+	}
+	return nil
+}
+
+type releaseNotesDiffCmd struct {
+	oldPath string
+	newPath string
+	output  string
+}
+
+func newReleaseNotesDiffCmd() *cobra.Command {
+	rc := releaseNotesDiffCmd{}
+
+	releaseNotesDiffCmd := &cobra.Command{
+		Use:   releaseNotesDiffName + " <old> <new>",
+		Short: releaseNotesDiffShortDescription,
+		Long:  releaseNotesDiffLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := rc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating releaseNotesDiffCmd")
+			}
+			changes, err := rc.run()
+			if err != nil {
+				return err
+			}
+			if changes > 0 {
+				return differencesFound(cmd, "%d changes between %s and %s", changes, rc.oldPath, rc.newPath)
+			}
+			return nil
+		},
+	}
+
+	f := releaseNotesDiffCmd.Flags()
+	f.StringVarP(&rc.output, "output", "o", "human", "output format, human or json")
+	return releaseNotesDiffCmd
+}
+
+func (rc *releaseNotesDiffCmd) validate(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		cmd.Usage()
+		return errors.New("release-notes diff takes the old and the new release notes as arguments")
+	}
+	rc.oldPath, rc.newPath = args[0], args[1]
+	if rc.output != "human" && rc.output != "json" {
+		return errors.Errorf(`output format "%s" is not supported`, rc.output)
+	}
+	return nil
+}
+
+// run prints the changes between the release notes and returns their number
+func (rc *releaseNotesDiffCmd) run() (int, error) {
+	from, err := loadReleaseNotes(rc.oldPath)
+	if err != nil {
+		return 0, err
+	}
+	to, err := loadReleaseNotes(rc.newPath)
+	if err != nil {
+		return 0, err
+	}
+	changes := vhd.DiffReleaseNotes(from, to)
+
+	if rc.output == "json" {
+		data, err := helpers.JSONMarshalIndent(changes, "", "  ", false)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Println(string(data))
+		return len(changes), nil
+	}
+
+	fmt.Printf("%s -> %s\n", releaseNotesBuild(from), releaseNotesBuild(to))
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+		return 0, nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tKIND\tNAME\tOLD\tNEW")
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Type, c.Kind, c.Name, releaseNotesVersions(c.OldVersions), releaseNotesVersions(c.NewVersions))
+	}
+	return len(changes), w.Flush()
+}
+
+// loadReleaseNotes reads the text or the JSON of release notes
+func loadReleaseNotes(path string) (*vhd.ReleaseNotes, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading release notes")
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		r := &vhd.ReleaseNotes{}
+		if err := json.Unmarshal(b, r); err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", path)
+		}
+		return r, nil
+	}
+	r, err := vhd.ParseReleaseNotes(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", path)
+	}
+	return r, nil
+}
+
+// releaseNotesBuild returns the build number and the date of a VHD build
+func releaseNotesBuild(r *vhd.ReleaseNotes) string {
+	date := r.BuildStarted
+	if date.IsZero() {
+		date = r.BuildCompleted
+	}
+	return fmt.Sprintf("%s (%s)", r.BuildNumber, date.Format("2006-01-02"))
+}
+
+// releaseNotesVersions returns the versions of a component, - if it has none and unpinned for a component without a pinned version
+func releaseNotesVersions(versions []string) string {
+	if len(versions) == 0 {
+		return "-"
+	}
+	formatted := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if v == "" {
+			v = "unpinned"
+		}
+		formatted = append(formatted, v)
+	}
+	return strings.Join(formatted, ", ")
+}
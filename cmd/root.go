@@ -64,6 +64,13 @@ func NewRootCmd() *cobra.Command {
 
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newGenerateCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newDecodeCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newValidateCmd())
+	rootCmd.AddCommand(newVHDCheckCmd())
+	rootCmd.AddCommand(newVHDCmd())
+	rootCmd.AddCommand(newReleaseNotesCmd())
 	rootCmd.AddCommand(newGetVersionsCmd())
 	rootCmd.AddCommand(newOrchestratorsCmd())
 	rootCmd.AddCommand(getCompletionCmd(rootCmd))
@@ -0,0 +1,84 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Azure/agentbaker/pkg/server"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	serveName             = "serve"
+	serveShortDescription = "Serve node bootstrapping generation over HTTP"
+	serveLongDescription  = "Runs an HTTP server returning the customData payload, the CSE command and the rendered parameters and variables for a node bootstrapping configuration"
+)
+
+type serveCmd struct {
+	address         string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	shutdownTimeout time.Duration
+}
+
+func newServeCmd() *cobra.Command {
+	sc := serveCmd{}
+
+	serveCmd := &cobra.Command{
+		Use:   serveName,
+		Short: serveShortDescription,
+		Long:  serveLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sc.run()
+		},
+	}
+
+	f := serveCmd.Flags()
+	f.StringVar(&sc.address, "address", ":8080", "address to listen on")
+	f.DurationVar(&sc.readTimeout, "read-timeout", 30*time.Second, "maximum duration for reading a request")
+	f.DurationVar(&sc.writeTimeout, "write-timeout", 60*time.Second, "maximum duration for writing a response")
+	f.DurationVar(&sc.shutdownTimeout, "shutdown-timeout", 10*time.Second, "maximum duration to wait for in-flight requests on shutdown")
+	return serveCmd
+}
+
+func (sc *serveCmd) run() error {
+	srv := &http.Server{
+		Addr:         sc.address,
+		Handler:      server.NewServer(),
+		ReadTimeout:  sc.readTimeout,
+		WriteTimeout: sc.writeTimeout,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Infof("Serving node bootstrapping on %s", sc.address)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.Wrap(err, "serving node bootstrapping")
+	case sig := <-stop:
+		log.Infof("Received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sc.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return errors.Wrap(err, "shutting down the server")
+	}
+	return nil
+}
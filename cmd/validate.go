@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	validateName             = "validate"
+	validateShortDescription = "Validate an input against the node bootstrapping requirements"
+	validateLongDescription  = "Checks that an api model or a node bootstrapping configuration holds everything the node bootstrapping templates assume and prints every invalid field. " +
+		"An api model is validated after setting its defaults, once for every agent pool"
+)
+
+type validateCmd struct {
+	inputPath string
+	pools     []string
+}
+
+func newValidateCmd() *cobra.Command {
+	vc := validateCmd{}
+
+	validateCmd := &cobra.Command{
+		Use:   validateName + " <input>",
+		Short: validateShortDescription,
+		Long:  validateLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := vc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating validateCmd")
+			}
+			return vc.run()
+		},
+	}
+
+	f := validateCmd.Flags()
+	f.StringSliceVar(&vc.pools, "pool", []string{}, "only validate these agent pools (can specify multiple or separate values with commas: pool1,pool2)")
+	return validateCmd
+}
+
+func (vc *validateCmd) validate(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Usage()
+		return errors.New("validate takes the input as argument")
+	}
+	vc.inputPath = args[0]
+	if _, err := os.Stat(vc.inputPath); err != nil {
+		return errors.Wrapf(err, "reading input %s", vc.inputPath)
+	}
+	return nil
+}
+
+func (vc *validateCmd) run() error {
+	configs, err := loadNodeBootstrappingConfigurations(vc.inputPath)
+	if err != nil {
+		return errors.Wrapf(err, "loading %s", vc.inputPath)
+	}
+
+	names := vc.pools
+	if len(names) == 0 {
+		for name := range configs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	invalid := 0
+	for _, name := range names {
+		config, ok := configs[name]
+		if !ok {
+			return errors.Errorf("--pool %s does not match any agent pool of the input", name)
+		}
+		err := config.Validate()
+		if err == nil {
+			continue
+		}
+		invalid++
+		errs, ok := err.(agent.ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, e := range errs {
+			fmt.Printf("agent pool %s: %s\n", name, e)
+		}
+	}
+	if invalid > 0 {
+		return errors.Errorf("%d of %d agent pools are invalid", invalid, len(names))
+	}
+	log.Infof("%s is valid", vc.inputPath)
+	return nil
+}
+
+// loadNodeBootstrappingConfigurations returns the node bootstrapping configuration of every agent pool of an api model
+// or of a node bootstrapping configuration keyed by the pool name. An api model gets its defaults set first
+func loadNodeBootstrappingConfigurations(inputPath string) (map[string]*agent.NodeBootstrappingConfiguration, error) {
+	b, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	probe := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, errors.Wrap(err, "parsing input")
+	}
+	if _, ok := probe["agentPoolProfile"]; ok {
+		config := &agent.NodeBootstrappingConfiguration{}
+		if err := json.Unmarshal(b, config); err != nil {
+			return nil, errors.Wrap(err, "parsing node bootstrapping configuration")
+		}
+		name := ""
+		if config.AgentPoolProfile != nil {
+			name = config.AgentPoolProfile.Name
+		}
+		return map[string]*agent.NodeBootstrappingConfiguration{name: config}, nil
+	}
+	if _, ok := probe["properties"]; !ok {
+		return nil, errors.New("input is neither an api model nor a node bootstrapping configuration")
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{},
+	}
+	cs, _, err := apiloader.LoadContainerServiceFromFile(inputPath, false, false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the api model")
+	}
+	if _, err = cs.SetPropertiesDefaults(api.PropertiesDefaultsParams{
+		IsScale:    false,
+		IsUpgrade:  false,
+		PkiKeySize: helpers.DefaultPkiKeySize,
+	}); err != nil {
+		return nil, errors.Wrap(err, "setting api model defaults")
+	}
+	configs := map[string]*agent.NodeBootstrappingConfiguration{}
+	for _, profile := range cs.Properties.AgentPoolProfiles {
+		// the identity inputs are not validated, they are not part of the api model
+		config, err := agent.ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile, "", "", "", "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting the api model to a node bootstrapping configuration for agent pool %s", profile.Name)
+		}
+		configs[profile.Name] = config
+	}
+	return configs, nil
+}
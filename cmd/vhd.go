@@ -0,0 +1,124 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/agentbaker/pkg/vhd"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	vhdName             = "vhd"
+	vhdShortDescription = "Work with the builds of VHDs"
+	vhdLongDescription  = "Work with the builds of the VHDs agent pools boot from, described by the build specs in vhdbuilder/packer"
+
+	vhdTemplateName             = "template"
+	vhdTemplateShortDescription = "Render the packer template of a VHD build spec"
+	vhdTemplateLongDescription  = "Validates a VHD build spec, e.g. vhdbuilder/packer/vhd-build-spec.yaml, and renders the packer template building it. " +
+		"The OS SKU, Ubuntu release, Hyper-V generation and storage of the spec can be overridden, the files the template uploads must exist relative to the repository root"
+)
+
+func newVHDCmd() *cobra.Command {
+	vhdCmd := &cobra.Command{
+		Use:   vhdName,
+		Short: vhdShortDescription,
+		Long:  vhdLongDescription,
+	}
+	vhdCmd.AddCommand(newVHDTemplateCmd())
+	return vhdCmd
+}
+
+type vhdTemplateCmd struct {
+	specPath         string
+	outputPath       string
+	repositoryRoot   string
+	ubuntuRelease    string
+	hyperVGeneration string
+	storage          string
+
+	spec *vhd.BuildSpec
+}
+
+func newVHDTemplateCmd() *cobra.Command {
+	vc := vhdTemplateCmd{}
+
+	vhdTemplateCmd := &cobra.Command{
+		Use:   vhdTemplateName + " <spec>",
+		Short: vhdTemplateShortDescription,
+		Long:  vhdTemplateLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := vc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating vhdTemplateCmd")
+			}
+			return vc.run()
+		},
+	}
+
+	f := vhdTemplateCmd.Flags()
+	f.StringVarP(&vc.outputPath, "output-file", "o", "", "file to write the packer template to (default stdout)")
+	f.StringVar(&vc.repositoryRoot, "repository-root", ".", "root of the repository the sources of the files are relative to")
+	f.StringVar(&vc.ubuntuRelease, "ubuntu-release", "", "override the Ubuntu release of the spec, 16.04 or 18.04")
+	f.StringVar(&vc.hyperVGeneration, "hyperv-generation", "", "override the Hyper-V generation of the spec, V1 or V2")
+	f.StringVar(&vc.storage, "storage", "", "override the storage of the spec, classic or sig")
+	return vhdTemplateCmd
+}
+
+func (vc *vhdTemplateCmd) validate(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Usage()
+		return errors.New("vhd template takes the build spec as argument")
+	}
+	vc.specPath = args[0]
+
+	spec, err := vhd.LoadBuildSpec(vc.specPath)
+	if err != nil {
+		return err
+	}
+	if vc.ubuntuRelease != "" {
+		spec.UbuntuRelease = vc.ubuntuRelease
+	}
+	if vc.hyperVGeneration != "" {
+		spec.HyperVGeneration = vhd.HyperVGeneration(vc.hyperVGeneration)
+	}
+	if vc.storage != "" {
+		spec.Storage = vhd.Storage(vc.storage)
+	}
+	if err := spec.Validate(); err != nil {
+		return errors.Wrapf(err, "validating %s", vc.specPath)
+	}
+	vc.spec = spec
+	return nil
+}
+
+func (vc *vhdTemplateCmd) run() error {
+	t, err := vc.spec.PackerTemplate()
+	if err != nil {
+		return err
+	}
+	for _, source := range t.Sources() {
+		if _, err := os.Stat(filepath.Join(vc.repositoryRoot, filepath.FromSlash(source))); err != nil {
+			return errors.Wrapf(err, "packer template uploads %s", source)
+		}
+	}
+
+	data, err := vhd.RenderPackerTemplate(vc.spec)
+	if err != nil {
+		return err
+	}
+	if vc.outputPath == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(vc.outputPath, data, 0644); err != nil {
+		return errors.Wrapf(err, "writing %s", vc.outputPath)
+	}
+	log.Infof("wrote %s", vc.outputPath)
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/Azure/agentbaker/pkg/vhd"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	vhdCheckName             = "vhd-check"
+	vhdCheckShortDescription = "List the binaries and images a node downloads because its VHD does not have them"
+	vhdCheckLongDescription  = "Renders the node bootstrapping of every Linux agent pool of an api model or a node bootstrapping configuration and looks up every binary and container image its CSE fetches " +
+		"in the components of a VHD, from its component manifest or its release notes. A cache miss costs minutes per node and fails in clusters with restricted egress"
+)
+
+type vhdCheckCmd struct {
+	inputPath        string
+	manifestPath     string
+	releaseNotesPath string
+	pools            []string
+	output           string
+	failOnMiss       bool
+}
+
+func newVHDCheckCmd() *cobra.Command {
+	vc := vhdCheckCmd{}
+
+	vhdCheckCmd := &cobra.Command{
+		Use:   vhdCheckName + " <input>",
+		Short: vhdCheckShortDescription,
+		Long:  vhdCheckLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := vc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating vhdCheckCmd")
+			}
+			return vc.run()
+		},
+	}
+
+	f := vhdCheckCmd.Flags()
+	f.StringVar(&vc.manifestPath, "manifest", "", "the component manifest of the VHD, e.g. vhdbuilder/packer/components.json")
+	f.StringVar(&vc.releaseNotesPath, "release-notes", "", "the release notes of the VHD, e.g. vhdbuilder/release-notes/AKSUbuntu/1804/2020.03.24.txt")
+	f.StringSliceVar(&vc.pools, "pool", []string{}, "only check these agent pools (can specify multiple or separate values with commas: pool1,pool2)")
+	f.StringVarP(&vc.output, "output", "o", "human", "output format, human or json")
+	f.BoolVar(&vc.failOnMiss, "fail-on-miss", false, "exit with an error if an agent pool has a cache miss")
+	return vhdCheckCmd
+}
+
+func (vc *vhdCheckCmd) validate(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Usage()
+		return errors.New("vhd-check takes the input as argument")
+	}
+	vc.inputPath = args[0]
+	if (vc.manifestPath == "") == (vc.releaseNotesPath == "") {
+		return errors.New("exactly one of --manifest and --release-notes must be set")
+	}
+	if vc.output != "human" && vc.output != "json" {
+		return errors.Errorf(`output format "%s" is not supported`, vc.output)
+	}
+	return nil
+}
+
+func (vc *vhdCheckCmd) run() error {
+	manifest, err := vc.loadManifest()
+	if err != nil {
+		return err
+	}
+	configs, err := loadNodeBootstrappingConfigurations(vc.inputPath)
+	if err != nil {
+		return errors.Wrapf(err, "loading %s", vc.inputPath)
+	}
+
+	names := vc.pools
+	if len(names) == 0 {
+		for name := range configs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	templateGenerator := agent.InitializeTemplateGenerator()
+	analyses := map[string]*agent.VHDCacheAnalysis{}
+	misses := 0
+	for _, name := range names {
+		config, ok := configs[name]
+		if !ok {
+			return errors.Errorf("--pool %s does not match any agent pool of the input", name)
+		}
+		if err := config.Validate(); err != nil {
+			return errors.Wrapf(err, "validating agent pool %s", name)
+		}
+		if config.AgentPoolProfile.IsWindows() {
+			log.Warnf("agent pool %s: skipping, the VHD cache analysis only supports Linux agent pools", name)
+			continue
+		}
+		analysis, err := templateGenerator.GetVHDCacheAnalysis(config, manifest)
+		if err != nil {
+			return errors.Wrapf(err, "analyzing agent pool %s", name)
+		}
+		if !analysis.VHD {
+			log.Warnf("agent pool %s: distro %s is not a VHD distro, the node downloads every artifact", name, config.AgentPoolProfile.Distro)
+		} else if len(analysis.Misses) > 0 {
+			log.Warnf("agent pool %s: the node downloads %d artifacts the VHD does not have", name, len(analysis.Misses))
+		}
+		misses += len(analysis.Misses)
+		analyses[name] = analysis
+	}
+
+	if vc.output == "json" {
+		data, err := helpers.JSONMarshalIndent(analyses, "", "  ", false)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "POOL\tCACHE\tKIND\tNAME\tARTIFACT")
+		for _, name := range names {
+			analysis, ok := analyses[name]
+			if !ok {
+				continue
+			}
+			for _, artifacts := range [][]*agent.VHDArtifact{analysis.Hits, analysis.Misses} {
+				for _, a := range artifacts {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, cacheResult(a), a.Kind, a.Name, artifactDescription(a))
+				}
+			}
+		}
+		w.Flush()
+	}
+
+	if vc.failOnMiss && misses > 0 {
+		return errors.Errorf("%d cache misses", misses)
+	}
+	return nil
+}
+
+func (vc *vhdCheckCmd) loadManifest() (*vhd.Manifest, error) {
+	if vc.manifestPath != "" {
+		manifest, err := vhd.LoadManifest(vc.manifestPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading %s", vc.manifestPath)
+		}
+		return manifest, nil
+	}
+	b, err := ioutil.ReadFile(vc.releaseNotesPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading --release-notes")
+	}
+	manifest, err := vhd.ManifestFromReleaseNotes(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading %s", vc.releaseNotesPath)
+	}
+	return manifest, nil
+}
+
+func cacheResult(a *agent.VHDArtifact) string {
+	if a.Cached {
+		return "hit"
+	}
+	return "miss"
+}
+
+// artifactDescription returns the URL of a file or an image and the version of a package or the Kubernetes binaries
+func artifactDescription(a *agent.VHDArtifact) string {
+	switch a.Kind {
+	case agent.VHDArtifactPackage, agent.VHDArtifactKubernetesBinaries:
+		return a.Version
+	default:
+		return a.URL
+	}
+}
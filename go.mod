@@ -16,4 +16,5 @@ require (
 	github.com/spf13/cobra v0.0.3
 	github.com/spf13/pflag v1.0.3
 	gopkg.in/ini.v1 v1.41.0
+	gopkg.in/yaml.v2 v2.2.1
 )
@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// cseexitcodes generates the Go table of the custom script extension exit codes from the
+// ERR_* variables of cse_helpers.sh. It fails if two exit codes share a number, including
+// numbers of deprecated exit codes, or if an exit code matches none of the categories.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ERR_KUBELET_START_FAIL=34 {{/* kubelet could not be started by systemctl */}}
+	exitCodeRe = regexp.MustCompile(`^(ERR_[A-Z0-9_]+)=(\d+)\s*\{\{/\*\s*(.*?)\s*\*/\}\}`)
+	// {{/* ERR_SYSTEMCTL_ENABLE_FAIL=3 Service could not be enabled by systemctl -- DEPRECATED */}}
+	deprecatedExitCodeRe = regexp.MustCompile(`^\{\{/\*\s*(ERR_[A-Z0-9_]+)=(\d+)\s.*DEPRECATED\s*\*/\}\}`)
+)
+
+// categories are matched in order, the first match wins
+var categories = []struct {
+	re       *regexp.Regexp
+	category string
+}{
+	{regexp.MustCompile(`^ERR_AZURE_STACK_`), "CSEExitCodeCategoryAzureStack"},
+	{regexp.MustCompile(`^ERR_(GPU|SGX)_`), "CSEExitCodeCategoryGPU"},
+	{regexp.MustCompile(`^ERR_(OUTBOUND_CONN_FAIL|CUSTOM_SEARCH_DOMAINS_FAIL|CONTAINER_IMG_PULL_TIMEOUT|KEYVAULT_SECRET_FETCH_FAIL)$|_DOWNLOAD_TIMEOUT$`), "CSEExitCodeCategoryNetwork"},
+	{regexp.MustCompile(`^ERR_(APT|MOBY|MS_PROD_DEB|HOLD_WALINUXAGENT|RELEASE_HOLD_WALINUXAGENT|SYSTEMD_INSTALL)(_|$)|_INSTALL_TIMEOUT$|_APT_KEY_TIMEOUT$`), "CSEExitCodeCategoryPackageInstall"},
+	{regexp.MustCompile(`^ERR_(SYSTEMCTL|DOCKER_START|KUBELET|ETCD|K8S|KUBECTL|MODPROBE|SYSCTL|CLOUD_INIT|FILE_WATCH|CSE_PROVISION|PROTECTED_SETTINGS)_`), "CSEExitCodeCategoryRuntime"},
+	{regexp.MustCompile(`^ERR_(CIS|PACKER|VHD)_`), "CSEExitCodeCategoryOther"},
+}
+
+// initialisms keeps the case of these words in Go identifiers
+var initialisms = map[string]string{
+	"ARM": "ARM",
+	"CIS": "CIS",
+	"CNI": "CNI",
+	"CSE": "CSE",
+	"GPG": "GPG",
+	"GPU": "GPU",
+	"K8S": "K8s",
+	"MS":  "MS",
+	"PW":  "PW",
+	"SGX": "SGX",
+	"VHD": "VHD",
+}
+
+type exitCode struct {
+	name        string
+	goName      string
+	code        int
+	description string
+	category    string
+}
+
+func main() {
+	input := flag.String("input", "", "path of cse_helpers.sh")
+	output := flag.String("output", "", "path of the generated Go file")
+	pkg := flag.String("package", "agent", "package of the generated Go file")
+	flag.Parse()
+
+	if err := run(*input, *output, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "cseexitcodes: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, output, pkg string) error {
+	b, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	codes, err := parse(string(b))
+	if err != nil {
+		return fmt.Errorf("%s: %v", input, err)
+	}
+	src, err := generate(pkg, codes)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, src, 0644)
+}
+
+func parse(helpers string) ([]exitCode, error) {
+	codes := []exitCode{}
+	used := map[int]string{}
+	for i, line := range strings.Split(helpers, "\n") {
+		line = strings.TrimSpace(line)
+		deprecated := false
+		m := exitCodeRe.FindStringSubmatch(line)
+		if m == nil {
+			m = deprecatedExitCodeRe.FindStringSubmatch(line)
+			deprecated = true
+		}
+		if m == nil {
+			continue
+		}
+		name := m[1]
+		code, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if other, ok := used[code]; ok {
+			return nil, fmt.Errorf("line %d: %s reuses exit code %d of %s", i+1, name, code, other)
+		}
+		used[code] = name
+		if deprecated {
+			continue
+		}
+
+		category := ""
+		for _, c := range categories {
+			if c.re.MatchString(name) {
+				category = c.category
+				break
+			}
+		}
+		if category == "" {
+			return nil, fmt.Errorf("line %d: %s matches no exit code category", i+1, name)
+		}
+		codes = append(codes, exitCode{
+			name:        name,
+			goName:      goName(name),
+			code:        code,
+			description: description(m[3]),
+			category:    category,
+		})
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no exit codes found")
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].code < codes[j].code })
+	return codes, nil
+}
+
+// goName converts ERR_OUTBOUND_CONN_FAIL to CSEExitCodeOutboundConnFail
+func goName(name string) string {
+	var sb strings.Builder
+	sb.WriteString("CSEExitCode")
+	for _, word := range strings.Split(strings.TrimPrefix(name, "ERR_"), "_") {
+		if s, ok := initialisms[word]; ok {
+			sb.WriteString(s)
+			continue
+		}
+		sb.WriteString(word[:1] + strings.ToLower(word[1:]))
+	}
+	return sb.String()
+}
+
+// description lower cases the first word of the shell comment unless it is an acronym or a proper name
+func description(comment string) string {
+	word := strings.Fields(comment)[0]
+	for _, r := range word[1:] {
+		if unicode.IsUpper(r) {
+			return comment
+		}
+	}
+	return strings.ToLower(comment[:1]) + comment[1:]
+}
+
+func generate(pkg string, codes []exitCode) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by hack/cseexitcodes from cse_helpers.sh. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "const (\n")
+	for _, c := range codes {
+		fmt.Fprintf(&buf, "\t// %s is %s, %s\n", c.goName, c.name, c.description)
+		fmt.Fprintf(&buf, "\t%s CSEExitCode = %d\n", c.goName, c.code)
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+	fmt.Fprintf(&buf, "var cseExitCodes = []CSEExitCodeInfo{\n")
+	for _, c := range codes {
+		fmt.Fprintf(&buf, "\t{Name: %q, Code: %s, Description: %q, Category: %s},\n", c.name, c.goName, c.description, c.category)
+	}
+	fmt.Fprintf(&buf, "}\n")
+	return format.Source(buf.Bytes())
+}
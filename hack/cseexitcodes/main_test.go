@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	codes, err := parse(`
+ERR_OUTBOUND_CONN_FAIL=50 {{/* Unable to establish outbound connection */}}
+ERR_KUBELET_START_FAIL=34 {{/* kubelet could not be started by systemctl */}}
+{{/* ERR_SYSTEMCTL_ENABLE_FAIL=3 Service could not be enabled by systemctl -- DEPRECATED */}}
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != 2 || codes[0].goName != "CSEExitCodeKubeletStartFail" || codes[1].category != "CSEExitCodeCategoryNetwork" {
+		t.Errorf("unexpected exit codes %+v", codes)
+	}
+	if codes[1].description != "unable to establish outbound connection" {
+		t.Errorf("unexpected description %q", codes[1].description)
+	}
+}
+
+func TestParseDuplicateExitCode(t *testing.T) {
+	for name, c := range map[string]struct {
+		helpers  string
+		expected string
+	}{
+		"duplicate": {
+			helpers: `ERR_KUBELET_START_FAIL=34 {{/* kubelet could not be started by systemctl */}}
+ERR_DOCKER_START_FAIL=34 {{/* docker could not be started by systemctl */}}`,
+			expected: "line 2: ERR_DOCKER_START_FAIL reuses exit code 34 of ERR_KUBELET_START_FAIL",
+		},
+		"reused deprecated": {
+			helpers: `{{/* ERR_SYSTEMCTL_ENABLE_FAIL=3 Service could not be enabled by systemctl -- DEPRECATED */}}
+ERR_SYSTEMCTL_START_FAIL=3 {{/* Service could not be started or enabled by systemctl */}}`,
+			expected: "line 2: ERR_SYSTEMCTL_START_FAIL reuses exit code 3 of ERR_SYSTEMCTL_ENABLE_FAIL",
+		},
+		"no category": {
+			helpers:  `ERR_SOMETHING_ELSE=200 {{/* something else */}}`,
+			expected: "matches no exit code category",
+		},
+	} {
+		if _, err := parse(c.helpers); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, c.expected, err)
+		}
+	}
+}
@@ -15,6 +15,9 @@ func main() {
 	log.SetFormatter(&log.TextFormatter{ForceColors: true})
 	log.SetOutput(colorable.NewColorableStdout())
 	if err := cmd.NewRootCmd().Execute(); err != nil {
+		if e, ok := err.(*cmd.ExitError); ok {
+			os.Exit(e.Code)
+		}
 		os.Exit(1)
 	}
 }
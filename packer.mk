@@ -1,8 +1,14 @@
-build-packer:
-	@packer build -var-file=vhdbuilder/packer/settings.json vhdbuilder/packer/vhd-image-builder.json
+build-packer: generate-packer-template
+	@packer build -var-file=vhdbuilder/packer/settings.json packer-template.json
 
-build-packer-windows:
-	@packer build -var-file=vhdbuilder/packer/settings.json vhdbuilder/packer/windows-vhd-builder.json
+build-packer-windows: generate-packer-template-windows
+	@packer build -var-file=vhdbuilder/packer/settings.json packer-template.json
+
+generate-packer-template:
+	@go run -mod=vendor . vhd template vhdbuilder/packer/vhd-build-spec.yaml --ubuntu-release=${UBUNTU_SKU} -o packer-template.json
+
+generate-packer-template-windows:
+	@go run -mod=vendor . vhd template vhdbuilder/packer/windows-vhd-build-spec.yaml -o packer-template.json
 
 init-packer:
 	@./vhdbuilder/packer/init-variables.sh
@@ -10,11 +16,14 @@ init-packer:
 az-login:
 	az login --service-principal -u ${CLIENT_ID} -p ${CLIENT_SECRET} --tenant ${TENANT_ID}
 
+generate-release-notes:
+	@go run -mod=vendor . release-notes generate vhd-inventory.jsonl
+
 run-packer: az-login
-	@packer version && ($(MAKE) -f packer.mk init-packer | tee packer-output) && ($(MAKE) -f packer.mk build-packer | tee -a packer-output)
+	@packer version && ($(MAKE) -f packer.mk init-packer | tee packer-output) && ($(MAKE) -f packer.mk build-packer | tee -a packer-output) && $(MAKE) -f packer.mk generate-release-notes
 
 run-packer-windows: az-login
-	@packer version && ($(MAKE) -f packer.mk init-packer | tee packer-output) && ($(MAKE) -f packer.mk build-packer-windows | tee -a packer-output)
+	@packer version && ($(MAKE) -f packer.mk init-packer | tee packer-output) && ($(MAKE) -f packer.mk build-packer-windows | tee -a packer-output) && $(MAKE) -f packer.mk generate-release-notes
 
 az-copy: az-login
 	azcopy-preview copy "${OS_DISK_SAS}" "${CLASSIC_BLOB}${CLASSIC_SAS_TOKEN}" --recursive=true
@@ -139,6 +139,17 @@ configureKubeletServerCert() {
     openssl req -new -x509 -days 7300 -key $KUBELET_SERVER_PRIVATE_KEY_PATH -out $KUBELET_SERVER_CERT_PATH -subj "/CN=${NODE_NAME}"
 }
 
+fetchKeyVaultSecrets() {
+    set +x
+    if [[ -n "${SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_SECRET_URL}" ]]; then
+        SERVICE_PRINCIPAL_CLIENT_SECRET=$(get_keyvault_secret "${SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_SECRET_URL}") || exit $ERR_KEYVAULT_SECRET_FETCH_FAIL
+    fi
+    if [[ -n "${KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL}" ]]; then
+        KUBELET_PRIVATE_KEY=$(get_keyvault_secret "${KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL}") || exit $ERR_KEYVAULT_SECRET_FETCH_FAIL
+    fi
+    set -x
+}
+
 configureK8s() {
     KUBELET_PRIVATE_KEY_PATH="/etc/kubernetes/certs/client.key"
     touch "${KUBELET_PRIVATE_KEY_PATH}"
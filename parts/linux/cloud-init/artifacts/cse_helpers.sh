@@ -13,7 +13,7 @@ ERR_ETCD_VOL_MOUNT_FAIL=13 {{/* Unable to mount etcd disk volume */}}
 ERR_ETCD_START_TIMEOUT=14 {{/* Unable to start etcd runtime */}}
 ERR_ETCD_CONFIG_FAIL=15 {{/* Unable to configure etcd cluster */}}
 ERR_DOCKER_INSTALL_TIMEOUT=20 {{/* Timeout waiting for docker install */}}
-ERR_DOCKER_DOWNLOAD_TIMEOUT=21 {{/* Timout waiting for docker downloads */}}
+ERR_DOCKER_DOWNLOAD_TIMEOUT=21 {{/* Timeout waiting for docker downloads */}}
 ERR_DOCKER_KEY_DOWNLOAD_TIMEOUT=22 {{/* Timeout waiting to download docker repo key */}}
 ERR_DOCKER_APT_KEY_TIMEOUT=23 {{/* Timeout waiting for docker apt-key */}}
 ERR_DOCKER_START_FAIL=24 {{/* Docker could not be started by systemctl */}}
@@ -32,7 +32,7 @@ ERR_MS_PROD_DEB_PKG_ADD_FAIL=43 {{/* Failed to add repo pkg file */}}
 {{/* ERR_FLEXVOLUME_DOWNLOAD_TIMEOUT=44 Failed to add repo pkg file -- DEPRECATED */}}
 ERR_SYSTEMD_INSTALL_FAIL=48 {{/* Unable to install required systemd version */}}
 ERR_MODPROBE_FAIL=49 {{/* Unable to load a kernel module using modprobe */}}
-ERR_OUTBOUND_CONN_FAIL=50 {{/* Unable to establish outbound connection */}}
+ERR_OUTBOUND_CONN_FAIL=50 {{/* Outbound connectivity to mcr.microsoft.com (gcr.azk8s.cn in Azure China) failed */}}
 ERR_KATA_KEY_DOWNLOAD_TIMEOUT=60 {{/* Timeout waiting to download kata repo key */}}
 ERR_KATA_APT_KEY_TIMEOUT=61 {{/* Timeout waiting for kata apt-key */}}
 ERR_KATA_INSTALL_TIMEOUT=62 {{/* Timeout waiting for kata install */}}
@@ -52,6 +52,8 @@ ERR_CIS_ASSIGN_ROOT_PW=111 {{/* Error assigning root password in CIS enforcement
 ERR_CIS_ASSIGN_FILE_PERMISSION=112 {{/* Error assigning permission to a file in CIS enforcement */}}
 ERR_PACKER_COPY_FILE=113 {{/* Error writing a file to disk during VHD CI */}}
 ERR_CIS_APPLY_PASSWORD_CONFIG=115 {{/* Error applying CIS-recommended passwd configuration */}}
+ERR_KEYVAULT_SECRET_FETCH_FAIL=116 {{/* Error fetching a Key Vault secret with the node's managed identity */}}
+ERR_PROTECTED_SETTINGS_NOT_FOUND=117 {{/* Protected settings file not found, the CSE command was rendered with its secrets in it */}}
 
 ERR_VHD_FILE_NOT_FOUND=124 {{/* VHD log file not found on VM built from VHD distro */}}
 ERR_VHD_BUILD_ERROR=125 {{/* Reserved for VHD CI exit conditions */}}
@@ -107,6 +109,21 @@ retrycmd_if_failure_no_stats() {
         fi
     done
 }
+get_keyvault_secret() {
+    local secret_url=$1 token
+    local vault_host=${secret_url#https://}
+    vault_host=${vault_host%%/*}
+    local token_url="http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https://${vault_host#*.}"
+    if [[ -n "${USER_ASSIGNED_IDENTITY_ID}" ]]; then
+        token_url="${token_url}&client_id=${USER_ASSIGNED_IDENTITY_ID}"
+    fi
+    for i in $(seq 1 10); do
+        token=$(curl -fsSL --max-time 10 -H Metadata:true "${token_url}" | jq -er .access_token) && \
+        curl -fsSL --max-time 10 -H "Authorization: Bearer ${token}" "${secret_url}?api-version=7.0" | jq -er .value && return 0
+        sleep 5
+    done
+    return 1
+}
 retrycmd_get_tarball() {
     tar_retries=$1; wait_sleep=$2; tarball=$3; url=$4
     echo "${tar_retries} retries"
@@ -265,4 +282,45 @@ sysctl_reload() {
 version_gte() {
   test "$(printf '%s\n' "$@" | sort -rV | head -n 1)" == "$1"
 }
+{{/* provisioning status reported to the caller, parsed by pkg/cse */}}
+PROVISION_STATUS_FILE=/var/log/azure/cluster-provision-status.json
+PROVISION_START_TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+PROVISION_PHASE=""
+PROVISION_PHASE_START=0
+PROVISION_PHASES=""
+provision_phase() {
+    local now=$(date +%s%3N)
+    if [[ -n "${PROVISION_PHASE}" ]]; then
+        PROVISION_PHASES="${PROVISION_PHASES}${PROVISION_PHASES:+,}{\"name\":\"${PROVISION_PHASE}\",\"durationMs\":$((now - PROVISION_PHASE_START))}"
+    fi
+    PROVISION_PHASE=$1
+    PROVISION_PHASE_START=$now
+}
+write_provision_status() {
+    local exit_code=$1
+    local failed_step=""
+    if [[ $exit_code -ne 0 ]]; then
+        failed_step=${PROVISION_PHASE}
+    fi
+    provision_phase ""
+    mkdir -p $(dirname ${PROVISION_STATUS_FILE})
+    cat > ${PROVISION_STATUS_FILE}.tmp <<PROVISIONSTATUS
+{
+  "exitCode": ${exit_code},
+  "failedStep": "${failed_step}",
+  "startTime": "${PROVISION_START_TIME}",
+  "endTime": "$(date -u +%Y-%m-%dT%H:%M:%SZ)",
+  "phases": [${PROVISION_PHASES}],
+  "versions": {
+    "kubernetes": "${KUBERNETES_VERSION}",
+    "containerRuntime": "${CONTAINER_RUNTIME}",
+    "moby": "${MOBY_VERSION}",
+    "containerd": "${CONTAINERD_VERSION}",
+    "os": "${OS}",
+    "kernel": "$(uname -r)"
+  }
+}
+PROVISIONSTATUS
+    mv ${PROVISION_STATUS_FILE}.tmp ${PROVISION_STATUS_FILE}
+}
 #HELPERSEOF
@@ -15,6 +15,16 @@ for i in $(seq 1 3600); do
 done
 sed -i "/#HELPERSEOF/d" {{GetCSEHelpersScriptFilepath}}
 source {{GetCSEHelpersScriptFilepath}}
+trap 'write_provision_status $?' EXIT
+provision_phase waitForScripts
+
+if [ -f {{GetProtectedSettingsFilepath}} ]; then
+    set +x
+    source {{GetProtectedSettingsFilepath}}
+    set -x
+elif [[ "${PROTECTED_SETTINGS_REQUIRED}" == "true" ]]; then
+    exit $ERR_PROTECTED_SETTINGS_NOT_FOUND
+fi
 
 wait_for_file 3600 1 {{GetCSEInstallScriptFilepath}} || exit $ERR_FILE_WATCH_TIMEOUT
 source {{GetCSEInstallScriptFilepath}}
@@ -43,6 +53,7 @@ else
     REBOOTREQUIRED=false
 fi
 
+provision_phase prepareNode
 configureAdminUser
 
 {{- if not NeedsContainerd}}
@@ -66,6 +77,7 @@ else
     FULL_INSTALL_REQUIRED=true
 fi
 
+provision_phase installDeps
 if [[ $OS == $UBUNTU_OS_NAME ]] && [ "$FULL_INSTALL_REQUIRED" = "true" ]; then
     installDeps
 else
@@ -76,6 +88,12 @@ if [[ $OS == $UBUNTU_OS_NAME ]]; then
     ensureAuditD
 fi
 
+{{- if HasNodeKeyVaultSecrets}}
+provision_phase fetchKeyVaultSecrets
+fetchKeyVaultSecrets
+{{end}}
+
+provision_phase installContainerRuntime
 {{- if not HasCoreOS}}
 installContainerRuntime
 {{end}}
@@ -99,6 +117,7 @@ fi
 docker login -u $SERVICE_PRINCIPAL_CLIENT_ID -p $SERVICE_PRINCIPAL_CLIENT_SECRET {{GetPrivateAzureRegistryServer}}
 {{end}}
 
+provision_phase installKubernetes
 installKubeletAndKubectl
 
 if [[ $OS != $COREOS_OS_NAME ]]; then
@@ -120,6 +139,7 @@ wait_for_file 3600 1 {{GetCustomSearchDomainsCSEScriptFilepath}} || exit $ERR_FI
 {{GetCustomSearchDomainsCSEScriptFilepath}} > /opt/azure/containers/setup-custom-search-domain.log 2>&1 || exit $ERR_CUSTOM_SEARCH_DOMAINS_FAIL
 {{end}}
 
+provision_phase ensureContainerRuntime
 {{- if IsDockerContainerRuntime}}
 ensureDocker
 {{else if IsKataContainerRuntime}}
@@ -128,6 +148,7 @@ if grep -q vmx /proc/cpuinfo; then
 fi
 {{end}}
 
+provision_phase configureKubernetes
 configureK8s
 
 configureCNI
@@ -141,9 +162,11 @@ ensureContainerd
 ensureDHCPv6
 {{end}}
 
+provision_phase ensureKubelet
 ensureKubelet
 ensureJournal
 
+provision_phase finalizeNode
 if $FULL_INSTALL_REQUIRED; then
     if [[ $OS == $UBUNTU_OS_NAME ]]; then
         {{/* mitigation for bug https://bugs.launchpad.net/ubuntu/+source/linux/+bug/1676635 */}}
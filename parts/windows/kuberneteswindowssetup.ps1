@@ -82,7 +82,7 @@ $global:SubscriptionId = "{{GetVariable "subscriptionId"}}"
 $global:ResourceGroup = "{{GetVariable "resourceGroup"}}"
 $global:VmType = "{{GetVariable "vmType"}}"
 $global:SubnetName = "{{GetVariable "subnetName"}}"
-$global:MasterSubnet = "{{GetWindowsMasterSubnetARMParam}}"
+$global:MasterSubnet = "{{GetParameter "masterSubnet"}}"
 $global:SecurityGroupName = "{{GetVariable "nsgName"}}"
 $global:VNetName = "{{GetVariable "virtualNetworkName"}}"
 $global:RouteTableName = "{{GetVariable "routeTableName"}}"
@@ -93,9 +93,9 @@ $global:KubeClusterCIDR = "{{GetParameter "kubeClusterCidr"}}"
 $global:KubeServiceCIDR = "{{GetParameter "kubeServiceCidr"}}"
 $global:VNetCIDR = "{{GetParameter "vnetCidr"}}"
 {{if IsKubernetesVersionGe "1.16.0"}}
-$global:KubeletNodeLabels = "{{GetAgentKubernetesLabels . "',variables('labelResourceGroup'),'"}}"
+$global:KubeletNodeLabels = "{{GetAgentKubernetesLabels . (GetVariable "labelResourceGroup")}}"
 {{else}}
-$global:KubeletNodeLabels = "{{GetAgentKubernetesLabelsDeprecated . "',variables('labelResourceGroup'),'"}}"
+$global:KubeletNodeLabels = "{{GetAgentKubernetesLabelsDeprecated . (GetVariable "labelResourceGroup")}}"
 {{end}}
 $global:KubeletConfigArgs = @( {{GetKubeletConfigKeyValsPsh .KubernetesConfig }} )
 
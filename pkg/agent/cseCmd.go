@@ -4,22 +4,61 @@
 package agent
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
 	"github.com/Azure/aks-engine/pkg/api"
 )
 
-func getBootstrappingCSE(cs *api.ContainerService, profile *api.AgentPoolProfile) string {
-	if profile.IsWindows() {
-		return "[concat('echo %DATE%,%TIME%,%COMPUTERNAME% && powershell.exe -ExecutionPolicy Unrestricted -command \"', '$arguments = ', variables('singleQuote'),'-MasterIP ',parameters('kubernetesEndpoint'),' -KubeDnsServiceIp ',parameters('kubeDnsServiceIp'),' -MasterFQDNPrefix ',variables('masterFqdnPrefix'),' -Location ',variables('location'),' -TargetEnvironment ',parameters('targetEnvironment'),' -AgentKey ',parameters('clientPrivateKey'),' -AADClientId ',variables('servicePrincipalClientId'),' -AADClientSecret ',variables('singleQuote'),variables('singleQuote'),base64(variables('servicePrincipalClientSecret')),variables('singleQuote'),variables('singleQuote'),' -NetworkAPIVersion ',variables('apiVersionNetwork'),' ',variables('singleQuote'), ' ; ', variables('windowsCustomScriptSuffix'), '\" > %SYSTEMDRIVE%\\AzureData\\CustomDataSetupScript.log 2>&1 ; exit $LASTEXITCODE')]"
-	} else {
-		return ""
+const windowsCustomScriptSuffix = " $inputFile = '%SYSTEMDRIVE%\\AzureData\\CustomData.bin' ; $outputFile = '%SYSTEMDRIVE%\\AzureData\\CustomDataSetupScript.ps1' ; Copy-Item $inputFile $outputFile ; Invoke-Expression('{0} {1}' -f $outputFile, $arguments) ; "
+
+// getWindowsNodeCSE returns the Windows custom script extension command of armtemplate.GetBootstrappingCSE
+// with the ARM parameters and variables resolved
+func getWindowsNodeCSE(config *NodeBootstrappingConfiguration, parameters paramsMap) *NodeBootstrappingCSE {
+	agentKeyStatement, agentKey := getWindowsKeyVaultSecretStatement(config, parameters, "clientPrivateKey", "agentKey", false)
+	if agentKeyStatement == "" {
+		agentKey = getParameterValue(parameters, "clientPrivateKey")
+	}
+	aadClientSecretStatement, aadClientSecret := getWindowsKeyVaultSecretStatement(config, parameters, "servicePrincipalClientSecret", "aadClientSecret", true)
+	if aadClientSecretStatement == "" {
+		aadClientSecret = getWindowsAADClientSecret(parameters)
+	}
+	return &NodeBootstrappingCSE{
+		Environment: map[string]string{},
+		Command: getWindowsNodeCSECommand(agentKeyStatement+aadClientSecretStatement,
+			getWindowsNodeCSEArguments(config, parameters, agentKey, aadClientSecret)),
 	}
 }
 
-func generateUserAssignedIdentityClientIDParameter(cs *api.ContainerService) string {
-	if cs.Properties.OrchestratorProfile != nil &&
-		cs.Properties.OrchestratorProfile.KubernetesConfig != nil &&
-		cs.Properties.OrchestratorProfile.KubernetesConfig.UserAssignedIDEnabled() {
-		return "' USER_ASSIGNED_IDENTITY_ID=',reference(concat('Microsoft.ManagedIdentity/userAssignedIdentities/', variables('userAssignedID')), '2018-11-30').clientId, ' '"
+// getWindowsNodeCSEArguments returns the arguments of the customData script, agentKey and aadClientSecret are
+// inserted as-is so they may also be PowerShell expressions closing and reopening the quoted arguments
+func getWindowsNodeCSEArguments(config *NodeBootstrappingConfiguration, parameters paramsMap, agentKey, aadClientSecret string) string {
+	masterFQDNPrefix := ""
+	if config.HostedMasterProfile != nil {
+		masterFQDNPrefix = strings.ToLower(config.HostedMasterProfile.DNSPrefix)
 	}
-	return "' USER_ASSIGNED_IDENTITY_ID=',' '"
+	return fmt.Sprintf("-MasterIP %s -KubeDnsServiceIp %s -MasterFQDNPrefix %s -Location %s -TargetEnvironment %s -AgentKey %s -AADClientId %s -AADClientSecret ''%s'' -NetworkAPIVersion %s ",
+		getParameterValue(parameters, "kubernetesEndpoint"),
+		getParameterValue(parameters, "kubeDNSServiceIP"),
+		masterFQDNPrefix,
+		config.Location,
+		getParameterValue(parameters, "targetEnvironment"),
+		agentKey,
+		getParameterValue(parameters, "servicePrincipalClientId"),
+		aadClientSecret,
+		api.APIVersionNetwork)
+}
+
+// getWindowsAADClientSecret returns the service principal secret as passed to the customData script, base64 encoded
+// so it needs no quoting
+func getWindowsAADClientSecret(parameters paramsMap) string {
+	return base64.StdEncoding.EncodeToString([]byte(getParameterValue(parameters, "servicePrincipalClientSecret")))
+}
+
+// getWindowsNodeCSECommand returns the CSE command running the customData script with arguments,
+// statements run before $arguments is set
+func getWindowsNodeCSECommand(statements, arguments string) string {
+	return fmt.Sprintf("echo %%DATE%%,%%TIME%%,%%COMPUTERNAME%% && powershell.exe -ExecutionPolicy Unrestricted -command \"%s$arguments = '%s' ; %s\" > %%SYSTEMDRIVE%%\\AzureData\\CustomDataSetupScript.log 2>&1 ; exit $LASTEXITCODE",
+		statements, arguments, windowsCustomScriptSuffix)
 }
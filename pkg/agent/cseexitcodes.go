@@ -0,0 +1,84 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+//go:generate go run ../../hack/cseexitcodes -input ../../parts/linux/cloud-init/artifacts/cse_helpers.sh -output cseexitcodes_generated.go
+
+import "fmt"
+
+// CSEExitCode is an exit code of the Linux custom script extension, the ERR_* variables of cse_helpers.sh
+type CSEExitCode int
+
+// CSEExitCodeCategory groups exit codes by the kind of failure
+type CSEExitCodeCategory string
+
+const (
+	// CSEExitCodeCategoryNetwork is a failure to reach the network, e.g. downloads and outbound connectivity
+	CSEExitCodeCategoryNetwork CSEExitCodeCategory = "network"
+	// CSEExitCodeCategoryPackageInstall is a failure to install a package
+	CSEExitCodeCategoryPackageInstall CSEExitCodeCategory = "packageInstall"
+	// CSEExitCodeCategoryRuntime is a failure to start or configure a node component
+	CSEExitCodeCategoryRuntime CSEExitCodeCategory = "runtime"
+	// CSEExitCodeCategoryGPU is a failure to install or start GPU and SGX drivers
+	CSEExitCodeCategoryGPU CSEExitCodeCategory = "gpu"
+	// CSEExitCodeCategoryAzureStack is a failure specific to Azure Stack
+	CSEExitCodeCategoryAzureStack CSEExitCodeCategory = "azureStack"
+	// CSEExitCodeCategoryOther is a failure during VHD builds and CIS enforcement
+	CSEExitCodeCategoryOther CSEExitCodeCategory = "other"
+)
+
+// CSEExitCodeInfo describes an exit code of the Linux custom script extension
+type CSEExitCodeInfo struct {
+	Name        string              `json:"name"`
+	Code        CSEExitCode         `json:"code"`
+	Description string              `json:"description"`
+	Category    CSEExitCodeCategory `json:"category"`
+}
+
+var cseExitCodesByCode = func() map[CSEExitCode]CSEExitCodeInfo {
+	m := make(map[CSEExitCode]CSEExitCodeInfo, len(cseExitCodes))
+	for _, info := range cseExitCodes {
+		m[info.Code] = info
+	}
+	return m
+}()
+
+// GetCSEExitCodes returns every exit code defined in cse_helpers.sh, sorted by code
+func GetCSEExitCodes() []CSEExitCodeInfo {
+	codes := make([]CSEExitCodeInfo, len(cseExitCodes))
+	copy(codes, cseExitCodes)
+	return codes
+}
+
+// Info returns the description of the exit code and whether it is defined in cse_helpers.sh
+func (c CSEExitCode) Info() (CSEExitCodeInfo, bool) {
+	info, ok := cseExitCodesByCode[c]
+	return info, ok
+}
+
+// Name returns the name of the exit code in cse_helpers.sh, or an empty string for an unknown exit code
+func (c CSEExitCode) Name() string {
+	return cseExitCodesByCode[c].Name
+}
+
+// Category returns the category of the exit code, or an empty string for an unknown exit code
+func (c CSEExitCode) Category() CSEExitCodeCategory {
+	return cseExitCodesByCode[c].Category
+}
+
+// Description returns a human readable description of the exit code
+func (c CSEExitCode) Description() string {
+	if info, ok := cseExitCodesByCode[c]; ok {
+		return info.Description
+	}
+	return fmt.Sprintf("unknown custom script extension exit code %d", int(c))
+}
+
+// Error implements error so that an exit code can be matched with errors.Is
+func (c CSEExitCode) Error() string {
+	if info, ok := cseExitCodesByCode[c]; ok {
+		return fmt.Sprintf("%s (%s, exit status %d)", info.Description, info.Name, int(c))
+	}
+	return c.Description()
+}
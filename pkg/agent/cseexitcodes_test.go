@@ -0,0 +1,55 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetCSEExitCodes(t *testing.T) {
+	codes := GetCSEExitCodes()
+	if len(codes) == 0 {
+		t.Fatalf("expected exit codes generated from cse_helpers.sh")
+	}
+	for i, info := range codes {
+		if i > 0 && codes[i-1].Code >= info.Code {
+			t.Errorf("expected exit codes sorted by unique code, got %d after %d", info.Code, codes[i-1].Code)
+		}
+		if info.Name == "" || info.Description == "" || info.Category == "" {
+			t.Errorf("incomplete exit code %+v", info)
+		}
+	}
+
+	info, ok := CSEExitCodeOutboundConnFail.Info()
+	if !ok || info.Code != 50 || info.Name != "ERR_OUTBOUND_CONN_FAIL" || info.Category != CSEExitCodeCategoryNetwork {
+		t.Errorf("unexpected exit code 50 %+v", info)
+	}
+	if CSEExitCodeGPUDriversInstallTimeout.Category() != CSEExitCodeCategoryGPU ||
+		CSEExitCodeAzureStackGetARMToken.Category() != CSEExitCodeCategoryAzureStack ||
+		CSEExitCodeAptUpdateTimeout.Category() != CSEExitCodeCategoryPackageInstall ||
+		CSEExitCodeKubeletStartFail.Category() != CSEExitCodeCategoryRuntime {
+		t.Errorf("unexpected exit code categories")
+	}
+}
+
+func TestCSEExitCodeError(t *testing.T) {
+	err := fmt.Errorf("provisioning: %w", CSEExitCodeKubeletStartFail)
+	if !errors.Is(err, CSEExitCodeKubeletStartFail) {
+		t.Errorf("expected %v to match CSEExitCodeKubeletStartFail", err)
+	}
+	expected := "kubelet could not be started by systemctl (ERR_KUBELET_START_FAIL, exit status 34)"
+	if CSEExitCodeKubeletStartFail.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, CSEExitCodeKubeletStartFail.Error())
+	}
+
+	unknown := CSEExitCode(231)
+	if _, ok := unknown.Info(); ok || unknown.Name() != "" || unknown.Category() != "" {
+		t.Errorf("expected exit code 231 to be unknown")
+	}
+	if unknown.Error() != "unknown custom script extension exit code 231" {
+		t.Errorf("unexpected error %q", unknown.Error())
+	}
+}
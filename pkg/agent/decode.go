@@ -0,0 +1,199 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// windowsCustomDataPath is where the Windows CSE copies the customData before running it, see windowsCustomScriptSuffix
+	windowsCustomDataPath = `C:\AzureData\CustomDataSetupScript.ps1`
+	// windowsAgentFunctionsDir is where kuberneteswindowssetup.ps1 expands the GetKubernetesWindowsAgentFunctions archive
+	windowsAgentFunctionsDir = `C:\AzureData\`
+)
+
+// windowsAgentFunctionsRe matches the base64 zip archive of GetKubernetesWindowsAgentFunctions in kuberneteswindowssetup.ps1
+var windowsAgentFunctionsRe = regexp.MustCompile(`\$zippedFiles = "([A-Za-z0-9+/=]*)"`)
+
+// DecodedFile is a file the customData writes on the node
+type DecodedFile struct {
+	// Path is the absolute path of the file on the node, e.g. /etc/default/kubelet or C:\AzureData\CustomDataSetupScript.ps1
+	Path string
+	// Permissions are the octal permissions of a cloud-init write_files entry, e.g. 0644, empty if not set
+	Permissions string
+	// Owner is the owner of a cloud-init write_files entry, e.g. root, empty if not set
+	Owner   string
+	Content []byte
+}
+
+// RelativePath returns Path as a slash separated path relative to the root of the node, C:\AzureData\x becomes C/AzureData/x
+func (f *DecodedFile) RelativePath() string {
+	p := strings.Replace(f.Path, `\`, "/", -1)
+	p = strings.Replace(p, ":", "", 1)
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// DecodeCustomData reverses the encoding of a customData payload and returns the files it writes on the node.
+// customData is the cloud-init document or Windows script, its base64 encoding as stored in the VM model, or an
+// instance metadata service dump with compute.customData or compute.userData. The write_files of cloud-init are
+// returned with their content decoded, the Windows script is returned along with the files of its functions archive
+func DecodeCustomData(customData []byte) ([]DecodedFile, error) {
+	// trailing whitespace is content of the last file
+	data := bytes.TrimLeftFunc(customData, unicode.IsSpace)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var err error
+		if data, err = decodeInstanceMetadata(data); err != nil {
+			return nil, err
+		}
+		data = bytes.TrimLeftFunc(data, unicode.IsSpace)
+	}
+	if bytes.HasPrefix(data, []byte("[")) {
+		return nil, errors.New("customData is an ARM expression, decode the customData of a VM or generate with --output-format plain")
+	}
+	if !isCustomDataDocument(data) {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return nil, errors.Wrap(err, "customData is neither a cloud-init document, a Windows script nor base64")
+		}
+		data = bytes.TrimLeftFunc(decoded, unicode.IsSpace)
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		var err error
+		if data, err = gunzip(data); err != nil {
+			return nil, errors.Wrap(err, "decompressing customData")
+		}
+	}
+
+	if bytes.HasPrefix(data, []byte("#cloud-config")) {
+		return decodeCloudInit(data)
+	}
+	if windowsAgentFunctionsRe.Match(data) {
+		return decodeWindowsCustomData(data)
+	}
+	return nil, errors.New("customData is neither a cloud-init document nor a Windows script")
+}
+
+func isCustomDataDocument(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("#cloud-config")) || windowsAgentFunctionsRe.Match(data)
+}
+
+// decodeInstanceMetadata returns the customData, or the userData, of an instance metadata service dump
+func decodeInstanceMetadata(data []byte) ([]byte, error) {
+	metadata := struct {
+		Compute struct {
+			CustomData string `json:"customData"`
+			UserData   string `json:"userData"`
+		} `json:"compute"`
+	}{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, errors.Wrap(err, "parsing instance metadata")
+	}
+	if metadata.Compute.CustomData != "" {
+		return []byte(metadata.Compute.CustomData), nil
+	}
+	if metadata.Compute.UserData != "" {
+		return []byte(metadata.Compute.UserData), nil
+	}
+	return nil, errors.New("instance metadata has neither compute.customData nor compute.userData")
+}
+
+func decodeCloudInit(data []byte) ([]DecodedFile, error) {
+	doc := struct {
+		WriteFiles []struct {
+			Path        string `yaml:"path"`
+			Permissions string `yaml:"permissions"`
+			Owner       string `yaml:"owner"`
+			Encoding    string `yaml:"encoding"`
+			Content     string `yaml:"content"`
+		} `yaml:"write_files"`
+	}{}
+	// gzip encoded content is tagged !!binary, drop the tag and decode the base64 along with b64 encoded content
+	if err := yaml.Unmarshal(bytes.Replace(data, []byte("!!binary "), nil, -1), &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing cloud-init")
+	}
+
+	files := make([]DecodedFile, 0, len(doc.WriteFiles))
+	for _, f := range doc.WriteFiles {
+		content := []byte(f.Content)
+		switch f.Encoding {
+		case "", "text/plain":
+		case "b64", "base64", "gz", "gzip", "gz+b64", "gz+base64", "gzip+b64", "gzip+base64":
+			// the templates leave indented blank lines after the content of some files
+			decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(f.Content), ""))
+			if err != nil {
+				return nil, errors.Wrapf(err, "decoding base64 content of %s", f.Path)
+			}
+			content = decoded
+			if strings.HasPrefix(f.Encoding, "gz") {
+				if content, err = gunzip(content); err != nil {
+					return nil, errors.Wrapf(err, "decompressing content of %s", f.Path)
+				}
+			}
+		default:
+			return nil, errors.Errorf("unknown encoding %s of %s", f.Encoding, f.Path)
+		}
+		files = append(files, DecodedFile{
+			Path:        f.Path,
+			Permissions: f.Permissions,
+			Owner:       f.Owner,
+			Content:     content,
+		})
+	}
+	return files, nil
+}
+
+func decodeWindowsCustomData(data []byte) ([]DecodedFile, error) {
+	files := []DecodedFile{{Path: windowsCustomDataPath, Content: data}}
+
+	m := windowsAgentFunctionsRe.FindSubmatch(data)
+	archive, err := base64.StdEncoding.DecodeString(string(m[1]))
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding the Windows agent functions archive")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening the Windows agent functions archive")
+	}
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening %s in the Windows agent functions archive", zf.Name)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s in the Windows agent functions archive", zf.Name)
+		}
+		files = append(files, DecodedFile{
+			Path:    windowsAgentFunctionsDir + strings.Replace(zf.Name, "/", `\`, -1),
+			Content: content,
+		})
+	}
+	return files, nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
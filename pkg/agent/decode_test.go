@@ -0,0 +1,134 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testCloudInit = `#cloud-config
+
+write_files:
+- path: /opt/azure/containers/provision.sh
+  permissions: "0744"
+  encoding: gzip
+  owner: root
+  content: !!binary |
+    %s
+
+- path: /etc/kubernetes/certs/ca.crt
+  permissions: "0644"
+  encoding: base64
+  owner: root
+  content: |
+    %s
+
+- path: /etc/default/kubelet
+  permissions: "0644"
+  owner: root
+  content: |
+    KUBELET_FLAGS=--v=2
+`
+
+func TestDecodeCustomData(t *testing.T) {
+	cloudInit := fmt.Sprintf(testCloudInit,
+		getBase64EncodedGzippedCustomScriptFromStr("echo provision\n"),
+		base64.StdEncoding.EncodeToString([]byte("ca")))
+	customData := base64.StdEncoding.EncodeToString([]byte(cloudInit))
+	inputs := map[string]string{
+		"document":          cloudInit,
+		"base64":            customData,
+		"instance metadata": fmt.Sprintf(`{"compute": {"customData": "%s"}}`, customData),
+		"user data":         fmt.Sprintf(`{"compute": {"customData": "", "userData": "%s"}}`, customData),
+	}
+	for name, input := range inputs {
+		files, err := DecodeCustomData([]byte(input))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		expected := []DecodedFile{
+			{Path: "/opt/azure/containers/provision.sh", Permissions: "0744", Owner: "root", Content: []byte("echo provision\n")},
+			{Path: "/etc/kubernetes/certs/ca.crt", Permissions: "0644", Owner: "root", Content: []byte("ca")},
+			{Path: "/etc/default/kubelet", Permissions: "0644", Owner: "root", Content: []byte("KUBELET_FLAGS=--v=2\n")},
+		}
+		if len(files) != len(expected) {
+			t.Fatalf("%s: expected %d files, got %d", name, len(expected), len(files))
+		}
+		for i, f := range files {
+			e := expected[i]
+			if f.Path != e.Path || f.Permissions != e.Permissions || f.Owner != e.Owner || !bytes.Equal(f.Content, e.Content) {
+				t.Errorf("%s: expected %+v, got %+v", name, e, f)
+			}
+		}
+	}
+}
+
+func TestDecodeWindowsCustomData(t *testing.T) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("windows/kuberneteswindowsfunctions.ps1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Write([]byte("function Test {}"))
+	zw.Close()
+	script := fmt.Sprintf("<#\n#>\n$zippedFiles = \"%s\"\n", base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	files, err := DecodeCustomData([]byte(base64.StdEncoding.EncodeToString([]byte(script))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the script and one function file, got %+v", files)
+	}
+	if files[0].Path != `C:\AzureData\CustomDataSetupScript.ps1` || string(files[0].Content) != script {
+		t.Errorf("unexpected script %+v", files[0])
+	}
+	if files[1].Path != `C:\AzureData\windows\kuberneteswindowsfunctions.ps1` || string(files[1].Content) != "function Test {}" {
+		t.Errorf("unexpected function file %+v", files[1])
+	}
+	if files[1].RelativePath() != "C/AzureData/windows/kuberneteswindowsfunctions.ps1" {
+		t.Errorf("unexpected relative path %s", files[1].RelativePath())
+	}
+}
+
+func TestDecodeCustomDataErrors(t *testing.T) {
+	cases := map[string]string{
+		"arm expression":    "[base64(concat('#cloud-config\\n\\n',variables('x')))]",
+		"not base64":        "#!/bin/bash echo",
+		"not a document":    base64.StdEncoding.EncodeToString([]byte("hello")),
+		"empty metadata":    `{"compute": {}}`,
+		"unknown encoding":  "#cloud-config\nwrite_files:\n- path: /x\n  encoding: rot13\n  content: x\n",
+		"invalid gzip data": "#cloud-config\nwrite_files:\n- path: /x\n  encoding: gzip\n  content: !!binary |\n    aGVsbG8=\n",
+	}
+	for name, input := range cases {
+		if _, err := DecodeCustomData([]byte(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDecodedFileRelativePath(t *testing.T) {
+	cases := map[string]string{
+		"/etc/default/kubelet":         "etc/default/kubelet",
+		"/opt/../../etc/passwd":        "etc/passwd",
+		`C:\AzureData\k8s\config.ps1`:  "C/AzureData/k8s/config.ps1",
+		`C:\AzureData\..\..\Windows\x`: "Windows/x",
+		"relative/path":                "relative/path",
+	}
+	for p, expected := range cases {
+		f := &DecodedFile{Path: p}
+		if actual := f.RelativePath(); actual != expected {
+			t.Errorf("%s: expected %s, got %s", p, expected, actual)
+		}
+	}
+	if !strings.HasPrefix(windowsCustomDataPath, windowsAgentFunctionsDir) {
+		t.Errorf("expected the customData script to be written to %s", windowsAgentFunctionsDir)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines around the changes of a file diff
+	diffContextLines = 3
+)
+
+var (
+	// windowsCSEArgumentsRe matches the arguments of the customData script in the Windows CSE command, see getWindowsNodeCSE
+	windowsCSEArgumentsRe = regexp.MustCompile(`\$arguments = '((?:[^']|'')*)'`)
+	// windowsCSEArgumentRe matches a single -Name value argument, values may be quoted with ''
+	windowsCSEArgumentRe = regexp.MustCompile(`-(\w+) (''[^']*''|\S*)`)
+	// windowsCSEExpressionRe matches a PowerShell variable inserted into the arguments, see getWindowsKeyVaultSecretStatement
+	windowsCSEExpressionRe = regexp.MustCompile(`' \+ \$([\w.]+) \+ '`)
+)
+
+// NodeBootstrappingContent is the decoded content of a node bootstrapping
+type NodeBootstrappingContent struct {
+	// Files are the files written by the customData keyed by their path on the node
+	Files map[string][]byte
+	// Variables are the environment variables of the Linux CSE command or the arguments of the Windows CSE command
+	Variables map[string]string
+	// Command is the CSE command without its variables
+	Command string
+}
+
+// DecodeNodeBootstrapping decodes the files written by the customData and the variables of the CSE command
+func DecodeNodeBootstrapping(nodeBootstrapping *NodeBootstrapping) (*NodeBootstrappingContent, error) {
+	if nodeBootstrapping == nil || nodeBootstrapping.CSE == nil {
+		return nil, errors.New("node bootstrapping has no CSE command")
+	}
+	files, err := DecodeCustomData([]byte(nodeBootstrapping.CustomData))
+	if err != nil {
+		return nil, err
+	}
+	content := &NodeBootstrappingContent{
+		Files:     make(map[string][]byte, len(files)),
+		Variables: make(map[string]string, len(nodeBootstrapping.CSE.Environment)),
+		Command:   nodeBootstrapping.CSE.Command,
+	}
+	for _, f := range files {
+		content.Files[f.Path] = f.Content
+	}
+	for k, v := range nodeBootstrapping.CSE.Environment {
+		content.Variables[k] = v
+	}
+	// protected settings are variables of the Linux CSE and arguments of the Windows CSE,
+	// see GetNodeBootstrappingCmdWithProtectedSettings
+	for k, v := range nodeBootstrapping.CSE.ProtectedSettings {
+		content.Variables[k] = v
+		content.Command = strings.Replace(content.Command, fmt.Sprintf(windowsProtectedSettingFormat, k), v, -1)
+	}
+	// arguments set by PowerShell statements, e.g. Key Vault secrets, are kept as the PowerShell variable
+	content.Command = windowsCSEExpressionRe.ReplaceAllString(content.Command, "$$$1")
+
+	// the Windows CSE passes its settings as script arguments instead of environment variables
+	if m := windowsCSEArgumentsRe.FindStringSubmatchIndex(content.Command); m != nil {
+		arguments := content.Command[m[2]:m[3]]
+		for _, a := range windowsCSEArgumentRe.FindAllStringSubmatch(arguments, -1) {
+			content.Variables[a[1]] = strings.Trim(a[2], "'")
+		}
+		content.Command = content.Command[:m[2]] + "<arguments>" + content.Command[m[3]:]
+	}
+	return content, nil
+}
+
+// Redact replaces the secret variables with their fingerprints, see RedactSecret, and every occurrence of their values and of the
+// secrets of r in the files, variables and command, see Redactor
+func (c *NodeBootstrappingContent) Redact(r *Redactor) {
+	for k, v := range c.Variables {
+		if cseSecretVariables[k] {
+			r.AddSecret(v)
+		}
+	}
+	for path, content := range c.Files {
+		c.Files[path] = []byte(r.Redact(string(content)))
+	}
+	for k, v := range c.Variables {
+		if cseSecretVariables[k] && v != "" {
+			c.Variables[k] = RedactSecret(v)
+			continue
+		}
+		c.Variables[k] = r.Redact(v)
+	}
+	c.Command = r.Redact(c.Command)
+}
+
+// DiffChange is how a file or variable changed between two node bootstrappings
+type DiffChange string
+
+const (
+	// DiffAdded is only in the second node bootstrapping
+	DiffAdded DiffChange = "added"
+	// DiffRemoved is only in the first node bootstrapping
+	DiffRemoved DiffChange = "removed"
+	// DiffModified is in both node bootstrappings with different content
+	DiffModified DiffChange = "modified"
+)
+
+// FileDiff is a file that changed between two node bootstrappings
+type FileDiff struct {
+	Path   string     `json:"path"`
+	Change DiffChange `json:"change"`
+	// UnifiedDiff are the changed lines with diffContextLines lines of context
+	UnifiedDiff string `json:"unifiedDiff"`
+}
+
+// VariableDiff is a CSE variable that changed between two node bootstrappings
+type VariableDiff struct {
+	Name   string     `json:"name"`
+	Change DiffChange `json:"change"`
+	Before string     `json:"before,omitempty"`
+	After  string     `json:"after,omitempty"`
+}
+
+// NodeBootstrappingDiff is the per file and per variable difference of two node bootstrappings
+type NodeBootstrappingDiff struct {
+	Files     []FileDiff     `json:"files,omitempty"`
+	Variables []VariableDiff `json:"variables,omitempty"`
+	// Command is the unified diff of the CSE commands without their variables, empty if they are the same
+	Command string `json:"command,omitempty"`
+}
+
+// DiffNodeBootstrappingContent returns the files and variables that differ between before and after, sorted by path and name
+func DiffNodeBootstrappingContent(before, after *NodeBootstrappingContent) *NodeBootstrappingDiff {
+	d := &NodeBootstrappingDiff{}
+
+	for _, path := range unionKeys(fileKeys(before.Files), fileKeys(after.Files)) {
+		b, inBefore := before.Files[path]
+		a, inAfter := after.Files[path]
+		switch {
+		case !inBefore:
+			d.Files = append(d.Files, FileDiff{Path: path, Change: DiffAdded, UnifiedDiff: unifiedDiff(nil, splitLines(string(a)))})
+		case !inAfter:
+			d.Files = append(d.Files, FileDiff{Path: path, Change: DiffRemoved, UnifiedDiff: unifiedDiff(splitLines(string(b)), nil)})
+		case string(a) != string(b):
+			d.Files = append(d.Files, FileDiff{Path: path, Change: DiffModified, UnifiedDiff: unifiedDiff(splitLines(string(b)), splitLines(string(a)))})
+		}
+	}
+
+	for _, name := range unionKeys(variableKeys(before.Variables), variableKeys(after.Variables)) {
+		b, inBefore := before.Variables[name]
+		a, inAfter := after.Variables[name]
+		switch {
+		case !inBefore:
+			d.Variables = append(d.Variables, VariableDiff{Name: name, Change: DiffAdded, After: a})
+		case !inAfter:
+			d.Variables = append(d.Variables, VariableDiff{Name: name, Change: DiffRemoved, Before: b})
+		case a != b:
+			d.Variables = append(d.Variables, VariableDiff{Name: name, Change: DiffModified, Before: b, After: a})
+		}
+	}
+
+	if before.Command != after.Command {
+		d.Command = unifiedDiff(splitLines(before.Command), splitLines(after.Command))
+	}
+	return d
+}
+
+// Empty reports whether the node bootstrappings are the same
+func (d *NodeBootstrappingDiff) Empty() bool {
+	return len(d.Files) == 0 && len(d.Variables) == 0 && d.Command == ""
+}
+
+// String formats the diff for humans, files first, then variables, then the command
+func (d *NodeBootstrappingDiff) String() string {
+	var sb strings.Builder
+	for _, f := range d.Files {
+		fmt.Fprintf(&sb, "%s file %s\n%s", f.Change, f.Path, f.UnifiedDiff)
+	}
+	for _, v := range d.Variables {
+		switch v.Change {
+		case DiffAdded:
+			fmt.Fprintf(&sb, "added variable %s=%q\n", v.Name, v.After)
+		case DiffRemoved:
+			fmt.Fprintf(&sb, "removed variable %s=%q\n", v.Name, v.Before)
+		default:
+			fmt.Fprintf(&sb, "modified variable %s: %q -> %q\n", v.Name, v.Before, v.After)
+		}
+	}
+	if d.Command != "" {
+		fmt.Fprintf(&sb, "modified CSE command\n%s", d.Command)
+	}
+	return sb.String()
+}
+
+func fileKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func variableKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func unionKeys(a, b []string) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, k := range append(a, b...) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp is a line of an edit script, ' ' for an unchanged line, '-' for a removed line and '+' for an added line
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// lineEditScript returns the shortest edit script turning a into b using the longest common subsequence of lines
+func lineEditScript(a, b []string) []diffOp {
+	// strip the common prefix and suffix, templates changes are usually small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of ma[i:] and mb[j:]
+	lcs := make([][]int32, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, diffOp{' ', ma[i]})
+			i++
+			j++
+		case j >= len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', ma[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', mb[j]})
+			j++
+		}
+	}
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// unifiedDiff returns the hunks of the unified diff of a and b, without file headers
+func unifiedDiff(a, b []string) string {
+	ops := lineEditScript(a, b)
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// extend the hunk until more than twice the context of unchanged lines separate two changes
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				break
+			}
+			end = next
+		}
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := end + diffContextLines
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		// line numbers of the hunk in a and b
+		aLine, bLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = hunkEnd
+	}
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testWindowsCSECommand = `powershell.exe -command "$arguments = '-MasterIP abc.aks.com -MasterFQDNPrefix  -Location westus2 -AgentKey a2V5LWRhdGE= -AADClientSecret ''c2VjcmV0LWRhdGE='' ' ; Invoke-Expression('{0} {1}' -f $outputFile, $arguments)"`
+
+func TestDecodeNodeBootstrappingWindowsArguments(t *testing.T) {
+	nodeBootstrapping := &NodeBootstrapping{
+		CustomData: fmt.Sprintf("$zippedFiles = \"%s\"\n", emptyZipBase64()),
+		CSE:        &NodeBootstrappingCSE{Environment: map[string]string{}, Command: testWindowsCSECommand},
+	}
+	content, err := DecodeNodeBootstrapping(nodeBootstrapping)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"MasterIP":         "abc.aks.com",
+		"MasterFQDNPrefix": "",
+		"Location":         "westus2",
+		"AgentKey":         "a2V5LWRhdGE=",
+		"AADClientSecret":  "c2VjcmV0LWRhdGE=",
+	}
+	if len(content.Variables) != len(expected) {
+		t.Errorf("expected variables %v, got %v", expected, content.Variables)
+	}
+	for k, v := range expected {
+		if content.Variables[k] != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, content.Variables[k])
+		}
+	}
+	if !strings.Contains(content.Command, "$arguments = '<arguments>'") {
+		t.Errorf("expected the arguments to be removed from the command, got %s", content.Command)
+	}
+
+	content.Redact(NewRedactor())
+	expectedRedacted := map[string]string{
+		"AgentKey":        Fingerprint("key-data"),
+		"AADClientSecret": Fingerprint("secret-data"),
+	}
+	for k, v := range expectedRedacted {
+		if content.Variables[k] != v {
+			t.Errorf("expected %s to be redacted to %s, got %q", k, v, content.Variables[k])
+		}
+	}
+}
+
+func TestNodeBootstrappingContentRedact(t *testing.T) {
+	content := &NodeBootstrappingContent{
+		Files: map[string][]byte{
+			"/etc/kubernetes/azure.json":                           []byte(`{"aadClientId": "client-id", "aadClientSecret": "sp-secret"}`),
+			"/opt/azure/containers/setup-custom-search-domains.sh": []byte(`echo "realm-password" | realm join`),
+		},
+		Variables: map[string]string{
+			"SERVICE_PRINCIPAL_CLIENT_ID":     "client-id",
+			"SERVICE_PRINCIPAL_CLIENT_SECRET": "sp-secret",
+			"KUBELET_PRIVATE_KEY":             base64.StdEncoding.EncodeToString([]byte("client-key")),
+			"KUBELET_CLIENT_KEY_COPY":         "client-key",
+		},
+	}
+	content.Redact(NewRedactor("realm-password"))
+
+	expectedFiles := map[string]string{
+		"/etc/kubernetes/azure.json":                           fmt.Sprintf(`{"aadClientId": "client-id", "aadClientSecret": "%s"}`, Fingerprint("sp-secret")),
+		"/opt/azure/containers/setup-custom-search-domains.sh": fmt.Sprintf(`echo "%s" | realm join`, Fingerprint("realm-password")),
+	}
+	for path, expected := range expectedFiles {
+		if string(content.Files[path]) != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, content.Files[path])
+		}
+	}
+	expectedVariables := map[string]string{
+		"SERVICE_PRINCIPAL_CLIENT_ID":     "client-id",
+		"SERVICE_PRINCIPAL_CLIENT_SECRET": Fingerprint("sp-secret"),
+		"KUBELET_PRIVATE_KEY":             Fingerprint("client-key"),
+		"KUBELET_CLIENT_KEY_COPY":         Fingerprint("client-key"),
+	}
+	for k, expected := range expectedVariables {
+		if content.Variables[k] != expected {
+			t.Errorf("%s: expected %q, got %q", k, expected, content.Variables[k])
+		}
+	}
+}
+
+func TestDiffNodeBootstrappingContent(t *testing.T) {
+	before := &NodeBootstrappingContent{
+		Files: map[string][]byte{
+			"/etc/default/kubelet": []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"),
+			"/removed":             []byte("x\n"),
+			"/same":                []byte("same\n"),
+		},
+		Variables: map[string]string{"REMOVED": "1", "MODIFIED": "1", "SAME": "1"},
+		Command:   "/opt/azure/containers/provision.sh",
+	}
+	after := &NodeBootstrappingContent{
+		Files: map[string][]byte{
+			"/etc/default/kubelet": []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"),
+			"/added":               []byte("y"),
+			"/same":                []byte("same\n"),
+		},
+		Variables: map[string]string{"ADDED": "2", "MODIFIED": "2", "SAME": "1"},
+		Command:   "/opt/azure/containers/provision.sh",
+	}
+
+	d := DiffNodeBootstrappingContent(before, after)
+	expected := `added file /added
+@@ -0,0 +1,1 @@
++y
+\ No newline at end of file
+modified file /etc/default/kubelet
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -9,3 +9,4 @@
+ i
+ j
+ k
++l
+removed file /removed
+@@ -1,1 +0,0 @@
+-x
+added variable ADDED="2"
+modified variable MODIFIED: "1" -> "2"
+removed variable REMOVED="1"
+`
+	if d.String() != expected {
+		t.Errorf("expected diff\n%s\ngot\n%s", expected, d.String())
+	}
+	if d.Empty() {
+		t.Errorf("expected the diff not to be empty")
+	}
+	if !DiffNodeBootstrappingContent(before, before).Empty() {
+		t.Errorf("expected no differences between the same content")
+	}
+}
+
+func TestUnifiedDiffMergesCloseChanges(t *testing.T) {
+	a := splitLines("1\n2\n3\n4\n5\n6\n7\n8\n")
+	b := splitLines("1\nx\n3\n4\n5\n6\n7\ny\n")
+	expected := `@@ -1,8 +1,8 @@
+ 1
+-2
++x
+ 3
+ 4
+ 5
+ 6
+ 7
+-8
++y
+`
+	if actual := unifiedDiff(a, b); actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func emptyZipBase64() string {
+	// an empty zip archive is its end of central directory record
+	return base64.StdEncoding.EncodeToString(append([]byte("PK\x05\x06"), make([]byte, 18)...))
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"fmt"
+)
+
+// Template operations reported by TemplateError
+const (
+	TemplateOpLoad    = "load"
+	TemplateOpParse   = "parse"
+	TemplateOpExecute = "execute"
+)
+
+// TemplateError is returned when a node bootstrapping template cannot be loaded, parsed or executed
+type TemplateError struct {
+	// Template is the name of the template asset, e.g. linux/cloud-init/artifacts/cse_cmd.sh
+	Template string
+	// Op is the operation that failed, one of TemplateOpLoad, TemplateOpParse or TemplateOpExecute
+	Op  string
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	switch e.Op {
+	case TemplateOpLoad:
+		return fmt.Sprintf("template file %s does not exist: %v", e.Template, e.Err)
+	case TemplateOpParse:
+		return fmt.Sprintf("error parsing template file %s: %v", e.Template, e.Err)
+	default:
+		return fmt.Sprintf("error executing template file %s: %v", e.Template, e.Err)
+	}
+}
+
+// Unwrap returns the underlying error
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// FuncMapError is returned when a template func map call fails during template execution
+type FuncMapError struct {
+	// Func is the name of the func map entry, e.g. GetKubernetesWindowsAgentFunctions
+	Func string
+	Err  error
+}
+
+func (e *FuncMapError) Error() string {
+	return fmt.Sprintf("template func %s failed: %v", e.Func, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FuncMapError) Unwrap() error {
+	return e.Err
+}
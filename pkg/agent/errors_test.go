@@ -0,0 +1,60 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+)
+
+func TestGetSingleLineMissingTemplate(t *testing.T) {
+	tg := InitializeTemplateGenerator()
+	_, err := tg.getSingleLine("linux/cloud-init/artifacts/does-not-exist.sh", nil, nil)
+	if err == nil {
+		t.Fatalf("expected an error for a missing template")
+	}
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) {
+		t.Fatalf("expected a *TemplateError, got %T", err)
+	}
+	if templateErr.Op != TemplateOpLoad || templateErr.Template != "linux/cloud-init/artifacts/does-not-exist.sh" {
+		t.Fatalf("unexpected TemplateError %+v", templateErr)
+	}
+}
+
+func TestGetBase64EncodedGzippedCustomScriptMissingTemplate(t *testing.T) {
+	config := &NodeBootstrappingConfiguration{}
+	_, err := renderBase64EncodedGzippedCustomScript("linux/cloud-init/artifacts/does-not-exist.sh", config, getContainerServiceFuncMap(config))
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) || templateErr.Op != TemplateOpLoad {
+		t.Fatalf("expected a load *TemplateError, got %v", err)
+	}
+}
+
+func TestMakeAgentExtensionScriptCommandsMissingExtension(t *testing.T) {
+	config := &NodeBootstrappingConfiguration{}
+	for _, osType := range []api.OSType{api.Linux, api.Windows} {
+		profile := &api.AgentPoolProfile{
+			OSType: osType,
+			PreprovisionExtension: &api.Extension{
+				Name: "hello-world",
+			},
+		}
+		if _, err := makeAgentExtensionScriptCommands(config, profile, nil); err == nil {
+			t.Fatalf("expected an error for %s when the extension profile is missing", osType)
+		}
+	}
+}
+
+func TestGetBase64EncodedWindowsAgentFunctions(t *testing.T) {
+	str, err := getBase64EncodedWindowsAgentFunctions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if str == "" {
+		t.Fatalf("expected a non-empty zip archive")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// DefaultExtensionTimeout is the timeout of an HTTPExtensionSource download if Timeout is not set
+const DefaultExtensionTimeout = 30 * time.Second
+
+// ExtensionSource returns the files of the extensions an agent pool runs. The files are laid out as under
+// the rootURL of an extension profile: extensions/<name>/<version>/<file>
+type ExtensionSource interface {
+	GetExtensionFile(profile *api.ExtensionProfile, fileName string) ([]byte, error)
+}
+
+// extensionFilePath returns the path of an extension file relative to the extensions root
+func extensionFilePath(profile *api.ExtensionProfile, fileName string) string {
+	return path.Join("extensions", profile.Name, profile.Version, fileName)
+}
+
+// DirExtensionSource reads the extension files from a local copy of the extensions root
+type DirExtensionSource struct {
+	Root string
+}
+
+// GetExtensionFile reads the file from <Root>/extensions/<name>/<version>/<file>
+func (s *DirExtensionSource) GetExtensionFile(profile *api.ExtensionProfile, fileName string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.Root, filepath.FromSlash(extensionFilePath(profile, fileName))))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading file %s of extension %s version %s", fileName, profile.Name, profile.Version)
+	}
+	return b, nil
+}
+
+// BundleExtensionSource reads the extension files from a zip archive of the extensions root,
+// e.g. one embedded in the binary
+type BundleExtensionSource struct {
+	files map[string][]byte
+}
+
+// NewBundleExtensionSource returns an extension source reading from the zip archive b
+func NewBundleExtensionSource(b []byte) (*BundleExtensionSource, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening extension bundle")
+	}
+	s := &BundleExtensionSource{files: map[string][]byte{}}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening %s in extension bundle", f.Name)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s in extension bundle", f.Name)
+		}
+		s.files[path.Clean(f.Name)] = content
+	}
+	return s, nil
+}
+
+// GetExtensionFile returns the bundle entry extensions/<name>/<version>/<file>
+func (s *BundleExtensionSource) GetExtensionFile(profile *api.ExtensionProfile, fileName string) ([]byte, error) {
+	b, ok := s.files[extensionFilePath(profile, fileName)]
+	if !ok {
+		return nil, errors.Errorf("extension bundle has no file %s of extension %s version %s", fileName, profile.Name, profile.Version)
+	}
+	return b, nil
+}
+
+// HTTPExtensionSource downloads the extension files from the rootURL of the extension profile. A file found
+// in CacheDir is not downloaded again, the cache is laid out as the extensions root so a DirExtensionSource
+// can read it once the build environment is offline
+type HTTPExtensionSource struct {
+	// Timeout of a download, DefaultExtensionTimeout if zero
+	Timeout time.Duration
+	// CacheDir caches the downloaded files if not empty
+	CacheDir string
+	// Checksums are the hex encoded SHA-256 checksums of the files keyed by <name>/<version>/<file>,
+	// a file with a checksum is only returned, and cached, if it matches
+	Checksums map[string]string
+}
+
+// GetExtensionFile returns the cached file or downloads it
+func (s *HTTPExtensionSource) GetExtensionFile(profile *api.ExtensionProfile, fileName string) ([]byte, error) {
+	cachePath := ""
+	if s.CacheDir != "" {
+		cachePath = filepath.Join(s.CacheDir, filepath.FromSlash(extensionFilePath(profile, fileName)))
+		if b, err := ioutil.ReadFile(cachePath); err == nil && s.verify(profile, fileName, b) == nil {
+			return b, nil
+		}
+	}
+
+	b, err := s.download(profile, fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.verify(profile, fileName, b); err != nil {
+		return nil, err
+	}
+	if cachePath != "" {
+		if err = os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+			return nil, errors.Wrap(err, "creating extension cache directory")
+		}
+		if err = ioutil.WriteFile(cachePath, b, 0644); err != nil {
+			return nil, errors.Wrapf(err, "caching file %s of extension %s version %s", fileName, profile.Name, profile.Version)
+		}
+	}
+	return b, nil
+}
+
+func (s *HTTPExtensionSource) download(profile *api.ExtensionProfile, fileName string) ([]byte, error) {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultExtensionTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	requestURL := GetExtensionURL(profile.RootURL, profile.Name, profile.Version, fileName, profile.URLQuery)
+	res, err := client.Get(requestURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "downloading file %s of extension %s version %s", fileName, profile.Name, profile.Version)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("downloading file %s of extension %s version %s from %s: %s", fileName, profile.Name, profile.Version, requestURL, res.Status)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "downloading file %s of extension %s version %s", fileName, profile.Name, profile.Version)
+	}
+	return b, nil
+}
+
+// verify checks b against the checksum of the file if there is one
+func (s *HTTPExtensionSource) verify(profile *api.ExtensionProfile, fileName string, b []byte) error {
+	expected, ok := s.Checksums[path.Join(profile.Name, profile.Version, fileName)]
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256(b)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return errors.Errorf("file %s of extension %s version %s has SHA-256 checksum %s, expected %s", fileName, profile.Name, profile.Version, actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+// testExtensionsRoot is a local copy of the extensions root holding the hello-world extension
+const testExtensionsRoot = "testdata/extensions"
+
+// testExtensionScriptChecksum is the SHA-256 checksum of hello-world.sh
+const testExtensionScriptChecksum = "7e8cfe8f230f095c451180c81887a2d73cc1d7152a5d42d0ab8990eb9ac09026"
+
+func testExtensionProfile(rootURL string) *api.ExtensionProfile {
+	return &api.ExtensionProfile{Name: "hello-world", Version: "v1", Script: "hello-world.sh", RootURL: rootURL}
+}
+
+func readTestExtensionScript(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile(filepath.Join(testExtensionsRoot, "extensions", "hello-world", "v1", name))
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	return b
+}
+
+func TestDirExtensionSource(t *testing.T) {
+	source := &DirExtensionSource{Root: testExtensionsRoot}
+	b, err := source.GetExtensionFile(testExtensionProfile(""), "hello-world.sh")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := readTestExtensionScript(t, "hello-world.sh"); !bytes.Equal(b, expected) {
+		t.Errorf("expected %q, got %q", expected, b)
+	}
+	if _, err := source.GetExtensionFile(testExtensionProfile(""), "missing.sh"); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestBundleExtensionSource(t *testing.T) {
+	script := readTestExtensionScript(t, "hello-world.sh")
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	f, err := zw.Create("extensions/hello-world/v1/hello-world.sh")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Write(script)
+	zw.Close()
+
+	source, err := NewBundleExtensionSource(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := source.GetExtensionFile(testExtensionProfile(""), "hello-world.sh")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(b, script) {
+		t.Errorf("expected %q, got %q", script, b)
+	}
+	if _, err := source.GetExtensionFile(&api.ExtensionProfile{Name: "hello-world", Version: "v2"}, "hello-world.sh"); err == nil {
+		t.Errorf("expected an error for a version missing from the bundle")
+	}
+	if _, err := NewBundleExtensionSource([]byte("not a zip")); err == nil {
+		t.Errorf("expected an error for an invalid bundle")
+	}
+}
+
+func TestHTTPExtensionSource(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.FileServer(http.Dir(testExtensionsRoot)).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	cacheDir, err := ioutil.TempDir("", "extensions")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	profile := testExtensionProfile(server.URL + "/")
+	source := &HTTPExtensionSource{
+		CacheDir:  cacheDir,
+		Checksums: map[string]string{"hello-world/v1/hello-world.sh": testExtensionScriptChecksum},
+	}
+	script := readTestExtensionScript(t, "hello-world.sh")
+	for i := 0; i < 2; i++ {
+		b, err := source.GetExtensionFile(profile, "hello-world.sh")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(b, script) {
+			t.Errorf("expected %q, got %q", script, b)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the second read to hit the cache, got %d requests", requests)
+	}
+
+	// the cache is an extensions root
+	if _, err := (&DirExtensionSource{Root: cacheDir}).GetExtensionFile(profile, "hello-world.sh"); err != nil {
+		t.Errorf("expected the cache to be readable by a DirExtensionSource: %v", err)
+	}
+
+	source = &HTTPExtensionSource{Checksums: map[string]string{"hello-world/v1/hello-world.ps1": testExtensionScriptChecksum}}
+	if _, err := source.GetExtensionFile(profile, "hello-world.ps1"); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+	if _, err := source.GetExtensionFile(profile, "missing.sh"); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestPreprovisionExtensionFromSource(t *testing.T) {
+	cases := []struct {
+		name     string
+		golden   string
+		script   string
+		download string
+	}{
+		{"linux", "custom-search-domain", "hello-world.sh", "/usr/bin/curl"},
+		{"windows", "windows", "hello-world.ps1", "Invoke-WebRequest"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cs := loadGoldenContainerService(t, filepath.Join(goldenDir, c.golden, "apimodel.json"))
+			config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+			if err != nil {
+				t.Fatalf("converting the api model: %v", err)
+			}
+			profile := testExtensionProfile("https://extensions.invalid/")
+			profile.Script = c.script
+			config.ExtensionProfiles = []*api.ExtensionProfile{profile}
+			config.AgentPoolProfile.PreprovisionExtension = &api.Extension{Name: "hello-world"}
+
+			tg := InitializeTemplateGenerator()
+			nodeBootstrapping, err := tg.GetNodeBootstrapping(config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(nodeBootstrapping.CustomData, c.download) {
+				t.Errorf("expected the node to download the script without an extension source")
+			}
+
+			tg.ExtensionSource = &DirExtensionSource{Root: testExtensionsRoot}
+			nodeBootstrapping, err = tg.GetNodeBootstrapping(config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			script := base64.StdEncoding.EncodeToString(readTestExtensionScript(t, c.script))
+			if !strings.Contains(nodeBootstrapping.CustomData, script) {
+				t.Errorf("expected the customData to embed the script")
+			}
+			if strings.Contains(nodeBootstrapping.CustomData, c.download) {
+				t.Errorf("expected the node not to download the script")
+			}
+
+			tg.ExtensionSource = &DirExtensionSource{Root: "testdata/missing"}
+			if _, err = tg.GetNodeBootstrapping(config); err == nil {
+				t.Errorf("expected an error for a script missing from the extension source")
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"encoding/base64"
+	"text/template"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// getContainerServiceFuncMap returns the funcs the node bootstrapping templates call, every one of them is called
+// by a template, TestAuditTemplates fails on a func no template calls. The master and ARM template funcs are in
+// package armtemplate. These funcs are a thin wrapper for template generation operations,
+// all business logic is implemented in the underlying func
+func getContainerServiceFuncMap(config *NodeBootstrappingConfiguration) template.FuncMap {
+	cs := config.containerService()
+	return template.FuncMap{
+		// IsAzureStackCloud returns true if the cluster runs on Azure Stack
+		"IsAzureStackCloud": func() bool {
+			return cs.Properties.IsAzureStackCloud()
+		},
+		// HasNodeKeyVaultSecrets returns true if node secrets are fetched from Key Vault at boot
+		"HasNodeKeyVaultSecrets": func() bool {
+			return hasNodeKeyVaultSecrets(getParameters(config, "", ""))
+		},
+		// IsIPMasqAgentEnabled returns true if the ip-masq-agent addon is enabled
+		"IsIPMasqAgentEnabled": func() bool {
+			return cs.Properties.IsIPMasqAgentEnabled()
+		},
+		// IsKubernetesVersionGe returns true if the cluster Kubernetes version is at least version
+		"IsKubernetesVersionGe": func(version string) bool {
+			return cs.Properties.OrchestratorProfile.IsKubernetes() && IsKubernetesVersionGe(cs.Properties.OrchestratorProfile.OrchestratorVersion, version)
+		},
+		// GetAgentKubernetesLabels returns the kubelet --node-labels of the agent pool
+		"GetAgentKubernetesLabels": func(profile *api.AgentPoolProfile, rg string) string {
+			return profile.GetKubernetesLabels(rg, false)
+		},
+		// GetAgentKubernetesLabelsDeprecated returns the kubelet --node-labels of the agent pool including the deprecated role labels
+		"GetAgentKubernetesLabelsDeprecated": func(profile *api.AgentPoolProfile, rg string) string {
+			return profile.GetKubernetesLabels(rg, true)
+		},
+		// GetKubeletConfigKeyVals returns the kubelet flags of the kubernetes config
+		"GetKubeletConfigKeyVals": func(kc *api.KubernetesConfig) string {
+			if kc == nil {
+				return ""
+			}
+			return kc.GetOrderedKubeletConfigString()
+		},
+		// GetKubeletConfigKeyValsPsh returns the kubelet flags of the kubernetes config as a PowerShell list
+		"GetKubeletConfigKeyValsPsh": func(kc *api.KubernetesConfig) string {
+			if kc == nil {
+				return ""
+			}
+			return kc.GetOrderedKubeletConfigStringForPowershell()
+		},
+		// IsAzureCNI returns true if the network plugin is Azure CNI
+		"IsAzureCNI": func() bool {
+			return cs.Properties.OrchestratorProfile.IsAzureCNI()
+		},
+		// GetSshPublicKeysPowerShell returns the Linux profile SSH public keys as a PowerShell list
+		"GetSshPublicKeysPowerShell": func() string {
+			return getSSHPublicKeysPowerShell(cs.Properties.LinuxProfile)
+		},
+		// GetKubernetesWindowsAgentFunctions returns the base64 encoded zip of the Windows helper scripts
+		"GetKubernetesWindowsAgentFunctions": func() (string, error) {
+			str, err := getCachedBase64EncodedWindowsAgentFunctions()
+			if err != nil {
+				return "", &FuncMapError{Func: "GetKubernetesWindowsAgentFunctions", Err: err}
+			}
+			return str, nil
+		},
+		// IsNSeriesSKU returns true if the agent pool VM size has an Nvidia GPU
+		"IsNSeriesSKU": func(profile *api.AgentPoolProfile) bool {
+			return IsNvidiaEnabledSKU(profile.VMSize)
+		},
+		// HasCustomSearchDomain returns true if the Linux profile sets a custom search domain
+		"HasCustomSearchDomain": func() bool {
+			return cs.Properties.LinuxProfile != nil && cs.Properties.LinuxProfile.HasSearchDomain()
+		},
+		// GetSearchDomainName returns the custom search domain name
+		"GetSearchDomainName": func() string {
+			if cs.Properties.LinuxProfile != nil && cs.Properties.LinuxProfile.HasSearchDomain() {
+				return cs.Properties.LinuxProfile.CustomSearchDomain.Name
+			}
+			return ""
+		},
+		// GetSearchDomainRealmUser returns the custom search domain realm user
+		"GetSearchDomainRealmUser": func() string {
+			if cs.Properties.LinuxProfile != nil && cs.Properties.LinuxProfile.HasSearchDomain() {
+				return cs.Properties.LinuxProfile.CustomSearchDomain.RealmUser
+			}
+			return ""
+		},
+		// GetSearchDomainRealmPassword returns the custom search domain realm password
+		"GetSearchDomainRealmPassword": func() string {
+			if cs.Properties.LinuxProfile != nil && cs.Properties.LinuxProfile.HasSearchDomain() {
+				return cs.Properties.LinuxProfile.CustomSearchDomain.RealmPassword
+			}
+			return ""
+		},
+		// HasCiliumNetworkPlugin returns true if the network plugin is cilium
+		"HasCiliumNetworkPlugin": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin == NetworkPluginCilium
+		},
+		// HasCiliumNetworkPolicy returns true if the network policy is cilium
+		"HasCiliumNetworkPolicy": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPolicy == NetworkPolicyCilium
+		},
+		// HasAntreaNetworkPolicy returns true if the network policy is antrea
+		"HasAntreaNetworkPolicy": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPolicy == NetworkPolicyAntrea
+		},
+		// HasFlannelNetworkPlugin returns true if the network plugin is flannel
+		"HasFlannelNetworkPlugin": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin == NetworkPluginFlannel
+		},
+		// WindowsSSHEnabled returns true if OpenSSH is installed on Windows nodes
+		"WindowsSSHEnabled": func() bool {
+			return cs.Properties.WindowsProfile.SSHEnabled
+		},
+		// EnableEncryptionWithExternalKms returns true if the cluster encrypts secrets with an external KMS
+		"EnableEncryptionWithExternalKms": func() bool {
+			return to.Bool(cs.Properties.OrchestratorProfile.KubernetesConfig.EnableEncryptionWithExternalKms)
+		},
+		// IsIPv6DualStackFeatureEnabled returns true if the EnableIPv6DualStack feature flag is set
+		"IsIPv6DualStackFeatureEnabled": func() bool {
+			return cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack")
+		},
+		// GetBase64EncodedEnvironmentJSON returns the base64 encoded custom cloud environment
+		"GetBase64EncodedEnvironmentJSON": func() string {
+			customEnvironmentJSON, _ := cs.Properties.GetCustomEnvironmentJSON(false)
+			return base64.StdEncoding.EncodeToString([]byte(customEnvironmentJSON))
+		},
+		// GetIdentitySystem returns the identity system, adfs on Azure Stack clouds using it
+		"GetIdentitySystem": func() string {
+			if cs.Properties.IsAzureStackCloud() {
+				return cs.Properties.CustomCloudProfile.IdentitySystem
+			}
+
+			return api.AzureADIdentitySystem
+		},
+		// GetPodInfraContainerSpec returns the pause image
+		"GetPodInfraContainerSpec": func() string {
+			return cs.Properties.OrchestratorProfile.GetPodInfraContainerSpec()
+		},
+		// IsKubenet returns true if the network plugin is kubenet
+		"IsKubenet": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin == NetworkPluginKubenet
+		},
+		// NeedsContainerd returns true if the container runtime requires containerd
+		"NeedsContainerd": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.NeedsContainerd()
+		},
+		// IsKataContainerRuntime returns true if the container runtime is kata containers
+		"IsKataContainerRuntime": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.ContainerRuntime == api.KataContainers
+		},
+		// IsDockerContainerRuntime returns true if the container runtime is docker
+		"IsDockerContainerRuntime": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.ContainerRuntime == api.Docker
+		},
+		// HasNSeriesSKU returns true if any agent pool VM size has an Nvidia GPU
+		"HasNSeriesSKU": func() bool {
+			return cs.Properties.HasNSeriesSKU()
+		},
+		// HasDCSeriesSKU returns true if any agent pool VM size supports SGX
+		"HasDCSeriesSKU": func() bool {
+			return cs.Properties.HasDCSeriesSKU()
+		},
+		// HasCoreOS returns true if any pool runs CoreOS
+		"HasCoreOS": func() bool {
+			return cs.Properties.HasCoreOS()
+		},
+		// GetHyperkubeImageReference returns the hyperkube image
+		"GetHyperkubeImageReference": func() string {
+			hyperkubeImageBase := cs.Properties.OrchestratorProfile.KubernetesConfig.KubernetesImageBase
+			k8sComponents := api.K8sComponentsByVersionMap[cs.Properties.OrchestratorProfile.OrchestratorVersion]
+			hyperkubeImage := hyperkubeImageBase + k8sComponents["hyperkube"]
+			if cs.Properties.IsAzureStackCloud() {
+				hyperkubeImage = hyperkubeImage + AzureStackSuffix
+			}
+			if cs.Properties.OrchestratorProfile.KubernetesConfig.CustomHyperkubeImage != "" {
+				hyperkubeImage = cs.Properties.OrchestratorProfile.KubernetesConfig.CustomHyperkubeImage
+			}
+			return hyperkubeImage
+		},
+		// GetTargetEnvironment returns the Azure cloud name of the cluster location
+		"GetTargetEnvironment": func() string {
+			return GetCloudTargetEnv(cs.Location)
+		},
+		// GetCustomCloudConfigCSEScriptFilepath returns the path the custom cloud CSE script is written to
+		"GetCustomCloudConfigCSEScriptFilepath": func() string {
+			return customCloudConfigCSEScriptFilepath
+		},
+		// GetCSEHelpersScriptFilepath returns the path the CSE helpers script is written to
+		"GetCSEHelpersScriptFilepath": func() string {
+			return cseHelpersScriptFilepath
+		},
+		// GetProtectedSettingsFilepath returns the path of the CSE protected settings
+		"GetProtectedSettingsFilepath": func() string {
+			return LinuxProtectedSettingsFilePath
+		},
+		// GetCSEInstallScriptFilepath returns the path the CSE install script is written to
+		"GetCSEInstallScriptFilepath": func() string {
+			return cseInstallScriptFilepath
+		},
+		// GetCSEConfigScriptFilepath returns the path the CSE config script is written to
+		"GetCSEConfigScriptFilepath": func() string {
+			return cseConfigScriptFilepath
+		},
+		// GetCustomSearchDomainsCSEScriptFilepath returns the path the custom search domain script is written to
+		"GetCustomSearchDomainsCSEScriptFilepath": func() string {
+			return customSearchDomainsCSEScriptFilepath
+		},
+		// GetDHCPv6ServiceCSEScriptFilepath returns the path the DHCPv6 service unit is written to
+		"GetDHCPv6ServiceCSEScriptFilepath": func() string {
+			return dhcpV6ServiceCSEScriptFilepath
+		},
+		// GetDHCPv6ConfigCSEScriptFilepath returns the path the DHCPv6 config script is written to
+		"GetDHCPv6ConfigCSEScriptFilepath": func() string {
+			return dhcpV6ConfigCSEScriptFilepath
+		},
+		// HasPrivateAzureRegistryServer returns true if images are pulled from a private registry
+		"HasPrivateAzureRegistryServer": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateAzureRegistryServer != ""
+		},
+		// GetPrivateAzureRegistryServer returns the private registry images are pulled from
+		"GetPrivateAzureRegistryServer": func() string {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateAzureRegistryServer
+		},
+		// OpenBraces returns {{ for templates rendering go templates
+		"OpenBraces": func() string {
+			return "{{"
+		},
+		// CloseBraces returns }} for templates rendering go templates
+		"CloseBraces": func() string {
+			return "}}"
+		},
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestGetContainerServiceFuncMap(t *testing.T) {
+	cs := loadGoldenContainerService(t, filepath.Join(goldenDir, "custom-search-domain", "apimodel.json"))
+	config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+	if err != nil {
+		t.Fatalf("converting the api model: %v", err)
+	}
+	config.KubernetesConfig.NetworkPlugin = NetworkPluginKubenet
+	config.KubernetesConfig.PrivateAzureRegistryServer = "registry.azurecr.io"
+	funcMap := getContainerServiceFuncMap(config)
+
+	boolFuncs := map[string]bool{
+		"IsKubenet":                     true,
+		"IsAzureCNI":                    false,
+		"HasCustomSearchDomain":         true,
+		"HasPrivateAzureRegistryServer": true,
+		"IsAzureStackCloud":             false,
+	}
+	for name, expected := range boolFuncs {
+		if actual := funcMap[name].(func() bool)(); actual != expected {
+			t.Errorf("expected %s to return %t, got %t", name, expected, actual)
+		}
+	}
+
+	stringFuncs := map[string]string{
+		"GetSearchDomainName":           cs.Properties.LinuxProfile.CustomSearchDomain.Name,
+		"GetPrivateAzureRegistryServer": "registry.azurecr.io",
+		"GetIdentitySystem":             api.AzureADIdentitySystem,
+		"GetProtectedSettingsFilepath":  LinuxProtectedSettingsFilePath,
+		"OpenBraces":                    "{{",
+		"CloseBraces":                   "}}",
+	}
+	for name, expected := range stringFuncs {
+		if actual := funcMap[name].(func() string)(); actual != expected {
+			t.Errorf("expected %s to return %q, got %q", name, expected, actual)
+		}
+	}
+
+	isKubernetesVersionGe := funcMap["IsKubernetesVersionGe"].(func(string) bool)
+	if !isKubernetesVersionGe("1.10.0") || isKubernetesVersionGe("9.0.0") {
+		t.Errorf("expected IsKubernetesVersionGe to compare against %s", config.KubernetesVersion)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+)
+
+// update rewrites the golden files instead of comparing against them: go test ./pkg/agent -run TestGolden -update
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+const goldenDir = "testdata/golden"
+
+// TestGolden renders every api model in testdata/golden/<case>/apimodel.json and compares the output for each
+// agent pool against testdata/golden/<case>/<pool>. CustomData and CSECommand hold the ARM expressions, the files
+// written by the customData are decoded into files/ so template changes can be reviewed as text
+func TestGolden(t *testing.T) {
+	cases, err := ioutil.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatalf("reading %s: %v", goldenDir, err)
+	}
+	tg := InitializeTemplateGenerator()
+	for _, c := range cases {
+		if !c.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(goldenDir, c.Name())
+		t.Run(c.Name(), func(t *testing.T) {
+			cs := loadGoldenContainerService(t, filepath.Join(caseDir, "apimodel.json"))
+			for _, profile := range cs.Properties.AgentPoolProfiles {
+				config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile, "tenant", "sub", "rg", "")
+				if err != nil {
+					t.Fatalf("%s: converting the api model: %v", profile.Name, err)
+				}
+				actual := renderGoldenFiles(t, tg, config)
+				poolDir := filepath.Join(caseDir, profile.Name)
+				if *update {
+					writeGoldenFiles(t, poolDir, actual)
+					continue
+				}
+				compareGoldenFiles(t, poolDir, actual)
+			}
+		})
+	}
+}
+
+func loadGoldenContainerService(t testing.TB, path string) *api.ContainerService {
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{},
+	}
+	cs, _, err := apiloader.LoadContainerServiceFromFile(path, false, false, nil)
+	if err != nil {
+		t.Fatalf("loading %s: %v", path, err)
+	}
+	if _, err := cs.SetPropertiesDefaults(api.PropertiesDefaultsParams{
+		IsScale:    false,
+		IsUpgrade:  false,
+		PkiKeySize: helpers.DefaultPkiKeySize,
+	}); err != nil {
+		t.Fatalf("setting defaults for %s: %v", path, err)
+	}
+	return cs
+}
+
+// renderGoldenFiles returns the golden file contents keyed by their path relative to the agent pool directory
+func renderGoldenFiles(t *testing.T, tg *TemplateGenerator, config *NodeBootstrappingConfiguration) map[string]string {
+	customData, err := tg.GetNodeBootstrappingPayloadFromConfig(config)
+	if err != nil {
+		t.Fatalf("%s: rendering customData: %v", config.AgentPoolProfile.Name, err)
+	}
+	cseCmd, err := tg.GetNodeBootstrappingCmdFromConfig(config)
+	if err != nil {
+		t.Fatalf("%s: rendering the CSE command: %v", config.AgentPoolProfile.Name, err)
+	}
+	files := map[string]string{
+		"CustomData": customData,
+		"CSECommand": cseCmd,
+	}
+
+	nodeBootstrapping, err := tg.GetNodeBootstrapping(config)
+	if err != nil {
+		t.Fatalf("%s: rendering plain customData: %v", config.AgentPoolProfile.Name, err)
+	}
+	decoded, err := DecodeCustomData([]byte(nodeBootstrapping.CustomData))
+	if err != nil {
+		t.Fatalf("%s: decoding plain customData: %v", config.AgentPoolProfile.Name, err)
+	}
+	for _, f := range decoded {
+		files[filepath.Join("files", filepath.FromSlash(f.RelativePath()))] = string(f.Content)
+	}
+	return files
+}
+
+func writeGoldenFiles(t *testing.T, dir string, files map[string]string) {
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("removing %s: %v", dir, err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating %s: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", path, err)
+		}
+	}
+}
+
+func compareGoldenFiles(t *testing.T, dir string, files map[string]string) {
+	expected := map[string]bool{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		expected[name] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading golden files in %s: %v, run with -update to create them", dir, err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if !expected[name] {
+			t.Errorf("%s: unexpected file, run with -update if the change is intended", path)
+			continue
+		}
+		delete(expected, name)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading %s: %v", path, err)
+		}
+		if !bytes.Equal(b, []byte(files[name])) {
+			t.Errorf("%s: differs from the rendered output, run with -update and review the diff if the change is intended", path)
+		}
+	}
+	for name := range expected {
+		t.Errorf("%s: not rendered anymore, run with -update if the change is intended", filepath.Join(dir, name))
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+const (
+	// keyVaultSecretAPIVersion is the Key Vault data plane API version the nodes get secrets with
+	keyVaultSecretAPIVersion = "7.0"
+	// windowsIMDSTokenURLFormat is the Instance Metadata Service endpoint issuing managed identity tokens for a resource
+	windowsIMDSTokenURLFormat = "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=%s"
+)
+
+// nodeKeyVaultSecretParameters are the parameters of secrets in the CSE command, the CSE fetches them at boot
+// when they are Key Vault references
+var nodeKeyVaultSecretParameters = []string{"servicePrincipalClientSecret", "clientPrivateKey"}
+
+// keyVaultEnvironment returns the Azure environment of the configuration's cloud, the public cloud if it is unknown
+func (config *NodeBootstrappingConfiguration) keyVaultEnvironment() azure.Environment {
+	if config.isAzureStackCloud() && config.CustomCloudProfile.Environment != nil {
+		return *config.CustomCloudProfile.Environment
+	}
+	env, err := azure.EnvironmentFromName(config.cloudSpecConfig().CloudName)
+	if err != nil {
+		return azure.PublicCloud
+	}
+	return env
+}
+
+// getKeyVaultSecretURL returns the data plane URL of the Key Vault secret ref, empty if ref is nil
+func getKeyVaultSecretURL(config *NodeBootstrappingConfiguration, ref *KeyVaultRef) string {
+	if ref == nil {
+		return ""
+	}
+	vaultID := strings.TrimSuffix(ref.KeyVault.ID, "/")
+	vaultName := vaultID[strings.LastIndex(vaultID, "/")+1:]
+	url := fmt.Sprintf("https://%s.%s/secrets/%s", vaultName, config.keyVaultEnvironment().KeyVaultDNSSuffix, ref.SecretName)
+	if ref.SecretVersion != "" {
+		url += "/" + ref.SecretVersion
+	}
+	return url
+}
+
+// hasNodeKeyVaultSecrets returns true if a secret of the CSE command is a Key Vault reference
+func hasNodeKeyVaultSecrets(parameters paramsMap) bool {
+	for _, k := range nodeKeyVaultSecretParameters {
+		if getKeyvaultReference(parameters, k) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// getWindowsKeyVaultSecretStatement returns the PowerShell statement setting variable to the Key Vault secret of the
+// parameter k with the node's managed identity, and the expression inserting variable into the quoted script arguments.
+// Both are empty if the parameter is not a Key Vault reference
+func getWindowsKeyVaultSecretStatement(config *NodeBootstrappingConfiguration, parameters paramsMap, k, variable string, encode bool) (string, string) {
+	secretURL := getKeyVaultSecretURL(config, getKeyvaultReference(parameters, k))
+	if secretURL == "" {
+		return "", ""
+	}
+	// the Key Vault resource of the cloud, as get_keyvault_secret derives it on Linux nodes
+	tokenURL := fmt.Sprintf(windowsIMDSTokenURLFormat, "https://"+config.keyVaultEnvironment().KeyVaultDNSSuffix)
+	if config.Identity.UserAssignedIdentityClientID != "" {
+		tokenURL += "&client_id=" + config.Identity.UserAssignedIdentityClientID
+	}
+	value := fmt.Sprintf("(Invoke-RestMethod -Uri '%s?api-version=%s' -Headers @{Authorization = 'Bearer ' + (Invoke-RestMethod -Uri '%s' -Headers @{Metadata = 'true'}).access_token}).value",
+		secretURL, keyVaultSecretAPIVersion, tokenURL)
+	if encode {
+		value = fmt.Sprintf("[Convert]::ToBase64String([Text.Encoding]::UTF8.GetBytes(%s))", value)
+	}
+	return fmt.Sprintf("$%s = %s ; ", variable, value), fmt.Sprintf("' + $%s + '", variable)
+}
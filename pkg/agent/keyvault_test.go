@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKeyVaultID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/myvault"
+
+func TestKeyVaultSecretsParameters(t *testing.T) {
+	cs := loadGoldenContainerService(t, filepath.Join(goldenDir, "custom-search-domain", "apimodel.json"))
+	cs.Properties.CertificateProfile.CaPrivateKey = "literal-ca-private-key"
+	cs.Properties.CertificateProfile.KubeConfigPrivateKey = "literal-kubeconfig-private-key"
+	cs.Properties.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey = "literal-etcd-encryption-key"
+	config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clientPrivateKey := config.CertificateProfile.ClientPrivateKey
+	config.KeyVaultSecrets = &NodeKeyVaultSecrets{
+		ClientPrivateKey:     &KeyVaultRef{KeyVault: KeyVaultID{ID: testKeyVaultID}, SecretName: "client-key", SecretVersion: "v1"},
+		CaPrivateKey:         &KeyVaultRef{KeyVault: KeyVaultID{ID: testKeyVaultID}, SecretName: "ca-key"},
+		KubeConfigPrivateKey: &KeyVaultRef{KeyVault: KeyVaultID{ID: testKeyVaultID}, SecretName: "kubeconfig-key"},
+		EtcdEncryptionKey:    &KeyVaultRef{KeyVault: KeyVaultID{ID: testKeyVaultID}, SecretName: "etcd-key"},
+	}
+
+	parameters := getParameters(config, "", "")
+	expected := map[string]string{
+		"clientPrivateKey":     "client-key",
+		"caPrivateKey":         "ca-key",
+		"kubeConfigPrivateKey": "kubeconfig-key",
+		"etcdEncryptionKey":    "etcd-key",
+	}
+	for k, secretName := range expected {
+		ref := getKeyvaultReference(parameters, k)
+		if ref == nil {
+			t.Errorf("expected %s to be a Key Vault reference, got %v", k, parameters[k])
+			continue
+		}
+		if ref.KeyVault.ID != testKeyVaultID || ref.SecretName != secretName {
+			t.Errorf("expected %s to reference %s, got %+v", k, secretName, ref)
+		}
+	}
+
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, literal := range []string{clientPrivateKey, "literal-ca-private-key", "literal-kubeconfig-private-key", "literal-etcd-encryption-key"} {
+		if strings.Contains(string(data), literal) {
+			t.Errorf("expected the parameters not to hold the literal secret %q", literal)
+		}
+	}
+
+	if !hasNodeKeyVaultSecrets(parameters) {
+		t.Errorf("expected the CSE to fetch Key Vault secrets")
+	}
+	if url := getKeyVaultSecretURL(config, getKeyvaultReference(parameters, "clientPrivateKey")); url != "https://myvault.vault.azure.net/secrets/client-key/v1" {
+		t.Errorf("unexpected secret URL %s", url)
+	}
+}
+
+func TestGetNodeBootstrappingKeyVaultSecrets(t *testing.T) {
+	tg := InitializeTemplateGenerator()
+	for _, c := range []string{"custom-search-domain", "windows"} {
+		cs := loadGoldenContainerService(t, filepath.Join(goldenDir, c, "apimodel.json"))
+		clientPrivateKey := cs.Properties.CertificateProfile.ClientPrivateKey
+		config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+		if err != nil {
+			t.Fatalf("%s: converting the api model: %v", c, err)
+		}
+		config.KeyVaultSecrets = &NodeKeyVaultSecrets{
+			ClientPrivateKey: &KeyVaultRef{KeyVault: KeyVaultID{ID: testKeyVaultID}, SecretName: "client-key"},
+		}
+
+		nodeBootstrapping, err := tg.GetNodeBootstrapping(config)
+		if err != nil {
+			t.Fatalf("%s: rendering: %v", c, err)
+		}
+		content, err := DecodeNodeBootstrapping(nodeBootstrapping)
+		if err != nil {
+			t.Fatalf("%s: decoding: %v", c, err)
+		}
+		texts := []string{content.Command}
+		for _, v := range content.Variables {
+			texts = append(texts, v)
+		}
+		for _, text := range texts {
+			for _, form := range []string{clientPrivateKey, base64.StdEncoding.EncodeToString([]byte(clientPrivateKey))} {
+				if strings.Contains(text, form) {
+					t.Errorf("%s: expected the client private key not to be in the CSE", c)
+				}
+			}
+		}
+
+		secretURL := "https://myvault.vault.azure.net/secrets/client-key"
+		if config.isWindows() {
+			if !strings.Contains(content.Command, "$agentKey = (Invoke-RestMethod -Uri '"+secretURL+"?api-version=7.0'") ||
+				content.Variables["AgentKey"] != "$agentKey" {
+				t.Errorf("%s: expected the CSE command to fetch the agent key, got %s", c, content.Command)
+			}
+			continue
+		}
+		if content.Variables["KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL"] != secretURL {
+			t.Errorf("%s: unexpected KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL %q", c, content.Variables["KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL"])
+		}
+		if !strings.Contains(string(content.Files["/opt/azure/containers/provision.sh"]), "\nfetchKeyVaultSecrets\n") {
+			t.Errorf("%s: expected provision.sh to fetch the Key Vault secrets", c)
+		}
+	}
+}
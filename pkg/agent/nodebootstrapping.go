@@ -0,0 +1,264 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// NodeBootstrappingConfigurationVersion is the current version of the NodeBootstrappingConfiguration schema
+const NodeBootstrappingConfigurationVersion = "v1"
+
+const (
+	// LinuxProtectedSettingsFilePath is where provision.sh reads the protected settings of Linux nodes from,
+	// shell variable assignments readable only by root
+	LinuxProtectedSettingsFilePath = "/opt/azure/containers/protected-settings.env"
+	// WindowsProtectedSettingsFilePath is where the CSE command reads the protected settings of Windows nodes from,
+	// a JSON object readable only by SYSTEM and the Administrators group
+	WindowsProtectedSettingsFilePath = `C:\AzureData\ProtectedSettings.json`
+)
+
+// NodeBootstrappingConfiguration holds everything that affects the bootstrap data of a node in a single agent pool.
+// It is the agentbaker-owned subset of an aks-engine ContainerService, see ConvertContainerServiceToNodeBootstrappingConfiguration.
+type NodeBootstrappingConfiguration struct {
+	// APIVersion is the version of this schema, see NodeBootstrappingConfigurationVersion
+	APIVersion string `json:"apiVersion"`
+	Location   string `json:"location"`
+	// ClusterID is the unique name suffix of the cluster resources
+	ClusterID         string `json:"clusterID"`
+	KubernetesVersion string `json:"kubernetesVersion"`
+	// KubernetesConfig holds the container runtime, network plugin and policy, component images and kubelet config
+	KubernetesConfig        *api.KubernetesConfig        `json:"kubernetesConfig"`
+	CertificateProfile      *api.CertificateProfile      `json:"certificateProfile,omitempty"`
+	ServicePrincipalProfile *api.ServicePrincipalProfile `json:"servicePrincipalProfile,omitempty"`
+	AADProfile              *api.AADProfile              `json:"aadProfile,omitempty"`
+	HostedMasterProfile     *api.HostedMasterProfile     `json:"hostedMasterProfile,omitempty"`
+	// VnetCIDR is the address space of the cluster VNet, DefaultVNETCIDR if empty
+	VnetCIDR            string                  `json:"vnetCidr,omitempty"`
+	LinuxProfile        *api.LinuxProfile       `json:"linuxProfile,omitempty"`
+	WindowsProfile      *api.WindowsProfile     `json:"windowsProfile,omitempty"`
+	AgentPoolProfile    *api.AgentPoolProfile   `json:"agentPoolProfile"`
+	ExtensionProfiles   []*api.ExtensionProfile `json:"extensionProfiles,omitempty"`
+	CustomCloudProfile  *api.CustomCloudProfile `json:"customCloudProfile,omitempty"`
+	FeatureFlags        *api.FeatureFlags       `json:"featureFlags,omitempty"`
+	TelemetryProfile    *api.TelemetryProfile   `json:"telemetryProfile,omitempty"`
+	CloudProviderConfig CloudProviderConfig     `json:"cloudProviderConfig"`
+	Identity            NodeIdentity            `json:"identity"`
+	// KeyVaultSecrets replace secrets of the profiles with Key Vault references, see NodeKeyVaultSecrets
+	KeyVaultSecrets *NodeKeyVaultSecrets `json:"keyVaultSecrets,omitempty"`
+}
+
+// CloudProviderConfig holds the cluster level Azure resource names written to the node's azure.json
+type CloudProviderConfig struct {
+	VMType                          string `json:"vmType"`
+	SubnetName                      string `json:"subnetName"`
+	NSGName                         string `json:"nsgName"`
+	VirtualNetworkName              string `json:"virtualNetworkName"`
+	VirtualNetworkResourceGroupName string `json:"virtualNetworkResourceGroupName,omitempty"`
+	RouteTableName                  string `json:"routeTableName"`
+	PrimaryAvailabilitySetName      string `json:"primaryAvailabilitySetName,omitempty"`
+	PrimaryScaleSetName             string `json:"primaryScaleSetName,omitempty"`
+}
+
+// NodeIdentity holds the Azure identity the node runs under
+type NodeIdentity struct {
+	TenantID                     string `json:"tenantID"`
+	SubscriptionID               string `json:"subscriptionID"`
+	ResourceGroupName            string `json:"resourceGroupName"`
+	UserAssignedIdentityClientID string `json:"userAssignedIdentityClientID,omitempty"`
+}
+
+// NodeKeyVaultSecrets are Key Vault references to secrets of the configuration, a reference replaces the literal
+// secret so it never enters the node bootstrapping artifacts. The ARM parameters hold the reference and the CSE
+// fetches the secrets the node needs at boot with the node's managed identity. A secret holds the value of its
+// parameter, e.g. the base64 encoded PEM of a private key
+type NodeKeyVaultSecrets struct {
+	// ClientPrivateKey replaces CertificateProfile.ClientPrivateKey, the kubelet client key
+	ClientPrivateKey *KeyVaultRef `json:"clientPrivateKey,omitempty"`
+	// CaPrivateKey replaces CertificateProfile.CaPrivateKey
+	CaPrivateKey *KeyVaultRef `json:"caPrivateKey,omitempty"`
+	// KubeConfigPrivateKey replaces CertificateProfile.KubeConfigPrivateKey
+	KubeConfigPrivateKey *KeyVaultRef `json:"kubeConfigPrivateKey,omitempty"`
+	// EtcdEncryptionKey replaces KubernetesConfig.EtcdEncryptionKey
+	EtcdEncryptionKey *KeyVaultRef `json:"etcdEncryptionKey,omitempty"`
+}
+
+// ConvertContainerServiceToNodeBootstrappingConfiguration converts an aks-engine ContainerService and one of its
+// agent pools into a NodeBootstrappingConfiguration. Cluster level names are resolved against the whole
+// ContainerService so the result no longer depends on the master profile or the other agent pools. Everything
+// that describes the node itself, such as VHD, GPU and Windows specific content, is rendered from profile alone.
+func ConvertContainerServiceToNodeBootstrappingConfiguration(cs *api.ContainerService, profile *api.AgentPoolProfile,
+	tenantID, subscriptionID, resourceGroupName, userAssignedIdentityClientID string) (*NodeBootstrappingConfiguration, error) {
+	if cs == nil || cs.Properties == nil {
+		return nil, errors.New("container service properties must not be nil")
+	}
+	if profile == nil {
+		return nil, errors.New("agent pool profile must not be nil")
+	}
+	properties := cs.Properties
+	if properties.OrchestratorProfile == nil || !properties.OrchestratorProfile.IsKubernetes() {
+		return nil, errors.New("node bootstrapping is only supported for the Kubernetes orchestrator")
+	}
+
+	return &NodeBootstrappingConfiguration{
+		APIVersion:              NodeBootstrappingConfigurationVersion,
+		Location:                cs.Location,
+		ClusterID:               properties.GetClusterID(),
+		KubernetesVersion:       properties.OrchestratorProfile.OrchestratorVersion,
+		KubernetesConfig:        properties.OrchestratorProfile.KubernetesConfig,
+		CertificateProfile:      properties.CertificateProfile,
+		ServicePrincipalProfile: properties.ServicePrincipalProfile,
+		AADProfile:              properties.AADProfile,
+		HostedMasterProfile:     properties.HostedMasterProfile,
+		LinuxProfile:            properties.LinuxProfile,
+		WindowsProfile:          properties.WindowsProfile,
+		AgentPoolProfile:        profile,
+		ExtensionProfiles:       properties.ExtensionProfiles,
+		CustomCloudProfile:      properties.CustomCloudProfile,
+		FeatureFlags:            properties.FeatureFlags,
+		TelemetryProfile:        properties.TelemetryProfile,
+		CloudProviderConfig: CloudProviderConfig{
+			VMType:                          properties.GetVMType(),
+			SubnetName:                      properties.GetSubnetName(),
+			NSGName:                         properties.GetNSGName(),
+			VirtualNetworkName:              properties.GetVirtualNetworkName(),
+			VirtualNetworkResourceGroupName: properties.GetVNetResourceGroupName(),
+			RouteTableName:                  properties.GetRouteTableName(),
+			PrimaryAvailabilitySetName:      properties.GetPrimaryAvailabilitySetName(),
+			PrimaryScaleSetName:             properties.GetPrimaryScaleSetName(),
+		},
+		Identity: NodeIdentity{
+			TenantID:                     tenantID,
+			SubscriptionID:               subscriptionID,
+			ResourceGroupName:            resourceGroupName,
+			UserAssignedIdentityClientID: userAssignedIdentityClientID,
+		},
+	}, nil
+}
+
+// properties returns an aks-engine Properties view scoped to the node's agent pool,
+// it carries no master profile and no other agent pools.
+func (config *NodeBootstrappingConfiguration) properties() *api.Properties {
+	return &api.Properties{
+		ClusterID:               config.ClusterID,
+		OrchestratorProfile:     config.orchestratorProfile(),
+		AgentPoolProfiles:       []*api.AgentPoolProfile{config.AgentPoolProfile},
+		LinuxProfile:            config.LinuxProfile,
+		WindowsProfile:          config.WindowsProfile,
+		ExtensionProfiles:       config.ExtensionProfiles,
+		ServicePrincipalProfile: config.ServicePrincipalProfile,
+		CertificateProfile:      config.CertificateProfile,
+		AADProfile:              config.AADProfile,
+		HostedMasterProfile:     config.HostedMasterProfile,
+		FeatureFlags:            config.FeatureFlags,
+		CustomCloudProfile:      config.CustomCloudProfile,
+		TelemetryProfile:        config.TelemetryProfile,
+	}
+}
+
+func (config *NodeBootstrappingConfiguration) orchestratorProfile() *api.OrchestratorProfile {
+	return &api.OrchestratorProfile{
+		OrchestratorType:    api.Kubernetes,
+		OrchestratorVersion: config.KubernetesVersion,
+		KubernetesConfig:    config.KubernetesConfig,
+	}
+}
+
+// containerService returns an aks-engine ContainerService view scoped to the node's agent pool
+func (config *NodeBootstrappingConfiguration) containerService() *api.ContainerService {
+	return &api.ContainerService{
+		Location:   config.Location,
+		Properties: config.properties(),
+	}
+}
+
+func (config *NodeBootstrappingConfiguration) cloudSpecConfig() api.AzureEnvironmentSpecConfig {
+	return config.containerService().GetCloudSpecConfig()
+}
+
+func (config *NodeBootstrappingConfiguration) isAzureStackCloud() bool {
+	return config.CustomCloudProfile != nil
+}
+
+func (config *NodeBootstrappingConfiguration) isHostedMaster() bool {
+	return config.HostedMasterProfile != nil
+}
+
+func (config *NodeBootstrappingConfiguration) isWindows() bool {
+	return config.AgentPoolProfile != nil && config.AgentPoolProfile.IsWindows()
+}
+
+// outputFormat selects how the generated node bootstrapping artifacts are encoded
+type outputFormat int
+
+const (
+	// outputFormatARM embeds artifacts in ARM template expressions, e.g. [base64(concat('...'))]
+	outputFormatARM outputFormat = iota
+	// outputFormatPlain returns the artifacts as-is for SDK or Terraform based provisioning
+	outputFormatPlain
+)
+
+// NodeBootstrapping holds the plain node bootstrapping artifacts, they contain no ARM template expressions
+type NodeBootstrapping struct {
+	// CustomData is the cloud-init document for Linux nodes or the setup script for Windows nodes,
+	// it is not base64 encoded
+	CustomData string `json:"customData"`
+	// CSE is the custom script extension command that provisions the node
+	CSE *NodeBootstrappingCSE `json:"cse"`
+	// ContentHash is the hex encoded SHA-256 of CustomData and CSE, identical configurations have identical
+	// artifacts and so identical hashes, see Hash
+	ContentHash string `json:"contentHash"`
+}
+
+// Hash returns the hex encoded SHA-256 of CustomData and of CSE as JSON, the JSON of CSE has its map keys sorted
+func (n *NodeBootstrapping) Hash() (string, error) {
+	cseJSON, err := json.Marshal(n.CSE)
+	if err != nil {
+		return "", errors.Wrap(err, "hashing the CSE command")
+	}
+	return HashContent([]byte(n.CustomData), cseJSON), nil
+}
+
+// HashContent returns the hex encoded SHA-256 of the concatenated parts, each part is prefixed with its length so
+// moving bytes from a part to the next changes the hash
+func HashContent(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(part)))
+		h.Write(length[:])
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NodeBootstrappingCSE is a custom script extension command split into its environment and its invocation
+type NodeBootstrappingCSE struct {
+	// Environment holds the variables read by the provisioning script, Command must run with them set.
+	// It is empty for Windows nodes which take their settings as script arguments.
+	Environment map[string]string `json:"environment"`
+	// Command is the script invocation
+	Command string `json:"command"`
+	// ProtectedSettings are the secrets the provisioning script reads from ProtectedSettingsFile instead of
+	// Environment or Command, they are only set by GetNodeBootstrappingCmdWithProtectedSettings
+	ProtectedSettings map[string]string `json:"protectedSettings,omitempty"`
+	// ProtectedSettingsFile is ProtectedSettings as the file to write on the node before Command runs
+	ProtectedSettingsFile *NodeBootstrappingFile `json:"protectedSettingsFile,omitempty"`
+}
+
+// NodeBootstrappingFile is a file to write on the node
+type NodeBootstrappingFile struct {
+	// Path is the absolute path of the file on the node
+	Path string `json:"path"`
+	// Permissions are the octal permissions of a file owned by root, empty on Windows where the file must only be
+	// readable by the Administrators group
+	Permissions string `json:"permissions,omitempty"`
+	Content     string `json:"content"`
+}
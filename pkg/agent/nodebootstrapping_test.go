@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestConvertContainerServiceToNodeBootstrappingConfiguration(t *testing.T) {
+	profile := &api.AgentPoolProfile{
+		Name:   "agentpool1",
+		VMSize: "Standard_DS2_v2",
+		OSType: api.Linux,
+	}
+	cs := &api.ContainerService{
+		Location: "westus2",
+		Properties: &api.Properties{
+			ClusterID: "12345678",
+			OrchestratorProfile: &api.OrchestratorProfile{
+				OrchestratorType:    api.Kubernetes,
+				OrchestratorVersion: "1.16.9",
+				KubernetesConfig:    &api.KubernetesConfig{},
+			},
+			HostedMasterProfile: &api.HostedMasterProfile{
+				FQDN:      "abc.aks.com",
+				DNSPrefix: "abc",
+			},
+			AgentPoolProfiles: []*api.AgentPoolProfile{profile},
+		},
+	}
+
+	config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile, "tenant", "sub", "rg", "msi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.APIVersion != NodeBootstrappingConfigurationVersion {
+		t.Errorf("expected apiVersion %s, got %s", NodeBootstrappingConfigurationVersion, config.APIVersion)
+	}
+	if config.KubernetesVersion != "1.16.9" || config.Location != "westus2" || config.ClusterID != "12345678" {
+		t.Errorf("unexpected cluster fields %+v", config)
+	}
+	if config.AgentPoolProfile != profile {
+		t.Errorf("expected the agent pool profile to be carried over")
+	}
+	if config.CloudProviderConfig.SubnetName != cs.Properties.GetSubnetName() ||
+		config.CloudProviderConfig.RouteTableName != cs.Properties.GetRouteTableName() ||
+		config.CloudProviderConfig.VMType != cs.Properties.GetVMType() {
+		t.Errorf("unexpected cloud provider config %+v", config.CloudProviderConfig)
+	}
+	expectedIdentity := NodeIdentity{
+		TenantID:                     "tenant",
+		SubscriptionID:               "sub",
+		ResourceGroupName:            "rg",
+		UserAssignedIdentityClientID: "msi",
+	}
+	if config.Identity != expectedIdentity {
+		t.Errorf("expected identity %+v, got %+v", expectedIdentity, config.Identity)
+	}
+}
+
+// TestConvertContainerServiceToNodeBootstrappingConfigurationMixedPools checks every pool of a cluster mixing
+// VHD, non-VHD GPU and Windows pools is rendered from its own profile while sharing the cluster level config
+func TestConvertContainerServiceToNodeBootstrappingConfigurationMixedPools(t *testing.T) {
+	cs := loadGoldenContainerService(t, filepath.Join(goldenDir, "mixed-pools", "apimodel.json"))
+	tg := InitializeTemplateGenerator()
+	configs := map[string]*NodeBootstrappingConfiguration{}
+	for _, profile := range cs.Properties.AgentPoolProfiles {
+		config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile, "tenant", "sub", "rg", "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", profile.Name, err)
+		}
+		configs[profile.Name] = config
+	}
+
+	cases := []struct {
+		pool    string
+		nonVHD  bool
+		gpu     bool
+		windows bool
+	}{
+		{pool: "vhd1"},
+		{pool: "gpu1", nonVHD: true, gpu: true},
+		{pool: "win1", windows: true},
+	}
+	for _, c := range cases {
+		config := configs[c.pool]
+		if config == nil {
+			t.Fatalf("%s: missing agent pool", c.pool)
+		}
+		if config.CloudProviderConfig != configs["vhd1"].CloudProviderConfig {
+			t.Errorf("%s: expected the cluster level cloud provider config %+v, got %+v", c.pool, configs["vhd1"].CloudProviderConfig, config.CloudProviderConfig)
+		}
+
+		parameters, err := tg.GetNodeBootstrappingParameters(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.pool, err)
+		}
+		if _, ok := parameters["windowsAdminUsername"]; ok != c.windows {
+			t.Errorf("%s: expected the windowsAdminUsername parameter to be set %t, got %t", c.pool, c.windows, ok)
+		}
+		if c.windows {
+			continue
+		}
+
+		nodeBootstrapping, err := tg.GetNodeBootstrapping(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.pool, err)
+		}
+		files, err := DecodeCustomData([]byte(nodeBootstrapping.CustomData))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.pool, err)
+		}
+		var hasAptPreferences, installsGPUDrivers bool
+		for _, f := range files {
+			switch f.Path {
+			case "/etc/apt/preferences":
+				hasAptPreferences = true
+			case "/opt/azure/containers/provision.sh":
+				installsGPUDrivers = strings.Contains(string(f.Content), "installGPUDrivers")
+			}
+		}
+		if hasAptPreferences != c.nonVHD {
+			t.Errorf("%s: expected the non VHD files to be written %t, got %t", c.pool, c.nonVHD, hasAptPreferences)
+		}
+		if installsGPUDrivers != c.gpu {
+			t.Errorf("%s: expected the GPU drivers to be installed %t, got %t", c.pool, c.gpu, installsGPUDrivers)
+		}
+	}
+}
+
+func TestConvertContainerServiceToNodeBootstrappingConfigurationErrors(t *testing.T) {
+	profile := &api.AgentPoolProfile{Name: "agentpool1"}
+	cases := []struct {
+		name    string
+		cs      *api.ContainerService
+		profile *api.AgentPoolProfile
+	}{
+		{
+			name:    "nil container service",
+			profile: profile,
+		},
+		{
+			name: "nil profile",
+			cs: &api.ContainerService{
+				Properties: &api.Properties{
+					OrchestratorProfile: &api.OrchestratorProfile{OrchestratorType: api.Kubernetes},
+				},
+			},
+		},
+		{
+			name: "non kubernetes orchestrator",
+			cs: &api.ContainerService{
+				Properties: &api.Properties{
+					OrchestratorProfile: &api.OrchestratorProfile{OrchestratorType: api.DCOS},
+				},
+			},
+			profile: profile,
+		},
+	}
+	for _, c := range cases {
+		if _, err := ConvertContainerServiceToNodeBootstrappingConfiguration(c.cs, c.profile, "", "", "", ""); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestGetNodeBootstrappingCmdFromConfigInvalid(t *testing.T) {
+	tg := InitializeTemplateGenerator()
+	if _, err := tg.GetNodeBootstrappingCmdFromConfig(&NodeBootstrappingConfiguration{}); err == nil {
+		t.Fatalf("expected an error for a configuration without an agent pool profile")
+	}
+	if _, err := tg.GetNodeBootstrappingPayloadFromConfig(nil); err == nil {
+		t.Fatalf("expected an error for a nil configuration")
+	}
+}
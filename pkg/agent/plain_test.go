@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestGetNodeBootstrappingCSEFromCommand(t *testing.T) {
+	cmd := `echo $(date),$(hostname);
+for i in $(seq 1 1200); do
+done;
+ADMINUSER=azureuser
+CONTAINERD_VERSION=
+SERVICE_PRINCIPAL_CLIENT_SECRET='a b'
+AUDITD_ENABLED=false
+/usr/bin/nohup /bin/bash -c "/bin/bash /opt/azure/containers/provision.sh"`
+
+	cse := getNodeBootstrappingCSEFromCommand(cmd)
+	expected := map[string]string{
+		"ADMINUSER":                       "azureuser",
+		"CONTAINERD_VERSION":              "",
+		"SERVICE_PRINCIPAL_CLIENT_SECRET": "a b",
+		"AUDITD_ENABLED":                  "false",
+	}
+	if len(cse.Environment) != len(expected) {
+		t.Fatalf("expected %d environment variables, got %v", len(expected), cse.Environment)
+	}
+	for k, v := range expected {
+		if cse.Environment[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, cse.Environment[k])
+		}
+	}
+	expectedCommand := `echo $(date),$(hostname); for i in $(seq 1 1200); do done; /usr/bin/nohup /bin/bash -c "/bin/bash /opt/azure/containers/provision.sh"`
+	if cse.Command != expectedCommand {
+		t.Errorf("expected command %q, got %q", expectedCommand, cse.Command)
+	}
+}
+
+func TestGetLabelResourceGroup(t *testing.T) {
+	cases := []struct {
+		rg       string
+		expected string
+	}{
+		{"rg", "rg"},
+		{"my(rg)", "my-rg-z"},
+		{"rg.", "rg.z"},
+		{strings.Repeat("a", 70), strings.Repeat("a", 63)},
+		{strings.Repeat("a", 62) + "_b", strings.Repeat("a", 62) + "z"},
+	}
+	for _, c := range cases {
+		config := &NodeBootstrappingConfiguration{Identity: NodeIdentity{ResourceGroupName: c.rg}}
+		if actual := getLabelResourceGroup(config, outputFormatPlain); actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.rg, c.expected, actual)
+		}
+		if actual := getLabelResourceGroup(config, outputFormatARM); actual != "',variables('labelResourceGroup'),'" {
+			t.Errorf("%s: expected an ARM variable reference, got %s", c.rg, actual)
+		}
+	}
+}
+
+func TestGetWindowsNodeCSE(t *testing.T) {
+	config := &NodeBootstrappingConfiguration{
+		Location:            "westus2",
+		HostedMasterProfile: &api.HostedMasterProfile{DNSPrefix: "ABC"},
+	}
+	parameters := paramsMap{}
+	addValue(parameters, "kubernetesEndpoint", "abc.aks.com")
+	addValue(parameters, "servicePrincipalClientSecret", "secret")
+
+	cse := getWindowsNodeCSE(config, parameters)
+	if len(cse.Environment) != 0 {
+		t.Errorf("expected no environment variables, got %v", cse.Environment)
+	}
+	for _, s := range []string{"-MasterIP abc.aks.com ", "-MasterFQDNPrefix abc ", "-Location westus2 ", "-AADClientSecret ''c2VjcmV0'' ", windowsCustomScriptSuffix} {
+		if !strings.Contains(cse.Command, s) {
+			t.Errorf("expected command to contain %q, got %s", s, cse.Command)
+		}
+	}
+	if strings.Contains(cse.Command, "parameters(") || strings.Contains(cse.Command, "variables(") {
+		t.Errorf("expected no ARM expressions, got %s", cse.Command)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// linuxProtectedSettingsFilePermissions makes the Linux protected settings file readable only by root
+	linuxProtectedSettingsFilePermissions = "0600"
+	// linuxProtectedSettingsRequiredVariable makes provision.sh fail with ERR_PROTECTED_SETTINGS_NOT_FOUND if the
+	// protected settings file is missing instead of provisioning without the secrets
+	linuxProtectedSettingsRequiredVariable = "PROTECTED_SETTINGS_REQUIRED"
+	// windowsProtectedSettingsStatement fails if the Windows protected settings file is missing, restricts it to SYSTEM
+	// and the Administrators group and reads it before the script arguments are set. The file is kept so a rerun of the
+	// CSE finds it
+	windowsProtectedSettingsStatement = `$protectedSettingsFile = '%SYSTEMDRIVE%\AzureData\ProtectedSettings.json' ; ` +
+		`if (-not (Test-Path $protectedSettingsFile)) { throw ('protected settings file ' + $protectedSettingsFile + ' not found') } ; ` +
+		`icacls $protectedSettingsFile /inheritance:r /grant:r '*S-1-5-18:F' '*S-1-5-32-544:F' | Out-Null ; ` +
+		`$protectedSettings = Get-Content -Raw -Path $protectedSettingsFile | ConvertFrom-Json ; `
+	// windowsProtectedSettingFormat inserts a protected setting into the quoted script arguments
+	windowsProtectedSettingFormat = "' + $protectedSettings.%s + '"
+)
+
+// GetNodeBootstrappingCmdWithProtectedSettings returns the CSE command of GetNodeBootstrapping with its secrets moved
+// to ProtectedSettings. The secrets are neither in Environment nor in Command, the provisioning script reads them from
+// ProtectedSettingsFile which must be written on the node before Command runs, e.g. by the protected settings of the
+// custom script extension
+func (t *TemplateGenerator) GetNodeBootstrappingCmdWithProtectedSettings(config *NodeBootstrappingConfiguration) (*NodeBootstrappingCSE, error) {
+	if err := config.validateRequired(); err != nil {
+		return nil, err
+	}
+	if config.isWindows() {
+		return getWindowsNodeCSEWithProtectedSettings(config, getParameters(config, "", ""))
+	}
+	cseCmd, err := t.getLinuxNodeCSE(config)
+	if err != nil {
+		return nil, err
+	}
+	return getLinuxNodeCSEWithProtectedSettings(getNodeBootstrappingCSEFromCommand(cseCmd)), nil
+}
+
+// getLinuxNodeCSEWithProtectedSettings moves the secret variables of a Linux CSE command to its protected settings,
+// provision.sh sources them from LinuxProtectedSettingsFilePath and fails if the file is missing
+func getLinuxNodeCSEWithProtectedSettings(cse *NodeBootstrappingCSE) *NodeBootstrappingCSE {
+	protectedSettings := map[string]string{}
+	for k, v := range cse.Environment {
+		if cseSecretVariables[k] {
+			protectedSettings[k] = v
+			delete(cse.Environment, k)
+		}
+	}
+	cse.Environment[linuxProtectedSettingsRequiredVariable] = "true"
+	cse.ProtectedSettings = protectedSettings
+	cse.ProtectedSettingsFile = &NodeBootstrappingFile{
+		Path:        LinuxProtectedSettingsFilePath,
+		Permissions: linuxProtectedSettingsFilePermissions,
+		Content:     formatLinuxProtectedSettings(protectedSettings),
+	}
+	return cse
+}
+
+// formatLinuxProtectedSettings returns the protected settings as single quoted shell variable assignments sorted by name
+func formatLinuxProtectedSettings(protectedSettings map[string]string) string {
+	names := make([]string, 0, len(protectedSettings))
+	for k := range protectedSettings {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&b, "%s='%s'\n", k, strings.Replace(protectedSettings[k], "'", `'\''`, -1))
+	}
+	return b.String()
+}
+
+// getWindowsNodeCSEWithProtectedSettings returns the Windows CSE command of getWindowsNodeCSE reading the AgentKey and
+// AADClientSecret arguments from WindowsProtectedSettingsFilePath, Key Vault references are still fetched by the command
+func getWindowsNodeCSEWithProtectedSettings(config *NodeBootstrappingConfiguration, parameters paramsMap) (*NodeBootstrappingCSE, error) {
+	protectedSettings := map[string]string{}
+	agentKeyStatement, agentKey := getWindowsKeyVaultSecretStatement(config, parameters, "clientPrivateKey", "agentKey", false)
+	if agentKeyStatement == "" {
+		protectedSettings["AgentKey"] = getParameterValue(parameters, "clientPrivateKey")
+		agentKey = fmt.Sprintf(windowsProtectedSettingFormat, "AgentKey")
+	}
+	aadClientSecretStatement, aadClientSecret := getWindowsKeyVaultSecretStatement(config, parameters, "servicePrincipalClientSecret", "aadClientSecret", true)
+	if aadClientSecretStatement == "" {
+		protectedSettings["AADClientSecret"] = getWindowsAADClientSecret(parameters)
+		aadClientSecret = fmt.Sprintf(windowsProtectedSettingFormat, "AADClientSecret")
+	}
+	content, err := json.Marshal(protectedSettings)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling the Windows protected settings")
+	}
+	statements := agentKeyStatement + aadClientSecretStatement
+	if len(protectedSettings) > 0 {
+		statements = windowsProtectedSettingsStatement + statements
+	}
+	return &NodeBootstrappingCSE{
+		Environment:       map[string]string{},
+		Command:           getWindowsNodeCSECommand(statements, getWindowsNodeCSEArguments(config, parameters, agentKey, aadClientSecret)),
+		ProtectedSettings: protectedSettings,
+		ProtectedSettingsFile: &NodeBootstrappingFile{
+			Path:    WindowsProtectedSettingsFilePath,
+			Content: string(content),
+		},
+	}, nil
+}
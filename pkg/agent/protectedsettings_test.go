@@ -0,0 +1,121 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestGetLinuxNodeCSEWithProtectedSettings(t *testing.T) {
+	cse := getLinuxNodeCSEWithProtectedSettings(&NodeBootstrappingCSE{
+		Environment: map[string]string{
+			"SERVICE_PRINCIPAL_CLIENT_ID":     "client-id",
+			"SERVICE_PRINCIPAL_CLIENT_SECRET": "it's secret",
+			"KUBELET_PRIVATE_KEY":             "a2V5",
+		},
+		Command: "/usr/bin/nohup /bin/bash -c \"/bin/bash /opt/azure/containers/provision.sh\"",
+	})
+	if len(cse.Environment) != 2 || cse.Environment["SERVICE_PRINCIPAL_CLIENT_ID"] != "client-id" || cse.Environment["PROTECTED_SETTINGS_REQUIRED"] != "true" {
+		t.Errorf("expected only the client id to stay in the environment and the protected settings to be required, got %v", cse.Environment)
+	}
+	if len(cse.ProtectedSettings) != 2 || cse.ProtectedSettings["KUBELET_PRIVATE_KEY"] != "a2V5" {
+		t.Errorf("expected the secrets in the protected settings, got %v", cse.ProtectedSettings)
+	}
+	expected := &NodeBootstrappingFile{
+		Path:        LinuxProtectedSettingsFilePath,
+		Permissions: "0600",
+		Content:     "KUBELET_PRIVATE_KEY='a2V5'\nSERVICE_PRINCIPAL_CLIENT_SECRET='it'\\''s secret'\n",
+	}
+	if *cse.ProtectedSettingsFile != *expected {
+		t.Errorf("expected protected settings file %+v, got %+v", expected, cse.ProtectedSettingsFile)
+	}
+}
+
+func TestGetWindowsNodeCSEWithProtectedSettings(t *testing.T) {
+	config := &NodeBootstrappingConfiguration{
+		Location:            "westus2",
+		HostedMasterProfile: &api.HostedMasterProfile{DNSPrefix: "abc"},
+	}
+	parameters := paramsMap{}
+	addValue(parameters, "kubernetesEndpoint", "abc.aks.com")
+	addValue(parameters, "clientPrivateKey", "a2V5")
+	addValue(parameters, "servicePrincipalClientSecret", "secret")
+
+	cse, err := getWindowsNodeCSEWithProtectedSettings(config, parameters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range []string{"a2V5", "c2VjcmV0"} {
+		if strings.Contains(cse.Command, s) {
+			t.Errorf("expected the command not to contain the secret %q, got %s", s, cse.Command)
+		}
+	}
+	for _, s := range []string{"icacls $protectedSettingsFile /inheritance:r /grant:r '*S-1-5-18:F' '*S-1-5-32-544:F'", "$protectedSettings = Get-Content -Raw -Path $protectedSettingsFile", "if (-not (Test-Path $protectedSettingsFile)) { throw ", "-AgentKey ' + $protectedSettings.AgentKey + ' ", "-AADClientSecret ''' + $protectedSettings.AADClientSecret + ''' "} {
+		if !strings.Contains(cse.Command, s) {
+			t.Errorf("expected command to contain %q, got %s", s, cse.Command)
+		}
+	}
+	var protectedSettings map[string]string
+	if err := json.Unmarshal([]byte(cse.ProtectedSettingsFile.Content), &protectedSettings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if protectedSettings["AgentKey"] != "a2V5" || protectedSettings["AADClientSecret"] != "c2VjcmV0" {
+		t.Errorf("unexpected protected settings %v", protectedSettings)
+	}
+
+	// the protected settings decode to the same arguments as the plain command
+	plain, err := DecodeNodeBootstrapping(&NodeBootstrapping{CustomData: windowsTestCustomData(), CSE: getWindowsNodeCSE(config, parameters)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	protected, err := DecodeNodeBootstrapping(&NodeBootstrapping{CustomData: windowsTestCustomData(), CSE: cse})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d := DiffNodeBootstrappingContent(plain, protected)
+	if len(d.Variables) != 0 {
+		t.Errorf("expected the same arguments, got\n%s", d)
+	}
+}
+
+func TestGetNodeBootstrappingCmdWithProtectedSettings(t *testing.T) {
+	tg := InitializeTemplateGenerator()
+	for _, c := range []string{"custom-search-domain", "windows"} {
+		cs := loadGoldenContainerService(t, filepath.Join(goldenDir, c, "apimodel.json"))
+		config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+		if err != nil {
+			t.Fatalf("%s: converting the api model: %v", c, err)
+		}
+		cse, err := tg.GetNodeBootstrappingCmdWithProtectedSettings(config)
+		if err != nil {
+			t.Fatalf("%s: rendering: %v", c, err)
+		}
+		if len(cse.ProtectedSettings) != 2 {
+			t.Errorf("%s: expected 2 protected settings, got %d", c, len(cse.ProtectedSettings))
+		}
+		for k, v := range cse.ProtectedSettings {
+			if v == "" {
+				continue
+			}
+			if strings.Contains(cse.Command, v) {
+				t.Errorf("%s: expected the command not to contain %s", c, k)
+			}
+			for name, value := range cse.Environment {
+				if strings.Contains(value, v) {
+					t.Errorf("%s: expected %s not to contain %s", c, name, k)
+				}
+			}
+		}
+	}
+}
+
+func windowsTestCustomData() string {
+	return fmt.Sprintf("$zippedFiles = \"%s\"\n", emptyZipBase64())
+}
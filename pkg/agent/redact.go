@@ -0,0 +1,287 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// fingerprintPrefix starts every fingerprint
+	fingerprintPrefix = "redacted-"
+	// minRedactedSecretLength is the length below which values are not replaced in text, they would match unrelated text
+	minRedactedSecretLength = 4
+)
+
+// sensitiveParameters are the parameters holding secrets. They are registered with addSecret, or with addKeyvaultReference
+// when the secret is in Key Vault, except servicePrincipalClientSecret which is a plain value without a KeyvaultSecretRef.
+// The certificates registered with addSecret are public and not redacted
+var sensitiveParameters = map[string]bool{
+	"apiServerPrivateKey":          true,
+	"caPrivateKey":                 true,
+	"clientPrivateKey":             true,
+	"kubeConfigPrivateKey":         true,
+	"servicePrincipalClientSecret": true,
+	"etcdEncryptionKey":            true,
+	"windowsAdminPassword":         true,
+}
+
+// cseSecretVariables are the CSE environment variables and Windows script arguments holding secrets
+var cseSecretVariables = map[string]bool{
+	"SERVICE_PRINCIPAL_CLIENT_SECRET": true,
+	"KUBELET_PRIVATE_KEY":             true,
+	"AgentKey":                        true,
+	"AADClientSecret":                 true,
+}
+
+// Fingerprint returns the replacement of a redacted secret, the first 12 hex digits of its SHA-256 hash.
+// It is stable so redacted artifacts still tell whether two secrets are the same
+func Fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return fmt.Sprintf("%s%x", fingerprintPrefix, sum[:6])
+}
+
+// redactSecret replaces a secret with its fingerprint, Key Vault references and fingerprints are kept
+func redactSecret(s *string) {
+	if *s == "" || strings.HasPrefix(*s, fingerprintPrefix) || keyvaultSecretPathRe.MatchString(*s) {
+		return
+	}
+	*s = Fingerprint(*s)
+}
+
+func redactServicePrincipalProfile(p *api.ServicePrincipalProfile) {
+	if p != nil {
+		redactSecret(&p.Secret)
+	}
+}
+
+func redactCertificateProfile(p *api.CertificateProfile) {
+	if p == nil {
+		return
+	}
+	redactSecret(&p.APIServerPrivateKey)
+	redactSecret(&p.CaPrivateKey)
+	redactSecret(&p.ClientPrivateKey)
+	redactSecret(&p.KubeConfigPrivateKey)
+	redactSecret(&p.EtcdServerPrivateKey)
+	redactSecret(&p.EtcdClientPrivateKey)
+	for i := range p.EtcdPeerPrivateKeys {
+		redactSecret(&p.EtcdPeerPrivateKeys[i])
+	}
+}
+
+func redactKubernetesConfig(k *api.KubernetesConfig) {
+	if k != nil {
+		redactSecret(&k.EtcdEncryptionKey)
+	}
+}
+
+func redactLinuxProfile(p *api.LinuxProfile) {
+	if p != nil && p.CustomSearchDomain != nil {
+		redactSecret(&p.CustomSearchDomain.RealmPassword)
+	}
+}
+
+func redactWindowsProfile(p *api.WindowsProfile) {
+	if p != nil {
+		redactSecret(&p.AdminPassword)
+	}
+}
+
+func redactAADProfile(p *api.AADProfile) {
+	if p != nil {
+		redactSecret(&p.ServerAppSecret)
+	}
+}
+
+// Redact replaces the secrets of the configuration with their fingerprints so the node bootstrapping artifacts
+// rendered from it can be shared. Key Vault references are kept, they don't hold the secret. The profiles are
+// redacted in place, a configuration converted from an api model shares them with the api model
+func (config *NodeBootstrappingConfiguration) Redact() {
+	redactServicePrincipalProfile(config.ServicePrincipalProfile)
+	redactCertificateProfile(config.CertificateProfile)
+	redactKubernetesConfig(config.KubernetesConfig)
+	redactLinuxProfile(config.LinuxProfile)
+	redactWindowsProfile(config.WindowsProfile)
+	redactAADProfile(config.AADProfile)
+}
+
+// RedactContainerService replaces the secrets of an api model with their fingerprints, see NodeBootstrappingConfiguration.Redact
+func RedactContainerService(cs *api.ContainerService) {
+	p := cs.Properties
+	if p == nil {
+		return
+	}
+	redactServicePrincipalProfile(p.ServicePrincipalProfile)
+	redactCertificateProfile(p.CertificateProfile)
+	if p.OrchestratorProfile != nil {
+		redactKubernetesConfig(p.OrchestratorProfile.KubernetesConfig)
+	}
+	redactLinuxProfile(p.LinuxProfile)
+	redactWindowsProfile(p.WindowsProfile)
+	redactAADProfile(p.AADProfile)
+}
+
+// GetNodeBootstrappingSecrets returns the secrets of the configuration that may appear in the node bootstrapping
+// artifacts, the values of the sensitive parameters and the secrets that are not parameters
+func GetNodeBootstrappingSecrets(config *NodeBootstrappingConfiguration) []string {
+	secrets := []string{}
+	for k, p := range getParameters(config, "", "") {
+		if !sensitiveParameters[k] {
+			continue
+		}
+		// Key Vault references have no value
+		if v, ok := p.(paramsMap)["value"].(string); ok && v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	if config.LinuxProfile != nil && config.LinuxProfile.HasSearchDomain() {
+		secrets = append(secrets, config.LinuxProfile.CustomSearchDomain.RealmPassword)
+	}
+	if config.AADProfile != nil && config.AADProfile.ServerAppSecret != "" {
+		secrets = append(secrets, config.AADProfile.ServerAppSecret)
+	}
+	sort.Strings(secrets)
+	return secrets
+}
+
+// Redactor replaces secrets in text with their fingerprints. Secrets are also found base64 encoded, base64
+// decoded and JSON escaped, and are fingerprinted by their decoded value so every form gets the same fingerprint.
+// Redactor is a logrus hook redacting log messages and fields, it is safe for concurrent use
+type Redactor struct {
+	mu           sync.RWMutex
+	replacements map[string]string
+	replacer     *strings.Replacer
+}
+
+// NewRedactor creates a Redactor for secrets
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{
+		replacements: map[string]string{},
+	}
+	for _, s := range secrets {
+		r.AddSecret(s)
+	}
+	return r
+}
+
+// AddSecret adds a secret to redact, secrets shorter than 4 characters are ignored
+func (r *Redactor) AddSecret(secret string) {
+	if len(secret) < minRedactedSecretLength {
+		return
+	}
+	value := decodeSecret(secret)
+	// already redacted
+	if strings.HasPrefix(value, fingerprintPrefix) {
+		return
+	}
+	fingerprint := Fingerprint(value)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	forms := append([]string{secret, value, base64.StdEncoding.EncodeToString([]byte(value))}, jsonEscape(value)...)
+	for _, form := range forms {
+		if len(form) >= minRedactedSecretLength {
+			r.replacements[form] = fingerprint
+		}
+	}
+	r.replacer = nil
+}
+
+// RedactSecret returns the fingerprint of a secret, or the secret itself if it is a fingerprint, plain or base64 encoded
+func RedactSecret(secret string) string {
+	value := decodeSecret(secret)
+	if strings.HasPrefix(value, fingerprintPrefix) {
+		return value
+	}
+	return Fingerprint(value)
+}
+
+// decodeSecret returns the base64 decoded secret if it decodes to text, certificates and keys are base64 encoded
+// in the parameters and CSE variables
+func decodeSecret(secret string) string {
+	if decoded, err := base64.StdEncoding.DecodeString(secret); err == nil && len(decoded) >= minRedactedSecretLength && utf8.Valid(decoded) {
+		return string(decoded)
+	}
+	return secret
+}
+
+// Redact replaces the secrets in s with their fingerprints
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+	if replacer == nil {
+		r.mu.Lock()
+		if r.replacer == nil {
+			// the longest secrets first so a secret containing another one is replaced as a whole
+			forms := make([]string, 0, len(r.replacements))
+			for form := range r.replacements {
+				forms = append(forms, form)
+			}
+			sort.Slice(forms, func(i, j int) bool {
+				if len(forms[i]) != len(forms[j]) {
+					return len(forms[i]) > len(forms[j])
+				}
+				return forms[i] < forms[j]
+			})
+			oldnew := make([]string, 0, 2*len(forms))
+			for _, form := range forms {
+				oldnew = append(oldnew, form, r.replacements[form])
+			}
+			r.replacer = strings.NewReplacer(oldnew...)
+		}
+		replacer = r.replacer
+		r.mu.Unlock()
+	}
+	return replacer.Replace(s)
+}
+
+// Levels implements logrus.Hook, every level is redacted
+func (r *Redactor) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire implements logrus.Hook, it redacts the message and the string and error fields of the entry
+func (r *Redactor) Fire(entry *log.Entry) error {
+	entry.Message = r.Redact(entry.Message)
+	for k, v := range entry.Data {
+		switch value := v.(type) {
+		case string:
+			entry.Data[k] = r.Redact(value)
+		case error:
+			entry.Data[k] = r.Redact(value.Error())
+		}
+	}
+	return nil
+}
+
+// jsonEscape returns s as it appears in a JSON string, e.g. a private key in apimodel.json, with and without
+// the HTML escaping of encoding/json
+func jsonEscape(s string) []string {
+	escaped := []string{}
+	for _, escapeHTML := range []bool{true, false} {
+		buf := new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(escapeHTML)
+		if err := enc.Encode(s); err != nil {
+			continue
+		}
+		// drop the quotes and the newline of Encode
+		b := bytes.TrimSpace(buf.Bytes())
+		escaped = append(escaped, string(b[1:len(b)-1]))
+	}
+	return escaped
+}
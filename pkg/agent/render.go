@@ -0,0 +1,206 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"text/template"
+
+	"github.com/Azure/agentbaker/pkg/templates"
+	"github.com/pkg/errors"
+)
+
+// defaultArtifactCacheSize bounds the rendered artifacts kept in memory, a Linux agent pool renders up to 18
+const defaultArtifactCacheSize = 2048
+
+var (
+	// parsedTemplates are the template assets parsed once, see getParsedTemplate
+	parsedTemplates = &parsedTemplateCache{templates: map[string]*template.Template{}}
+	// artifactCache memoizes the base64 encoded gzipped artifacts, see getBase64EncodedGzippedCustomScripts
+	artifactCache = newRenderCache(defaultArtifactCacheSize)
+	// gzipWriters reuse the compression state of the artifacts, a gzip writer allocates most of it
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+	// deterministicGzipHeader is the gzip header of the artifacts, OS 255 is unknown as the compress/gzip default
+	deterministicGzipHeader = gzip.Header{OS: 255}
+
+	windowsAgentFunctionsOnce sync.Once
+	windowsAgentFunctions     string
+	windowsAgentFunctionsErr  error
+)
+
+// parsedTemplateCache holds the template assets parsed once, keyed by asset name
+type parsedTemplateCache struct {
+	mu        sync.Mutex
+	templates map[string]*template.Template
+}
+
+// getParsedTemplate returns a clone of the parsed template asset bound to funcMap. The cached templates are parsed
+// with the func names of getBakerFuncMap, the funcs a template calls are only looked up by name at parse time
+func getParsedTemplate(name string, funcMap template.FuncMap) (*template.Template, error) {
+	parsedTemplates.mu.Lock()
+	templ, ok := parsedTemplates.templates[name]
+	parsedTemplates.mu.Unlock()
+	if !ok {
+		b, err := templates.Asset(name)
+		if err != nil {
+			return nil, &TemplateError{Template: name, Op: TemplateOpLoad, Err: err}
+		}
+		prototype := InitializeTemplateGenerator().getBakerFuncMap(&NodeBootstrappingConfiguration{}, paramsMap{}, paramsMap{})
+		templ, err = template.New(name).Funcs(prototype).Parse(string(b))
+		if err != nil {
+			return nil, &TemplateError{Template: name, Op: TemplateOpParse, Err: err}
+		}
+		parsedTemplates.mu.Lock()
+		parsedTemplates.templates[name] = templ
+		parsedTemplates.mu.Unlock()
+	}
+	clone, err := templ.Clone()
+	if err != nil {
+		return nil, &TemplateError{Template: name, Op: TemplateOpParse, Err: err}
+	}
+	return clone.Funcs(funcMap), nil
+}
+
+// renderCache is a least recently used cache of rendered artifacts
+type renderCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type renderCacheEntry struct {
+	key   string
+	value string
+}
+
+func newRenderCache(size int) *renderCache {
+	return &renderCache{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *renderCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*renderCacheEntry).value, true
+}
+
+func (c *renderCache) add(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&renderCacheEntry{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderCacheEntry).key)
+	}
+}
+
+// hashConfiguration returns the hash of the configuration the artifacts are memoized by, the configuration
+// holds everything the artifact templates read
+func hashConfiguration(config *NodeBootstrappingConfiguration) (string, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "hashing the node bootstrapping configuration")
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// getBase64EncodedGzippedCustomScripts renders the artifact templates concurrently and returns them base64
+// encoded and gzipped, keyed as files. An artifact rendered before for an identical configuration is not
+// rendered again
+func getBase64EncodedGzippedCustomScripts(files map[string]string, config *NodeBootstrappingConfiguration) (map[string]string, error) {
+	configHash, err := hashConfiguration(config)
+	if err != nil {
+		return nil, err
+	}
+	funcMap := getContainerServiceFuncMap(config)
+
+	type render struct {
+		key   string
+		value string
+		err   error
+	}
+	renders := make([]*render, 0, len(files))
+	var wg sync.WaitGroup
+	for k, file := range files {
+		r := &render{key: k}
+		renders = append(renders, r)
+		wg.Add(1)
+		go func(file string) {
+			defer wg.Done()
+			cacheKey := file + "\x00" + configHash
+			if v, ok := artifactCache.get(cacheKey); ok {
+				r.value = v
+				return
+			}
+			r.value, r.err = renderBase64EncodedGzippedCustomScript(file, config, funcMap)
+			if r.err == nil {
+				artifactCache.add(cacheKey, r.value)
+			}
+		}(file)
+	}
+	wg.Wait()
+
+	artifacts := map[string]string{}
+	for _, r := range renders {
+		if r.err != nil {
+			return nil, r.err
+		}
+		artifacts[r.key] = r.value
+	}
+	return artifacts, nil
+}
+
+// renderBase64EncodedGzippedCustomScript renders an artifact template with funcMap
+func renderBase64EncodedGzippedCustomScript(csFilename string, config *NodeBootstrappingConfiguration, funcMap template.FuncMap) (string, error) {
+	templ, err := getParsedTemplate(csFilename, funcMap)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	if err = templ.Option("missingkey=error").Execute(&buffer, config); err != nil {
+		return "", &TemplateError{Template: csFilename, Op: TemplateOpExecute, Err: err}
+	}
+	return getBase64EncodedGzippedCustomScriptFromStr(string(bytes.Replace(buffer.Bytes(), []byte("\r\n"), []byte("\n"), -1))), nil
+}
+
+// getBase64EncodedGzippedCustomScriptFromStr will return a base64-encoded string of the gzip'd source data.
+// The gzip header has no name and no modification time so identical data is encoded identically
+func getBase64EncodedGzippedCustomScriptFromStr(str string) string {
+	var gzipB bytes.Buffer
+	w := gzipWriters.Get().(*gzip.Writer)
+	w.Reset(&gzipB)
+	w.Header = deterministicGzipHeader
+	w.Write([]byte(str))
+	w.Close()
+	gzipWriters.Put(w)
+	return base64.StdEncoding.EncodeToString(gzipB.Bytes())
+}
+
+// getCachedBase64EncodedWindowsAgentFunctions returns the zip of getBase64EncodedWindowsAgentFunctions built once,
+// its scripts are not templates
+func getCachedBase64EncodedWindowsAgentFunctions() (string, error) {
+	windowsAgentFunctionsOnce.Do(func() {
+		windowsAgentFunctions, windowsAgentFunctionsErr = getBase64EncodedWindowsAgentFunctions()
+	})
+	return windowsAgentFunctions, windowsAgentFunctionsErr
+}
@@ -0,0 +1,217 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func loadBenchmarkConfiguration(b testing.TB, name string) *NodeBootstrappingConfiguration {
+	cs := loadGoldenContainerService(b, filepath.Join(goldenDir, name, "apimodel.json"))
+	config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+	if err != nil {
+		b.Fatalf("converting the api model: %v", err)
+	}
+	return config
+}
+
+func TestRenderCache(t *testing.T) {
+	c := newRenderCache(2)
+	c.add("a", "1")
+	c.add("b", "2")
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	// b is the least recently used
+	c.add("c", "3")
+	if _, ok := c.get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	for k, expected := range map[string]string{"a": "1", "c": "3"} {
+		if v, ok := c.get(k); !ok || v != expected {
+			t.Errorf("expected %s to be %s, got %q", k, expected, v)
+		}
+	}
+}
+
+func TestGetBase64EncodedGzippedCustomScriptsMemoized(t *testing.T) {
+	defer func(c *renderCache) { artifactCache = c }(artifactCache)
+	artifactCache = newRenderCache(defaultArtifactCacheSize)
+
+	config := loadBenchmarkConfiguration(t, "kubenet-docker")
+	var wg sync.WaitGroup
+	results := make([]map[string]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			artifacts, err := getBase64EncodedGzippedCustomScripts(linuxCloudInitFiles, config)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = artifacts
+		}(i)
+	}
+	wg.Wait()
+	for _, artifacts := range results[1:] {
+		if !reflect.DeepEqual(artifacts, results[0]) {
+			t.Fatalf("expected identical configurations to render identical artifacts")
+		}
+	}
+	if len(artifactCache.entries) != len(linuxCloudInitFiles) {
+		t.Errorf("expected %d memoized artifacts, got %d", len(linuxCloudInitFiles), len(artifactCache.entries))
+	}
+
+	// an artifact rendered for another configuration is not reused
+	config.LinuxProfile.CustomSearchDomain = &api.CustomSearchDomain{Name: "contoso.com", RealmUser: "user", RealmPassword: "password"}
+	artifacts, err := getBase64EncodedGzippedCustomScripts(linuxCloudInitFiles, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reflect.DeepEqual(artifacts, results[0]) {
+		t.Errorf("expected a different configuration to render different artifacts")
+	}
+	if len(artifactCache.entries) != 2*len(linuxCloudInitFiles) {
+		t.Errorf("expected %d memoized artifacts, got %d", 2*len(linuxCloudInitFiles), len(artifactCache.entries))
+	}
+}
+
+func TestDeterministicArtifacts(t *testing.T) {
+	b, err := base64.StdEncoding.DecodeString(getBase64EncodedGzippedCustomScriptFromStr("echo hello"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !zr.ModTime.IsZero() || zr.Name != "" {
+		t.Errorf("expected a gzip header without modification time and name, got %v and %q", zr.ModTime, zr.Name)
+	}
+
+	functions, err := getBase64EncodedWindowsAgentFunctions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err = base64.StdEncoding.DecodeString(functions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, f := range r.File {
+		if f.ModifiedDate != 0 || f.ModifiedTime != 0 || len(f.Extra) != 0 {
+			t.Errorf("expected %s to have no modification time", f.Name)
+		}
+	}
+
+	// rendered again rather than memoized
+	defer func(c *renderCache) { artifactCache = c }(artifactCache)
+	artifactCache = newRenderCache(0)
+	for _, name := range []string{"kubenet-docker", "windows"} {
+		config := loadBenchmarkConfiguration(t, name)
+		tg := InitializeTemplateGenerator()
+		first, err := tg.GetNodeBootstrapping(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		second, err := tg.GetNodeBootstrapping(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: expected identical configurations to render identical payloads", name)
+		}
+		if hash, _ := first.Hash(); first.ContentHash == "" || hash != first.ContentHash {
+			t.Errorf("%s: expected the content hash %s, got %q", name, hash, first.ContentHash)
+		}
+
+		config.AgentPoolProfile.CustomNodeLabels = map[string]string{"b": "2", "a": "1"}
+		changed, err := tg.GetNodeBootstrapping(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if changed.ContentHash == first.ContentHash {
+			t.Errorf("%s: expected a changed configuration to change the content hash", name)
+		}
+	}
+}
+
+func TestHashContent(t *testing.T) {
+	if HashContent([]byte("ab"), []byte("c")) == HashContent([]byte("a"), []byte("bc")) {
+		t.Errorf("expected the part boundaries to change the hash")
+	}
+}
+
+// benchmarkMemoization runs f with the memoized artifacts and with artifacts rendered on every iteration
+func benchmarkMemoization(b *testing.B, f func(b *testing.B)) {
+	defer func(c *renderCache) { artifactCache = c }(artifactCache)
+	b.Run("rendered", func(b *testing.B) {
+		artifactCache = newRenderCache(0)
+		b.ReportAllocs()
+		f(b)
+	})
+	b.Run("memoized", func(b *testing.B) {
+		artifactCache = newRenderCache(defaultArtifactCacheSize)
+		b.ReportAllocs()
+		f(b)
+	})
+}
+
+func BenchmarkGetCustomDataVariables(b *testing.B) {
+	config := loadBenchmarkConfiguration(b, "kubenet-docker")
+	benchmarkMemoization(b, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := getCustomDataVariables(config, outputFormatPlain); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetBase64EncodedWindowsAgentFunctions(b *testing.B) {
+	b.Run("built", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := getBase64EncodedWindowsAgentFunctions(); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := getCachedBase64EncodedWindowsAgentFunctions(); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetNodeBootstrapping(b *testing.B) {
+	for _, name := range []string{"kubenet-docker", "windows"} {
+		config := loadBenchmarkConfiguration(b, name)
+		b.Run(name, func(b *testing.B) {
+			tg := InitializeTemplateGenerator()
+			benchmarkMemoization(b, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := tg.GetNodeBootstrapping(config); err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+			})
+		})
+	}
+}
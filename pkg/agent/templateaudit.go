@@ -0,0 +1,293 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Azure/agentbaker/pkg/templates"
+	"github.com/pkg/errors"
+)
+
+// bakerTemplates are rendered with getBakerFuncMap, they look up parameters and variables
+var bakerTemplates = []string{
+	kubernetesNodeCustomDataYaml,
+	kubernetesCSECommandString,
+	kubernetesWindowsAgentCustomDataPS1,
+}
+
+// builtinTemplateFuncs are the text/template functions available without a func map
+var builtinTemplateFuncs = map[string]bool{
+	"and": true, "call": true, "html": true, "index": true, "slice": true, "js": true, "len": true, "not": true,
+	"or": true, "print": true, "printf": true, "println": true, "urlquery": true,
+	"eq": true, "ge": true, "gt": true, "le": true, "lt": true, "ne": true,
+}
+
+// TemplateAudit lists the func map entries, parameters and variables the node bootstrapping templates reference
+// but no configuration defines, and those defined but never referenced. Properties are reported as
+// <name>.<property>, e.g. cloudproviderConfig.cloudProviderRateLimit
+type TemplateAudit struct {
+	MissingFuncs      []string
+	DeadFuncs         []string
+	MissingParameters []string
+	DeadParameters    []string
+	MissingVariables  []string
+	DeadVariables     []string
+}
+
+// Empty returns true if the templates reference nothing undefined and everything defined is referenced
+func (a *TemplateAudit) Empty() bool {
+	return len(a.MissingFuncs) == 0 && len(a.DeadFuncs) == 0 &&
+		len(a.MissingParameters) == 0 && len(a.DeadParameters) == 0 &&
+		len(a.MissingVariables) == 0 && len(a.DeadVariables) == 0
+}
+
+func (a *TemplateAudit) String() string {
+	var b strings.Builder
+	for _, section := range []struct {
+		name  string
+		names []string
+	}{
+		{"missing func", a.MissingFuncs},
+		{"dead func", a.DeadFuncs},
+		{"missing parameter", a.MissingParameters},
+		{"dead parameter", a.DeadParameters},
+		{"missing variable", a.MissingVariables},
+		{"dead variable", a.DeadVariables},
+	} {
+		for _, name := range section.names {
+			fmt.Fprintf(&b, "%s %s\n", section.name, name)
+		}
+	}
+	return b.String()
+}
+
+// templateReferences are the func map calls and the parameter and variable keys of parsed templates
+type templateReferences struct {
+	funcs      map[string]bool
+	parameters map[string]bool
+	variables  map[string]bool
+}
+
+// AuditTemplates parses the node bootstrapping templates and cross-checks the funcs, parameters and variables
+// they reference against the func maps, getParameters and the customData and CSE command variables of the
+// configurations. An entry counts as defined if any configuration defines it, so the configurations should
+// cover every agent pool flavor the templates branch on
+func (t *TemplateGenerator) AuditTemplates(configs ...*NodeBootstrappingConfiguration) (*TemplateAudit, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("auditing templates requires at least one node bootstrapping configuration")
+	}
+	defined := templateReferences{funcs: map[string]bool{}, parameters: map[string]bool{}, variables: map[string]bool{}}
+	artifactFuncs := map[string]bool{}
+	for _, config := range configs {
+		if err := config.validateRequired(); err != nil {
+			return nil, err
+		}
+		params := getParameters(config, "", "")
+		addReferenceKeys(defined.parameters, params, "value")
+		variables, err := getWindowsCustomDataVariables(config, outputFormatPlain)
+		if err != nil {
+			return nil, err
+		}
+		addReferenceKeys(defined.variables, variables, "")
+		for name := range t.getBakerFuncMap(config, params, variables) {
+			defined.funcs[name] = true
+		}
+		for name := range getContainerServiceFuncMap(config) {
+			artifactFuncs[name] = true
+		}
+	}
+
+	used := templateReferences{funcs: map[string]bool{}, parameters: map[string]bool{}, variables: map[string]bool{}}
+	missingFuncs := map[string]bool{}
+	collect := func(names []string, funcs map[string]bool) error {
+		for _, name := range names {
+			refs, err := parseTemplateReferences(name)
+			if err != nil {
+				return err
+			}
+			for f := range refs.funcs {
+				used.funcs[f] = true
+				if !funcs[f] {
+					missingFuncs[f] = true
+				}
+			}
+			for k := range refs.parameters {
+				used.parameters[k] = true
+			}
+			for k := range refs.variables {
+				used.variables[k] = true
+			}
+		}
+		return nil
+	}
+	if err := collect(bakerTemplates, defined.funcs); err != nil {
+		return nil, err
+	}
+	if err := collect(linuxCloudInitArtifacts(), artifactFuncs); err != nil {
+		return nil, err
+	}
+
+	return &TemplateAudit{
+		MissingFuncs:      sortedKeys(missingFuncs),
+		DeadFuncs:         difference(defined.funcs, used.funcs),
+		MissingParameters: difference(used.parameters, defined.parameters),
+		DeadParameters:    difference(defined.parameters, used.parameters),
+		MissingVariables:  difference(used.variables, defined.variables),
+		DeadVariables:     difference(defined.variables, used.variables),
+	}, nil
+}
+
+// linuxCloudInitArtifacts returns the sorted names of the artifact templates written by the Linux customData,
+// they are rendered with getContainerServiceFuncMap
+func linuxCloudInitArtifacts() []string {
+	names := []string{}
+	for _, files := range []map[string]string{linuxCloudInitFiles, nonVHDCloudInitFiles} {
+		for _, name := range files {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addReferenceKeys adds the keys of m and the <key>.<property> keys of its map values to keys, the properties of
+// a value are read from its valueKey entry if not empty
+func addReferenceKeys(keys map[string]bool, m paramsMap, valueKey string) {
+	for k, v := range m {
+		keys[k] = true
+		value, ok := v.(paramsMap)
+		if ok && valueKey != "" {
+			value, ok = value[valueKey].(paramsMap)
+		}
+		if !ok {
+			continue
+		}
+		for p := range value {
+			keys[k+"."+p] = true
+		}
+	}
+}
+
+// parseTemplateReferences parses a template asset and returns the funcs it calls and the parameter and variable
+// keys it looks up with string literals
+func parseTemplateReferences(name string) (*templateReferences, error) {
+	b, err := templates.Asset(name)
+	if err != nil {
+		return nil, &TemplateError{Template: name, Op: TemplateOpLoad, Err: err}
+	}
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	// the tree set receives the main template and every template it defines
+	treeSet := map[string]*parse.Tree{}
+	if _, err := tree.Parse(string(b), "", "", treeSet); err != nil {
+		return nil, &TemplateError{Template: name, Op: TemplateOpParse, Err: err}
+	}
+	refs := &templateReferences{funcs: map[string]bool{}, parameters: map[string]bool{}, variables: map[string]bool{}}
+	for _, t := range treeSet {
+		refs.walk(t.Root)
+	}
+	return refs, nil
+}
+
+func (refs *templateReferences) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			refs.walk(child)
+		}
+	case *parse.ActionNode:
+		refs.walk(n.Pipe)
+	case *parse.IfNode:
+		refs.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		refs.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		refs.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		refs.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			refs.walk(cmd)
+		}
+	case *parse.ChainNode:
+		refs.walk(n.Node)
+	case *parse.CommandNode:
+		refs.walkCommand(n)
+	case *parse.IdentifierNode:
+		// an identifier is a func call wherever it appears, e.g. HasCoreOS in {{if not HasCoreOS}}
+		if !builtinTemplateFuncs[n.Ident] {
+			refs.funcs[n.Ident] = true
+		}
+	}
+}
+
+func (refs *templateReferences) walkBranch(n *parse.BranchNode) {
+	refs.walk(n.Pipe)
+	refs.walk(n.List)
+	refs.walk(n.ElseList)
+}
+
+func (refs *templateReferences) walkCommand(n *parse.CommandNode) {
+	for _, arg := range n.Args {
+		refs.walk(arg)
+	}
+	if len(n.Args) == 0 {
+		return
+	}
+	ident, ok := n.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return
+	}
+
+	literals := []string{}
+	for _, arg := range n.Args[1:] {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			break
+		}
+		literals = append(literals, s.Text)
+	}
+	switch {
+	case (ident.Ident == "GetParameter" || ident.Ident == "GetKeyVaultSecretURL") && len(literals) == 1:
+		refs.parameters[literals[0]] = true
+	case ident.Ident == "GetParameterProperty" && len(literals) == 2:
+		refs.parameters[literals[0]] = true
+		refs.parameters[literals[0]+"."+literals[1]] = true
+	case ident.Ident == "GetVariable" && len(literals) == 1:
+		refs.variables[literals[0]] = true
+	case ident.Ident == "GetVariableProperty" && len(literals) == 2:
+		refs.variables[literals[0]] = true
+		refs.variables[literals[0]+"."+literals[1]] = true
+	}
+}
+
+// difference returns the sorted keys of a that are not in b
+func difference(a, b map[string]bool) []string {
+	keys := map[string]bool{}
+	for k := range a {
+		if !b[k] {
+			keys[k] = true
+		}
+	}
+	return sortedKeys(keys)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+// loadAuditConfigurations returns the configurations of every golden agent pool and of a hosted master agent pool
+// setting the optional parameters the golden api models leave out
+func loadAuditConfigurations(t *testing.T) []*NodeBootstrappingConfiguration {
+	cases, err := ioutil.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatalf("reading %s: %v", goldenDir, err)
+	}
+	configs := []*NodeBootstrappingConfiguration{}
+	for _, c := range cases {
+		cs := loadGoldenContainerService(t, filepath.Join(goldenDir, c.Name(), "apimodel.json"))
+		for _, profile := range cs.Properties.AgentPoolProfiles {
+			config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile, "tenant", "sub", "rg", "")
+			if err != nil {
+				t.Fatalf("%s: converting the api model: %v", c.Name(), err)
+			}
+			configs = append(configs, config)
+		}
+	}
+
+	cs := loadGoldenContainerService(t, filepath.Join(goldenDir, "custom-search-domain", "apimodel.json"))
+	cs.Properties.HostedMasterProfile = &api.HostedMasterProfile{FQDN: "abc.aks.com"}
+	kubernetesConfig := cs.Properties.OrchestratorProfile.KubernetesConfig
+	kubernetesConfig.CustomKubeBinaryURL = "https://acs-mirror.azureedge.net/kubernetes/v1.16.7/binaries/kubernetes-node-linux-amd64.tar.gz"
+	kubernetesConfig.MobyVersion = "3.0.10"
+	kubernetesConfig.ContainerdVersion = "1.3.2"
+	config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+	if err != nil {
+		t.Fatalf("converting the hosted master api model: %v", err)
+	}
+	return append(configs, config)
+}
+
+func TestAuditTemplates(t *testing.T) {
+	audit, err := InitializeTemplateGenerator().AuditTemplates(loadAuditConfigurations(t)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(audit.MissingFuncs) > 0 {
+		t.Errorf("templates call funcs missing from the func maps: %v", audit.MissingFuncs)
+	}
+	if len(audit.MissingParameters) > 0 {
+		t.Errorf("templates look up parameters getParameters never defines: %v", audit.MissingParameters)
+	}
+	if len(audit.MissingVariables) > 0 {
+		t.Errorf("templates look up variables that are never defined: %v", audit.MissingVariables)
+	}
+	if len(audit.DeadVariables) > 0 {
+		t.Errorf("variables no template looks up: %v", audit.DeadVariables)
+	}
+	if len(audit.DeadFuncs) > 0 {
+		t.Errorf("funcs no template calls, move them to package armtemplate: %v", audit.DeadFuncs)
+	}
+	// the parameters also feed the ARM parameters of the agent pool, the dead ones are only reported
+	t.Logf("parameters no template looks up: %v", audit.DeadParameters)
+}
+
+func TestParseTemplateReferences(t *testing.T) {
+	refs, err := parseTemplateReferences(kubernetesCSECommandString)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, k := range []string{"containerdVersion", "cloudproviderConfig", "cloudproviderConfig.cloudProviderRateLimit", "clientPrivateKey"} {
+		if !refs.parameters[k] {
+			t.Errorf("expected parameter %s to be referenced", k)
+		}
+	}
+	if !refs.variables["tenantID"] {
+		t.Errorf("expected variable tenantID to be referenced")
+	}
+	for _, f := range []string{"GetParameter", "GetVariable", "GetKeyVaultSecretURL"} {
+		if !refs.funcs[f] {
+			t.Errorf("expected func %s to be referenced", f)
+		}
+	}
+	if refs.funcs["eq"] {
+		t.Errorf("expected builtin funcs not to be referenced")
+	}
+}
+
+func TestStrictRendering(t *testing.T) {
+	configs := loadAuditConfigurations(t)
+	// the hosted master configuration defines every parameter the Linux templates look up
+	config := configs[len(configs)-1]
+	tg := InitializeTemplateGenerator()
+	tg.Strict = true
+	if _, err := tg.GetNodeBootstrapping(config); err != nil {
+		t.Errorf("unexpected error rendering in strict mode: %v", err)
+	}
+
+	params := getParameters(config, "", "")
+	variables := getCSECommandVariables(config)
+	for _, strict := range []bool{false, true} {
+		tg.Strict = strict
+		funcMap := tg.getBakerFuncMap(config, params, variables)
+		lookups := map[string]func() (interface{}, error){
+			"GetParameter": func() (interface{}, error) {
+				return funcMap["GetParameter"].(func(string) (interface{}, error))("containerdVerison")
+			},
+			"GetParameterProperty": func() (interface{}, error) {
+				return funcMap["GetParameterProperty"].(func(string, string) (interface{}, error))("cloudproviderConfig", "cloudProviderRatelimit")
+			},
+			"GetVariable": func() (interface{}, error) {
+				return funcMap["GetVariable"].(func(string) (interface{}, error))("tenantId")
+			},
+		}
+		for name, lookup := range lookups {
+			v, err := lookup()
+			var funcMapErr *FuncMapError
+			switch {
+			case strict && !errors.As(err, &funcMapErr):
+				t.Errorf("%s: expected a FuncMapError in strict mode, got %v", name, err)
+			case !strict && (err != nil || v != ""):
+				t.Errorf("%s: expected an empty string, got %q, %v", name, v, err)
+			}
+		}
+	}
+}
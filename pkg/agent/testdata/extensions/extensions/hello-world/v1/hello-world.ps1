@@ -0,0 +1 @@
+Write-Host "hello world $args"
@@ -0,0 +1,392 @@
+#!/bin/bash
+NODE_INDEX=$(hostname | tail -c 2)
+NODE_NAME=$(hostname)
+if [[ $OS == $COREOS_OS_NAME ]]; then
+    PRIVATE_IP=$(ip a show eth0 | grep -Po 'inet \K[\d.]+')
+else
+    PRIVATE_IP=$(hostname -I | cut -d' ' -f1)
+fi
+ETCD_PEER_URL="https://${PRIVATE_IP}:2380"
+ETCD_CLIENT_URL="https://${PRIVATE_IP}:2379"
+
+systemctlEnableAndStart() {
+    systemctl_restart 100 5 30 $1
+    RESTART_STATUS=$?
+    systemctl status $1 --no-pager -l > /var/log/azure/$1-status.log
+    if [ $RESTART_STATUS -ne 0 ]; then
+        echo "$1 could not be started"
+        return 1
+    fi
+    if ! retrycmd_if_failure 120 5 25 systemctl enable $1; then
+        echo "$1 could not be enabled by systemctl"
+        return 1
+    fi
+}
+
+configureAdminUser(){
+    chage -E -1 -I -1 -m 0 -M 99999 "${ADMINUSER}"
+    chage -l "${ADMINUSER}"
+}
+
+configureSecrets(){
+    APISERVER_PRIVATE_KEY_PATH="/etc/kubernetes/certs/apiserver.key"
+    touch "${APISERVER_PRIVATE_KEY_PATH}"
+    chmod 0600 "${APISERVER_PRIVATE_KEY_PATH}"
+    chown root:root "${APISERVER_PRIVATE_KEY_PATH}"
+
+    CA_PRIVATE_KEY_PATH="/etc/kubernetes/certs/ca.key"
+    touch "${CA_PRIVATE_KEY_PATH}"
+    chmod 0600 "${CA_PRIVATE_KEY_PATH}"
+    chown root:root "${CA_PRIVATE_KEY_PATH}"
+
+    ETCD_SERVER_PRIVATE_KEY_PATH="/etc/kubernetes/certs/etcdserver.key"
+    touch "${ETCD_SERVER_PRIVATE_KEY_PATH}"
+    chmod 0600 "${ETCD_SERVER_PRIVATE_KEY_PATH}"
+    if [[ -z "${COSMOS_URI}" ]]; then
+      chown etcd:etcd "${ETCD_SERVER_PRIVATE_KEY_PATH}"
+    fi
+
+    ETCD_CLIENT_PRIVATE_KEY_PATH="/etc/kubernetes/certs/etcdclient.key"
+    touch "${ETCD_CLIENT_PRIVATE_KEY_PATH}"
+    chmod 0600 "${ETCD_CLIENT_PRIVATE_KEY_PATH}"
+    chown root:root "${ETCD_CLIENT_PRIVATE_KEY_PATH}"
+
+    ETCD_PEER_PRIVATE_KEY_PATH="/etc/kubernetes/certs/etcdpeer${NODE_INDEX}.key"
+    touch "${ETCD_PEER_PRIVATE_KEY_PATH}"
+    chmod 0600 "${ETCD_PEER_PRIVATE_KEY_PATH}"
+    if [[ -z "${COSMOS_URI}" ]]; then
+      chown etcd:etcd "${ETCD_PEER_PRIVATE_KEY_PATH}"
+    fi
+
+    ETCD_SERVER_CERTIFICATE_PATH="/etc/kubernetes/certs/etcdserver.crt"
+    touch "${ETCD_SERVER_CERTIFICATE_PATH}"
+    chmod 0644 "${ETCD_SERVER_CERTIFICATE_PATH}"
+    chown root:root "${ETCD_SERVER_CERTIFICATE_PATH}"
+
+    ETCD_CLIENT_CERTIFICATE_PATH="/etc/kubernetes/certs/etcdclient.crt"
+    touch "${ETCD_CLIENT_CERTIFICATE_PATH}"
+    chmod 0644 "${ETCD_CLIENT_CERTIFICATE_PATH}"
+    chown root:root "${ETCD_CLIENT_CERTIFICATE_PATH}"
+
+    ETCD_PEER_CERTIFICATE_PATH="/etc/kubernetes/certs/etcdpeer${NODE_INDEX}.crt"
+    touch "${ETCD_PEER_CERTIFICATE_PATH}"
+    chmod 0644 "${ETCD_PEER_CERTIFICATE_PATH}"
+    chown root:root "${ETCD_PEER_CERTIFICATE_PATH}"
+
+    set +x
+    echo "${APISERVER_PRIVATE_KEY}" | base64 --decode > "${APISERVER_PRIVATE_KEY_PATH}"
+    echo "${CA_PRIVATE_KEY}" | base64 --decode > "${CA_PRIVATE_KEY_PATH}"
+    echo "${ETCD_SERVER_PRIVATE_KEY}" | base64 --decode > "${ETCD_SERVER_PRIVATE_KEY_PATH}"
+    echo "${ETCD_CLIENT_PRIVATE_KEY}" | base64 --decode > "${ETCD_CLIENT_PRIVATE_KEY_PATH}"
+    echo "${ETCD_PEER_KEY}" | base64 --decode > "${ETCD_PEER_PRIVATE_KEY_PATH}"
+    echo "${ETCD_SERVER_CERTIFICATE}" | base64 --decode > "${ETCD_SERVER_CERTIFICATE_PATH}"
+    echo "${ETCD_CLIENT_CERTIFICATE}" | base64 --decode > "${ETCD_CLIENT_CERTIFICATE_PATH}"
+    echo "${ETCD_PEER_CERT}" | base64 --decode > "${ETCD_PEER_CERTIFICATE_PATH}"
+}
+
+configureEtcd() {
+    set -x
+
+    ETCD_SETUP_FILE=/opt/azure/containers/setup-etcd.sh
+    wait_for_file 1200 1 $ETCD_SETUP_FILE || exit $ERR_ETCD_CONFIG_FAIL
+    $ETCD_SETUP_FILE > /opt/azure/containers/setup-etcd.log 2>&1
+    RET=$?
+    if [ $RET -ne 0 ]; then
+        exit $RET
+    fi
+
+    MOUNT_ETCD_FILE=/opt/azure/containers/mountetcd.sh
+    wait_for_file 1200 1 $MOUNT_ETCD_FILE || exit $ERR_ETCD_CONFIG_FAIL
+    $MOUNT_ETCD_FILE || exit $ERR_ETCD_VOL_MOUNT_FAIL
+    systemctlEnableAndStart etcd || exit $ERR_ETCD_START_TIMEOUT
+    for i in $(seq 1 600); do
+        MEMBER="$(sudo etcdctl member list | grep -E ${NODE_NAME} | cut -d':' -f 1)"
+        if [ "$MEMBER" != "" ]; then
+            break
+        else
+            sleep 1
+        fi
+    done
+    retrycmd_if_failure 120 5 25 sudo etcdctl member update $MEMBER ${ETCD_PEER_URL} || exit $ERR_ETCD_CONFIG_FAIL
+}
+
+ensureRPC() {
+    systemctlEnableAndStart rpcbind || exit $ERR_SYSTEMCTL_START_FAIL
+    systemctlEnableAndStart rpc-statd || exit $ERR_SYSTEMCTL_START_FAIL
+}
+
+ensureAuditD() {
+  if [[ "${AUDITD_ENABLED}" == true ]]; then
+    systemctlEnableAndStart auditd || exit $ERR_SYSTEMCTL_START_FAIL
+  else
+    if apt list --installed | grep 'auditd'; then
+      apt_get_purge 20 30 120 auditd &
+    fi
+  fi
+}
+
+generateAggregatedAPICerts() {
+    AGGREGATED_API_CERTS_SETUP_FILE=/etc/kubernetes/generate-proxy-certs.sh
+    wait_for_file 1200 1 $AGGREGATED_API_CERTS_SETUP_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    $AGGREGATED_API_CERTS_SETUP_FILE
+}
+
+configureKubeletServerCert() {
+    KUBELET_SERVER_PRIVATE_KEY_PATH="/etc/kubernetes/certs/kubeletserver.key"
+    KUBELET_SERVER_CERT_PATH="/etc/kubernetes/certs/kubeletserver.crt"
+
+    openssl genrsa -out $KUBELET_SERVER_PRIVATE_KEY_PATH 2048
+    openssl req -new -x509 -days 7300 -key $KUBELET_SERVER_PRIVATE_KEY_PATH -out $KUBELET_SERVER_CERT_PATH -subj "/CN=${NODE_NAME}"
+}
+
+fetchKeyVaultSecrets() {
+    set +x
+    if [[ -n "${SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_SECRET_URL}" ]]; then
+        SERVICE_PRINCIPAL_CLIENT_SECRET=$(get_keyvault_secret "${SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_SECRET_URL}") || exit $ERR_KEYVAULT_SECRET_FETCH_FAIL
+    fi
+    if [[ -n "${KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL}" ]]; then
+        KUBELET_PRIVATE_KEY=$(get_keyvault_secret "${KUBELET_PRIVATE_KEY_KEYVAULT_SECRET_URL}") || exit $ERR_KEYVAULT_SECRET_FETCH_FAIL
+    fi
+    set -x
+}
+
+configureK8s() {
+    KUBELET_PRIVATE_KEY_PATH="/etc/kubernetes/certs/client.key"
+    touch "${KUBELET_PRIVATE_KEY_PATH}"
+    chmod 0600 "${KUBELET_PRIVATE_KEY_PATH}"
+    chown root:root "${KUBELET_PRIVATE_KEY_PATH}"
+
+    APISERVER_PUBLIC_KEY_PATH="/etc/kubernetes/certs/apiserver.crt"
+    touch "${APISERVER_PUBLIC_KEY_PATH}"
+    chmod 0644 "${APISERVER_PUBLIC_KEY_PATH}"
+    chown root:root "${APISERVER_PUBLIC_KEY_PATH}"
+
+    AZURE_JSON_PATH="/etc/kubernetes/azure.json"
+    touch "${AZURE_JSON_PATH}"
+    chmod 0600 "${AZURE_JSON_PATH}"
+    chown root:root "${AZURE_JSON_PATH}"
+
+    set +x
+    echo "${KUBELET_PRIVATE_KEY}" | base64 --decode > "${KUBELET_PRIVATE_KEY_PATH}"
+    echo "${APISERVER_PUBLIC_KEY}" | base64 --decode > "${APISERVER_PUBLIC_KEY_PATH}"
+    
+    SERVICE_PRINCIPAL_CLIENT_SECRET=${SERVICE_PRINCIPAL_CLIENT_SECRET//\\/\\\\}
+    SERVICE_PRINCIPAL_CLIENT_SECRET=${SERVICE_PRINCIPAL_CLIENT_SECRET//\"/\\\"}
+    cat << EOF > "${AZURE_JSON_PATH}"
+{
+    "cloud":"AzurePublicCloud",
+    "tenantId": "${TENANT_ID}",
+    "subscriptionId": "${SUBSCRIPTION_ID}",
+    "aadClientId": "${SERVICE_PRINCIPAL_CLIENT_ID}",
+    "aadClientSecret": "${SERVICE_PRINCIPAL_CLIENT_SECRET}",
+    "resourceGroup": "${RESOURCE_GROUP}",
+    "location": "${LOCATION}",
+    "vmType": "${VM_TYPE}",
+    "subnetName": "${SUBNET}",
+    "securityGroupName": "${NETWORK_SECURITY_GROUP}",
+    "vnetName": "${VIRTUAL_NETWORK}",
+    "vnetResourceGroup": "${VIRTUAL_NETWORK_RESOURCE_GROUP}",
+    "routeTableName": "${ROUTE_TABLE}",
+    "primaryAvailabilitySetName": "${PRIMARY_AVAILABILITY_SET}",
+    "primaryScaleSetName": "${PRIMARY_SCALE_SET}",
+    "cloudProviderBackoffMode": "${CLOUDPROVIDER_BACKOFF_MODE}",
+    "cloudProviderBackoff": ${CLOUDPROVIDER_BACKOFF},
+    "cloudProviderBackoffRetries": ${CLOUDPROVIDER_BACKOFF_RETRIES},
+    "cloudProviderBackoffExponent": ${CLOUDPROVIDER_BACKOFF_EXPONENT},
+    "cloudProviderBackoffDuration": ${CLOUDPROVIDER_BACKOFF_DURATION},
+    "cloudProviderBackoffJitter": ${CLOUDPROVIDER_BACKOFF_JITTER},
+    "cloudProviderRatelimit": ${CLOUDPROVIDER_RATELIMIT},
+    "cloudProviderRateLimitQPS": ${CLOUDPROVIDER_RATELIMIT_QPS},
+    "cloudProviderRateLimitBucket": ${CLOUDPROVIDER_RATELIMIT_BUCKET},
+    "cloudProviderRatelimitQPSWrite": ${CLOUDPROVIDER_RATELIMIT_QPS_WRITE},
+    "cloudProviderRatelimitBucketWrite": ${CLOUDPROVIDER_RATELIMIT_BUCKET_WRITE},
+    "useManagedIdentityExtension": ${USE_MANAGED_IDENTITY_EXTENSION},
+    "userAssignedIdentityID": "${USER_ASSIGNED_IDENTITY_ID}",
+    "useInstanceMetadata": ${USE_INSTANCE_METADATA},
+    "loadBalancerSku": "${LOAD_BALANCER_SKU}",
+    "disableOutboundSNAT": ${LOAD_BALANCER_DISABLE_OUTBOUND_SNAT},
+    "excludeMasterFromStandardLB": ${EXCLUDE_MASTER_FROM_STANDARD_LB},
+    "providerVaultName": "${KMS_PROVIDER_VAULT_NAME}",
+    "maximumLoadBalancerRuleCount": ${MAXIMUM_LOADBALANCER_RULE_COUNT},
+    "providerKeyName": "k8s",
+    "providerKeyVersion": ""
+}
+EOF
+    set -x
+    if [[ "${CLOUDPROVIDER_BACKOFF_MODE}" = "v2" ]]; then
+        sed -i "/cloudProviderBackoffExponent/d" /etc/kubernetes/azure.json
+        sed -i "/cloudProviderBackoffJitter/d" /etc/kubernetes/azure.json
+    fi
+
+    configureKubeletServerCert
+}
+
+configureCNI() {
+    
+    retrycmd_if_failure 120 5 25 modprobe br_netfilter || exit $ERR_MODPROBE_FAIL
+    echo -n "br_netfilter" > /etc/modules-load.d/br_netfilter.conf
+    configureCNIIPTables
+    
+}
+
+configureCNIIPTables() {
+    if [[ "${NETWORK_PLUGIN}" = "azure" ]]; then
+        mv $CNI_BIN_DIR/10-azure.conflist $CNI_CONFIG_DIR/
+        chmod 600 $CNI_CONFIG_DIR/10-azure.conflist
+        if [[ "${NETWORK_POLICY}" == "calico" ]]; then
+          sed -i 's#"mode":"bridge"#"mode":"transparent"#g' $CNI_CONFIG_DIR/10-azure.conflist
+        elif [[ "${NETWORK_POLICY}" == "" || "${NETWORK_POLICY}" == "none" ]] && [[ "${NETWORK_MODE}" == "transparent" ]]; then
+          sed -i 's#"mode":"bridge"#"mode":"transparent"#g' $CNI_CONFIG_DIR/10-azure.conflist
+        fi
+        /sbin/ebtables -t nat --list
+    fi
+}
+
+
+ensureContainerd() {
+    echo "Starting cri-containerd service..."
+    systemctlEnableAndStart containerd || exit $ERR_SYSTEMCTL_START_FAIL
+}
+
+
+ensureDocker() {
+    DOCKER_SERVICE_EXEC_START_FILE=/etc/systemd/system/docker.service.d/exec_start.conf
+    wait_for_file 1200 1 $DOCKER_SERVICE_EXEC_START_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    usermod -aG docker ${ADMINUSER}
+    DOCKER_MOUNT_FLAGS_SYSTEMD_FILE=/etc/systemd/system/docker.service.d/clear_mount_propagation_flags.conf
+    if [[ $OS != $COREOS_OS_NAME ]]; then
+        wait_for_file 1200 1 $DOCKER_MOUNT_FLAGS_SYSTEMD_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    fi
+    DOCKER_JSON_FILE=/etc/docker/daemon.json
+    for i in $(seq 1 1200); do
+        if [ -s $DOCKER_JSON_FILE ]; then
+            jq '.' < $DOCKER_JSON_FILE && break
+        fi
+        if [ $i -eq 1200 ]; then
+            exit $ERR_FILE_WATCH_TIMEOUT
+        else
+            sleep 1
+        fi
+    done
+    systemctlEnableAndStart docker || exit $ERR_DOCKER_START_FAIL
+    
+    DOCKER_MONITOR_SYSTEMD_TIMER_FILE=/etc/systemd/system/docker-monitor.timer
+    wait_for_file 1200 1 $DOCKER_MONITOR_SYSTEMD_TIMER_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    DOCKER_MONITOR_SYSTEMD_FILE=/etc/systemd/system/docker-monitor.service
+    wait_for_file 1200 1 $DOCKER_MONITOR_SYSTEMD_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    systemctlEnableAndStart docker-monitor.timer || exit $ERR_SYSTEMCTL_START_FAIL
+}
+
+
+
+
+
+ensureKubelet() {
+    KUBELET_DEFAULT_FILE=/etc/default/kubelet
+    wait_for_file 1200 1 $KUBELET_DEFAULT_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    KUBECONFIG_FILE=/var/lib/kubelet/kubeconfig
+    wait_for_file 1200 1 $KUBECONFIG_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    KUBELET_RUNTIME_CONFIG_SCRIPT_FILE=/opt/azure/containers/kubelet.sh
+    wait_for_file 1200 1 $KUBELET_RUNTIME_CONFIG_SCRIPT_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    systemctlEnableAndStart kubelet || exit $ERR_KUBELET_START_FAIL
+    
+    
+    
+}
+
+ensureLabelNodes() {
+    LABEL_NODES_SCRIPT_FILE=/opt/azure/containers/label-nodes.sh
+    wait_for_file 1200 1 $LABEL_NODES_SCRIPT_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    LABEL_NODES_SYSTEMD_FILE=/etc/systemd/system/label-nodes.service
+    wait_for_file 1200 1 $LABEL_NODES_SYSTEMD_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    systemctlEnableAndStart label-nodes || exit $ERR_SYSTEMCTL_START_FAIL
+}
+
+ensureJournal() {
+    {
+        echo "Storage=persistent"
+        echo "SystemMaxUse=1G"
+        echo "RuntimeMaxUse=1G"
+        echo "ForwardToSyslog=yes"
+    } >> /etc/systemd/journald.conf
+    systemctlEnableAndStart systemd-journald || exit $ERR_SYSTEMCTL_START_FAIL
+}
+
+ensureK8sControlPlane() {
+    if $REBOOTREQUIRED || [ "$NO_OUTBOUND" = "true" ]; then
+        return
+    fi
+    retrycmd_if_failure 120 5 25 $KUBECTL 2>/dev/null cluster-info || exit $ERR_K8S_RUNNING_TIMEOUT
+}
+
+createKubeManifestDir() {
+    KUBEMANIFESTDIR=/etc/kubernetes/manifests
+    mkdir -p $KUBEMANIFESTDIR
+}
+
+writeKubeConfig() {
+    KUBECONFIGDIR=/home/$ADMINUSER/.kube
+    KUBECONFIGFILE=$KUBECONFIGDIR/config
+    mkdir -p $KUBECONFIGDIR
+    touch $KUBECONFIGFILE
+    chown $ADMINUSER:$ADMINUSER $KUBECONFIGDIR
+    chown $ADMINUSER:$ADMINUSER $KUBECONFIGFILE
+    chmod 700 $KUBECONFIGDIR
+    chmod 600 $KUBECONFIGFILE
+    set +x
+    echo "
+---
+apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: \"$CA_CERTIFICATE\"
+    server: $KUBECONFIG_SERVER
+  name: \"$MASTER_FQDN\"
+contexts:
+- context:
+    cluster: \"$MASTER_FQDN\"
+    user: \"$MASTER_FQDN-admin\"
+  name: \"$MASTER_FQDN\"
+current-context: \"$MASTER_FQDN\"
+kind: Config
+users:
+- name: \"$MASTER_FQDN-admin\"
+  user:
+    client-certificate-data: \"$KUBECONFIG_CERTIFICATE\"
+    client-key-data: \"$KUBECONFIG_KEY\"
+" > $KUBECONFIGFILE
+    set -x
+}
+
+configClusterAutoscalerAddon() {
+    CLUSTER_AUTOSCALER_ADDON_FILE=/etc/kubernetes/addons/cluster-autoscaler-deployment.yaml
+    wait_for_file 1200 1 $CLUSTER_AUTOSCALER_ADDON_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    sed -i "s|<clientID>|$(echo $SERVICE_PRINCIPAL_CLIENT_ID | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
+    sed -i "s|<clientSec>|$(echo $SERVICE_PRINCIPAL_CLIENT_SECRET | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
+    sed -i "s|<subID>|$(echo $SUBSCRIPTION_ID | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
+    sed -i "s|<tenantID>|$(echo $TENANT_ID | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
+    sed -i "s|<rg>|$(echo $RESOURCE_GROUP | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
+}
+
+configACIConnectorAddon() {
+    ACI_CONNECTOR_CREDENTIALS=$(printf "{\"clientId\": \"%s\", \"clientSecret\": \"%s\", \"tenantId\": \"%s\", \"subscriptionId\": \"%s\", \"activeDirectoryEndpointUrl\": \"https://login.microsoftonline.com\",\"resourceManagerEndpointUrl\": \"https://management.azure.com/\", \"activeDirectoryGraphResourceId\": \"https://graph.windows.net/\", \"sqlManagementEndpointUrl\": \"https://management.core.windows.net:8443/\", \"galleryEndpointUrl\": \"https://gallery.azure.com/\", \"managementEndpointUrl\": \"https://management.core.windows.net/\"}" "$SERVICE_PRINCIPAL_CLIENT_ID" "$SERVICE_PRINCIPAL_CLIENT_SECRET" "$TENANT_ID" "$SUBSCRIPTION_ID" | base64 -w 0)
+
+    openssl req -newkey rsa:4096 -new -nodes -x509 -days 3650 -keyout /etc/kubernetes/certs/aci-connector-key.pem -out /etc/kubernetes/certs/aci-connector-cert.pem -subj "/C=US/ST=CA/L=virtualkubelet/O=virtualkubelet/OU=virtualkubelet/CN=virtualkubelet"
+    ACI_CONNECTOR_KEY=$(base64 /etc/kubernetes/certs/aci-connector-key.pem -w0)
+    ACI_CONNECTOR_CERT=$(base64 /etc/kubernetes/certs/aci-connector-cert.pem -w0)
+
+    ACI_CONNECTOR_ADDON_FILE=/etc/kubernetes/addons/aci-connector-deployment.yaml
+    wait_for_file 1200 1 $ACI_CONNECTOR_ADDON_FILE || exit $ERR_FILE_WATCH_TIMEOUT
+    sed -i "s|<creds>|$ACI_CONNECTOR_CREDENTIALS|g" $ACI_CONNECTOR_ADDON_FILE
+    sed -i "s|<rgName>|$RESOURCE_GROUP|g" $ACI_CONNECTOR_ADDON_FILE
+    sed -i "s|<cert>|$ACI_CONNECTOR_CERT|g" $ACI_CONNECTOR_ADDON_FILE
+    sed -i "s|<key>|$ACI_CONNECTOR_KEY|g" $ACI_CONNECTOR_ADDON_FILE
+}
+
+configAzurePolicyAddon() {
+    AZURE_POLICY_ADDON_FILE=/etc/kubernetes/addons/azure-policy-deployment.yaml
+    sed -i "s|<resourceId>|/subscriptions/$SUBSCRIPTION_ID/resourceGroups/$RESOURCE_GROUP|g" $AZURE_POLICY_ADDON_FILE
+}
+
+
+#EOF
@@ -0,0 +1,150 @@
+#!/bin/bash
+ERR_FILE_WATCH_TIMEOUT=6 
+set -x
+echo $(date),$(hostname), startcustomscript>>/opt/m
+
+for i in $(seq 1 3600); do
+    if [ -s /opt/azure/containers/provision_source.sh ]; then
+        grep -Fq '#HELPERSEOF' /opt/azure/containers/provision_source.sh && break
+    fi
+    if [ $i -eq 3600 ]; then
+        exit $ERR_FILE_WATCH_TIMEOUT
+    else
+        sleep 1
+    fi
+done
+sed -i "/#HELPERSEOF/d" /opt/azure/containers/provision_source.sh
+source /opt/azure/containers/provision_source.sh
+trap 'write_provision_status $?' EXIT
+provision_phase waitForScripts
+
+if [ -f /opt/azure/containers/protected-settings.env ]; then
+    set +x
+    source /opt/azure/containers/protected-settings.env
+    set -x
+elif [[ "${PROTECTED_SETTINGS_REQUIRED}" == "true" ]]; then
+    exit $ERR_PROTECTED_SETTINGS_NOT_FOUND
+fi
+
+wait_for_file 3600 1 /opt/azure/containers/provision_installs.sh || exit $ERR_FILE_WATCH_TIMEOUT
+source /opt/azure/containers/provision_installs.sh
+
+wait_for_file 3600 1 /opt/azure/containers/provision_configs.sh || exit $ERR_FILE_WATCH_TIMEOUT
+source /opt/azure/containers/provision_configs.sh
+
+set +x
+ETCD_PEER_CERT=$(echo ${ETCD_PEER_CERTIFICATES} | cut -d'[' -f 2 | cut -d']' -f 1 | cut -d',' -f $((${NODE_INDEX}+1)))
+ETCD_PEER_KEY=$(echo ${ETCD_PEER_PRIVATE_KEYS} | cut -d'[' -f 2 | cut -d']' -f 1 | cut -d',' -f $((${NODE_INDEX}+1)))
+set -x
+
+if [[ $OS == $COREOS_OS_NAME ]]; then
+    echo "Changing default kubectl bin location"
+    KUBECTL=/opt/kubectl
+fi
+
+if [ -f /var/run/reboot-required ]; then
+    REBOOTREQUIRED=true
+else
+    REBOOTREQUIRED=false
+fi
+
+provision_phase prepareNode
+configureAdminUser
+cleanUpContainerd
+
+
+if [[ "${GPU_NODE}" != "true" ]]; then
+    cleanUpGPUDrivers
+fi
+
+VHD_LOGS_FILEPATH=/opt/azure/vhd-install.complete
+if [ -f $VHD_LOGS_FILEPATH ]; then
+    echo "detected golden image pre-install"
+    cleanUpContainerImages
+    FULL_INSTALL_REQUIRED=false
+else
+    if [[ "${IS_VHD}" = true ]]; then
+        echo "Using VHD distro but file $VHD_LOGS_FILEPATH not found"
+        exit $ERR_VHD_FILE_NOT_FOUND
+    fi
+    FULL_INSTALL_REQUIRED=true
+fi
+
+provision_phase installDeps
+if [[ $OS == $UBUNTU_OS_NAME ]] && [ "$FULL_INSTALL_REQUIRED" = "true" ]; then
+    installDeps
+else
+    echo "Golden image; skipping dependencies installation"
+fi
+
+if [[ $OS == $UBUNTU_OS_NAME ]]; then
+    ensureAuditD
+fi
+
+provision_phase installContainerRuntime
+installContainerRuntime
+
+
+installNetworkPlugin
+
+provision_phase installKubernetes
+installKubeletAndKubectl
+
+if [[ $OS != $COREOS_OS_NAME ]]; then
+    ensureRPC
+fi
+
+createKubeManifestDir
+
+removeEtcd
+wait_for_file 3600 1 /opt/azure/containers/setup-custom-search-domains.sh || exit $ERR_FILE_WATCH_TIMEOUT
+/opt/azure/containers/setup-custom-search-domains.sh > /opt/azure/containers/setup-custom-search-domain.log 2>&1 || exit $ERR_CUSTOM_SEARCH_DOMAINS_FAIL
+
+
+provision_phase ensureContainerRuntime
+ensureDocker
+
+
+provision_phase configureKubernetes
+configureK8s
+
+configureCNI
+
+
+
+provision_phase ensureKubelet
+ensureKubelet
+ensureJournal
+
+provision_phase finalizeNode
+if $FULL_INSTALL_REQUIRED; then
+    if [[ $OS == $UBUNTU_OS_NAME ]]; then
+        
+        echo 2dd1ce17-079e-403c-b352-a1921ee207ee > /sys/bus/vmbus/drivers/hv_util/unbind
+        sed -i "13i\echo 2dd1ce17-079e-403c-b352-a1921ee207ee > /sys/bus/vmbus/drivers/hv_util/unbind\n" /etc/rc.local
+    fi
+fi
+if [[ $OS == $UBUNTU_OS_NAME ]]; then
+    apt_get_purge 20 30 120 apache2-utils &
+fi
+
+
+if $REBOOTREQUIRED; then
+    echo 'reboot required, rebooting node in 1 minute'
+    /bin/bash -c "shutdown -r 1 &"
+    if [[ $OS == $UBUNTU_OS_NAME ]]; then
+        aptmarkWALinuxAgent unhold &
+    fi
+else
+    if [[ $OS == $UBUNTU_OS_NAME ]]; then
+        /usr/lib/apt/apt.systemd.daily &
+        aptmarkWALinuxAgent unhold &
+    fi
+fi
+
+echo "Custom script finished successfully"
+echo $(date),$(hostname), endcustomscript>>/opt/m
+mkdir -p /opt/azure/containers && touch /opt/azure/containers/provision.complete
+ps auxfww > /opt/azure/provision-ps.log &
+
+#EOF
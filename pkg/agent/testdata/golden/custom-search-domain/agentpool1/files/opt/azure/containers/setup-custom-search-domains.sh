@@ -0,0 +1,10 @@
+#!/bin/bash
+set -x
+source /opt/azure/containers/provision_source.sh
+
+echo "  dns-search golden.local" | tee -a /etc/network/interfaces.d/50-cloud-init.cfg
+systemctl_restart 20 5 10 networking
+wait_for_apt_locks
+retrycmd_if_failure 10 5 120 apt-get -y install realmd sssd sssd-tools samba-common samba samba-common python2.7 samba-libs packagekit
+wait_for_apt_locks
+echo "realm-password" | realm join -U realmuser@$(echo "golden.local" | tr /a-z/ /A-Z/) $(echo "golden.local" | tr /a-z/ /A-Z/)
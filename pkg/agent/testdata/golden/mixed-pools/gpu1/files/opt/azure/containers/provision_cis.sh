@@ -0,0 +1,77 @@
+#!/bin/bash
+
+assignRootPW() {
+  if grep '^root:[!*]:' /etc/shadow; then
+    SALT=$(openssl rand -base64 5)
+    SECRET=$(openssl rand -base64 37)
+    CMD="import crypt, getpass, pwd; print crypt.crypt('$SECRET', '\$6\$$SALT\$')"
+    HASH=$(python -c "$CMD")
+
+    echo 'root:'$HASH | /usr/sbin/chpasswd -e || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+  fi
+}
+
+assignFilePermissions() {
+    FILES="
+    auth.log
+    alternatives.log
+    cloud-init.log
+    cloud-init-output.log
+    daemon.log
+    dpkg.log
+    kern.log
+    lastlog
+    waagent.log
+    syslog
+    unattended-upgrades/unattended-upgrades.log
+    unattended-upgrades/unattended-upgrades-dpkg.log
+    azure-vnet-ipam.log
+    azure-vnet-telemetry.log
+    azure-cnimonitor.log
+    azure-vnet.log
+    kv-driver.log
+    blobfuse-driver.log
+    blobfuse-flexvol-installer.log
+    landscape/sysinfo.log
+    "
+    for FILE in ${FILES}; do
+        FILEPATH="/var/log/${FILE}"
+        DIR=$(dirname "${FILEPATH}")
+        mkdir -p ${DIR} || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+        touch ${FILEPATH} || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+        chmod 640 ${FILEPATH} || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+    done
+    find /var/log -type f -perm '/o+r' -exec chmod 'g-wx,o-rwx' {} \;
+    chmod 600 /etc/passwd- || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+    chmod 600 /etc/shadow- || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+    chmod 600 /etc/group- || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+    chmod 644 /etc/default/grub || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+    for filepath in /etc/crontab /etc/cron.hourly /etc/cron.daily /etc/cron.weekly /etc/cron.monthly /etc/cron.d; do
+      chmod 0600 $filepath || exit $ERR_CIS_ASSIGN_FILE_PERMISSION
+    done
+}
+
+setPWExpiration() {
+  sed -i "s|PASS_MAX_DAYS||g" /etc/login.defs || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  grep 'PASS_MAX_DAYS' /etc/login.defs && exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  sed -i "s|PASS_MIN_DAYS||g" /etc/login.defs || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  grep 'PASS_MIN_DAYS' /etc/login.defs && exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  sed -i "s|INACTIVE=||g" /etc/default/useradd || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  grep 'INACTIVE=' /etc/default/useradd && exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  echo 'PASS_MAX_DAYS 90' >> /etc/login.defs || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  grep 'PASS_MAX_DAYS 90' /etc/login.defs || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  echo 'PASS_MIN_DAYS 7' >> /etc/login.defs || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  grep 'PASS_MIN_DAYS 7' /etc/login.defs || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  echo 'INACTIVE=30' >> /etc/default/useradd || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+  grep 'INACTIVE=30' /etc/default/useradd || exit $ERR_CIS_APPLY_PASSWORD_CONFIG
+}
+
+applyCIS() {
+  setPWExpiration
+  assignRootPW
+  assignFilePermissions
+}
+
+applyCIS
+
+#EOF
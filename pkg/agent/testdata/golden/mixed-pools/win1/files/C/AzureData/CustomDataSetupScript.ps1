@@ -0,0 +1,329 @@
+<#
+    .SYNOPSIS
+        Provisions VM as a Kubernetes agent.
+
+    .DESCRIPTION
+        Provisions VM as a Kubernetes agent.
+
+        The parameters passed in are required, and will vary per-deployment.
+
+        Notes on modifying this file:
+        - This file extension is PS1, but it is actually used as a template from pkg/engine/template_generator.go
+        - All of the lines that have braces in them will be modified. Please do not change them here, change them in the Go sources
+        - Single quotes are forbidden, they are reserved to delineate the different members for the ARM template concat() call
+#>
+[CmdletBinding(DefaultParameterSetName="Standard")]
+param(
+    [string]
+    [ValidateNotNullOrEmpty()]
+    $MasterIP,
+
+    [parameter()]
+    [ValidateNotNullOrEmpty()]
+    $KubeDnsServiceIp,
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $MasterFQDNPrefix,
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $Location,
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $AgentKey,
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $AADClientId,
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $AADClientSecret, # base64
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $NetworkAPIVersion,
+
+    [parameter(Mandatory=$true)]
+    [ValidateNotNullOrEmpty()]
+    $TargetEnvironment
+)
+
+
+
+# These globals will not change between nodes in the same cluster, so they are not
+# passed as powershell parameters
+
+## SSH public keys to add to authorized_keys
+$global:SSHKeys = @( "ssh-rsa AAAAB3NzaC1yc2E golden" )
+
+## Certificates generated by aks-engine
+$global:CACertificate = "ZHVtbXktY2FDZXJ0aWZpY2F0ZQ=="
+$global:AgentCertificate = "ZHVtbXktY2xpZW50Q2VydGlmaWNhdGU="
+
+## Download sources provided by aks-engine
+$global:KubeBinariesPackageSASURL = "https://acs-mirror.azureedge.net/wink8s/v1.16.7-1int.zip"
+$global:WindowsKubeBinariesURL = ""
+$global:KubeBinariesVersion = "1.16.7"
+
+## Docker Version
+$global:DockerVersion = "19.03.5"
+
+## VM configuration passed by Azure
+$global:WindowsTelemetryGUID = "fb801154-36b9-41bc-89c2-f4d4f05472b0"
+
+$global:TenantId = "tenant"
+
+$global:SubscriptionId = "sub"
+$global:ResourceGroup = "rg"
+$global:VmType = "vmss"
+$global:SubnetName = "subnet"
+$global:MasterSubnet = ""
+$global:SecurityGroupName = "k8s-master-32796208-nsg"
+$global:VNetName = "vnet"
+$global:RouteTableName = "k8s-master-32796208-routetable"
+$global:PrimaryAvailabilitySetName = ""
+$global:PrimaryScaleSetName = "k8s-vhd1-32796208-vmss"
+
+$global:KubeClusterCIDR = "10.244.0.0/16"
+$global:KubeServiceCIDR = "10.0.0.0/16"
+$global:VNetCIDR = ""
+
+$global:KubeletNodeLabels = "kubernetes.azure.com/role=agent,agentpool=win1,storageprofile=managed,storagetier=Standard_LRS,kubernetes.azure.com/cluster=rg"
+
+$global:KubeletConfigArgs = @( "--address=0.0.0.0", "--anonymous-auth=false", "--authentication-token-webhook=true", "--authorization-mode=Webhook", "--azure-container-registry-config=c:\k\azure.json", "--cgroups-per-qos=false", "--client-ca-file=c:\k\ca.crt", "--cloud-config=c:\k\azure.json", "--cloud-provider=azure", "--cluster-dns=10.0.0.10", "--cluster-domain=cluster.local", "--enforce-node-allocatable=""""", "--event-qps=0", "--eviction-hard=""""", "--feature-gates=RotateKubeletServerCertificate=true", "--hairpin-mode=promiscuous-bridge", "--image-gc-high-threshold=85", "--image-gc-low-threshold=80", "--image-pull-progress-deadline=20m", "--keep-terminated-pod-volumes=false", "--kubeconfig=c:\k\config", "--max-pods=110", "--network-plugin=kubenet", "--node-status-update-frequency=10s", "--non-masquerade-cidr=0.0.0.0/0", "--pod-infra-container-image=kubletwin/pause", "--pod-max-pids=-1", "--read-only-port=0", "--resolv-conf=""""", "--rotate-certificates=true", "--streaming-connection-idle-timeout=4h", "--system-reserved=memory=2Gi", "--tls-cipher-suites=TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_RSA_WITH_AES_256_GCM_SHA384,TLS_RSA_WITH_AES_128_GCM_SHA256" )
+
+$global:UseManagedIdentityExtension = "false"
+$global:UserAssignedClientID = ""
+$global:UseInstanceMetadata = "true"
+
+$global:LoadBalancerSku = "Basic"
+$global:ExcludeMasterFromStandardLB = "true"
+
+
+# Windows defaults, not changed by aks-engine
+$global:CacheDir = "c:\akse-cache"
+$global:KubeDir = "c:\k"
+$global:HNSModule = [Io.path]::Combine("$global:KubeDir", "hns.psm1")
+
+$global:KubeDnsSearchPath = "svc.cluster.local"
+
+$global:CNIPath = [Io.path]::Combine("$global:KubeDir", "cni")
+$global:NetworkMode = "L2Bridge"
+$global:CNIConfig = [Io.path]::Combine($global:CNIPath, "config", "`$global:NetworkMode.conf")
+$global:CNIConfigPath = [Io.path]::Combine("$global:CNIPath", "config")
+
+
+$global:AzureCNIDir = [Io.path]::Combine("$global:KubeDir", "azurecni")
+$global:AzureCNIBinDir = [Io.path]::Combine("$global:AzureCNIDir", "bin")
+$global:AzureCNIConfDir = [Io.path]::Combine("$global:AzureCNIDir", "netconf")
+
+# Azure cni configuration
+# $global:NetworkPolicy = "" # BUG: unused
+$global:NetworkPlugin = "kubenet"
+$global:VNetCNIPluginsURL = "https://acs-mirror.azureedge.net/cni/azure-vnet-cni-windows-amd64-v1.0.30.zip"
+
+# Base64 representation of ZIP archive
+$zippedFiles = "UEsDBBQACAAIAAAAAAAAAAAAAAAAAAAAAAAmAAAAd2luZG93cy9rdWJlcm5ldGVzd2luZG93c2Z1bmN0aW9ucy5wczG8V21v2zgS/u5fMUiFjY1Gdnu73cP6EKC9OMnmLnWCOk0PSI2CpkYWLxSpJUdxvF3/98NIsiy/ZJP2sNEHJSbn5eEzw5nRC7hKlAflQQBhmlkn3BxipRHIAqEniCyF3uZOKjOFODeSlDUePFmHESgDHjPhBCF46VRGntcE/K6ywk6rFStN6OBKpehJpBl83Qvap0jhQBBCeGJdKghspw/Bl71Fq7X00frkFGF4bqftIEXvxRQ7ra8tAIAg9VM4hOUy/LGyXuyXmhc5ZTkVwq2GXRjYmdFWRCdK48Udul+JssrwpXAibRc2+LkpfiOha78XJhJk3fwwIJdjZ3zjySkzHdfCwUenD75TdYCelBFM7aWgpNjotIo/L+BEOU8gE5S3HBWPCCoGUdALM0UJUILgRYpg+MXR1A5FNAcpZIIRWFOIXP86KEwGrDlk0UO4Obvosstxv3+KdFJttPkwnUK4eAUehZMJHMLbdrmsYmhfoaeQlSGYajsRun/E/gbKlTJfazZW+uxvoBxK5nLl1Lc3TRysYB4UWqMCwkXGJI37/XdaL+0o9KXDRavGVnnsHtncEIQG4dUmqDq/YO+j5+yu2LpD55U1YOMGUyEc2WzOUkwexM6mECy93Lwadzg0m3Hcqwl4b+8wPCNMoSKsVoSwobRlobgfEqvT8Ru1R9g4SeBR5g4vnSUrrfaNOPETKLNL4GY094Rpd4jUHfG+ovnSxNU8w3G/X0oMMBa5pgN4iobXPzYSh5/YOhQygXaQVda5RCxtHZs8LfPgWugc/eOwOquTrSio47591tBYktaQUMZDjWFlZJ3LBzl9ebhSXpNetLb/Wz+Eu1MSL60y9F4YMUXH1G4cjevZBvJ1GgOro0tnpw69v3QYo0MjkdW2V1dKOzX2R0qjIT0/soaUyXG/Vjgzd/YWw084+YC/5dwAuBRA+NHjP4VX8lK44q5wdeWLuyNjr9FNrH8Mw+7j7Lqcy3qNUd2ZNp3uVRdk0Wq9gIQo8/1ez5OQt/YOXaztrCtt2hO9H3968+aXN29+6v38y99f/+3nVVMY4iy8WnbAukBVCRZkwqEhOKzjulY1WY9/VaVx2RkgMFWNrZROcxWN+/0hzvi/Spodl4WB33zvYOW+LBbtf1llqrpRISlMd9ba2plRpIRWvyN3VrE0otBXp3gBI5silzVuBv/OJ+gMEvqiz3ugRBDM0CFE6NXUMN3Wwbky+T2Qm3OBS4tq2qM0A2XIgoDMRpXxCwOflInszB/AqQVywngtCvsWZP8zpVkXzuLST8ZdI7LozT4B3itPB4zKgGeIxkZYoYqF0mVVDxz+liuHERPBJW6/NLq/c/sPOLHuWMgkvJj8FyVVHCzLRDi0tNbAvnQ6DZEnBib4UmssVhlY59QHJDcPj2yaChN9x4RxLbSKBOHQ0jDX+sIdpxnN27sGiMrJE+ePhw0nwickJhobw8k7N/X/t2FlqGGSmVH4V1idD1CL+QilNZFvzlGcy+1AwSG8+gcECkJNNQ5eePmymQCc7+vp4JByZ+CHmmt4y8TUMguQgmSyoTUi4SgcacTsIXwPZU9ViY/vUeZFSB7IoKra1G6DlUaD31LqZtyQe+em53zx6hUO0rqE1naG0fG9oiMbIV+6t+1XnXWNhnxFJxzC6z+VWQsSS39/oBpD3IfcGG5NDQLqQ0K3211NYz/slKm3ebYNzt+Nro7/c3Z1dDE4LkYIZbYJ2awZDTRND2XuYAS58bmU6H2caz0vh3e8VwSSK96az70H8yissvvPEoofWTX32tCiHB6/DXIT8Dqm8lRbFbBYoMTZGewd3yci94QRC3MAi2u4i/y9tdznz8Mh0sy623M7PbJaY3EpRtUn5tfGZ17B9ikScewr0XM79d3Mvwa+pxFmPFoYqdCXJ0hvI+WK/nH7OcJJPi3nn10fhhDy+LO/nCmmipJ8UgwTqZLOehtTbzQY9pyY9VLhCV1v1Vl7s7Ih9gonPVmC0xW4/bXRn9tWE9LnTelngRjlaXYdZ5dWK6brCTB3aTwLVM+lNTGenJDYlWn0CJ/b8s8HMxPyFkmKjHL3ZKzbSs8E2Gbfjtdmzw43QZ2h62Y+fewqNSVbi9b/BgBQSwcIx1zPXzAGAAD2EgAAUEsDBBQACAAIAAAAAAAAAAAAAAAAAAAAAAAdAAAAd2luZG93cy93aW5kb3dzY29uZmlnZnVuYy5wczG0WN9zGjkSfuev6CKuA6o8M2ySdWJXUVteG+9xG2zKOPZDSKXETANaNNKs1GMgsf/3K2k0w4+Qc8rn5cEGjfTp+7pb3a2p1V7BEAlohmBQ3/MYgVBgiqRX8MfH3nkINzNugBvIDSaw4DSDOy4TtTBwKplYEY8NzIgycxJFiYpNmPJYK6MmFMYqjVAGuYlMHKdRrDRGseAoyUQpk2yKUaokJ6WDRYEZsBKzNsllTFxJSzC4KUkNkYjLae1bDQBgwDRLm+6r/Xxyv5FQN/tMJoyUXnUOSOfY+vzJkOZy+rmafOBlVNBWrnvacn/ttj3CdKBVhppWEAwYzaD+7z8/9E9GQzWhBdM46pdqRx5vdJZrjZJuURuu5GigBI85mtE5I3amhEAnqw7BJUsR6mcqTVHHnIleUofglokc95OD4ELpGGuP1m3XaPhXLDy3MoQpZEwTt9BAyo2nbAnsnnHBxgLBTg/h9GuuEWImQRcADApPJJBwMz+EcV6Ew20fDHEhQCImxkLiklAm7uF6q7HKZcL0au2uglhwNTzX/B69ow6UcT+hA83mH0jBXcqvxn9hTDaa3rz+cpWhZtazQyemFRb/3aJWeKN52pVJs35SL5xz4Lh3wGENKjaBm/8BiVBXe7bCIf+KxbKULYf7Vg7zLFOaMHFP/wdMny0dEp9AsyARCKpwC3LfqhjztniCIARu1xLErX4svPzkwcrUArWZoRBRqpJcYGRIaXuyJC6Cyk9r9/QkJ86EpWUD8pybufFOsra0v+EB7maoMfAeWluJVgIhwL+hodmiAQ9baHblWmoxt//7tR0z5mamc3iAS1xsWuOjsQblaZ46CwSnxvCp3LTRA1wonTIKbpXIU4TgggssYgMuby6GW2eiTGU9SaglEnSXmVAatQ3f3BSnRTBCQ6BRJmgzAqQqQVASmBBgOKH5GbsvivMZzJhObB6I4tyQSvlXjBI0c1JZlEtG9sRE1foqy3EMuOeInqId0EwixSrNGPExF5xWllvtFTRdElZSrIBNJhiTAS4NMSGYNaQp8vLHXmvtZ5u+Svnev9b4NqP5TOYSWX00Gl5d3NydXndH61S1kdVG3xmz/pJgo9HvWi0M6m6aF2K24Xey7wtu4/Nvz5v9bNPsfRsSPhW3IbhZZQjnd0onW9z+b059xv8puQ66LDFDYppgwKZY92KGrhiWEm20n0TRmMuprdk29VRx1CviLDhX8Rz1S9TdAsmXR19w3b9XcH519mf3+svpoPfltns97F1drsvP2LYoBBOloUCA+wLCgBKJPeMzJuGX92H7OGxDt+shkxzt6jKRTjnN8rFVGc3zsbMams2v3JgcTXR0fHx85CHIHj43DgtmYMKXmMB49TzMV+5HbIu+pODtm/dvj4/br9/6rS4V4YnlO0UCBoIbAjVxqalUe+gzGTdgJM8yJL/2oLJHB5pxrkWIS4TgA9QromqnOZssBJfz6LcPXM575533r389enP0r1jEPOm0l2/bx3V4gDMl71HThVZp8B+jZCv0vjPw4GpGH9Mxah9ZVkF1aB9giLbfKSuJ7Xg8220fWsmng14pEhKMVYL6pLKx6ywTt8YZOsF7FCqLTDKPXrGMB35lkDLSfFkElFlwimfQ3A65cJiPi6Bstg9/be3W7Pov78L2UX1jxH7uNCcMPqgp1D1zNw0mtv85tJFpm5d9AUwKfgnftOtbcJ+KEhZ25T3XSqYo6fPJyRBpY+CWaW47t2bje9TGITQsauNwH1S58obpKVrgPotnXPr2xH4ea9XXuj0yb35Cr53203rf/SN6372A3gQnLBf0pN4yFLeDpzRALJDp/R5/ceUHMhfi2cI33G0vdWvZB76F8Ho74G5K68fFaRv6a2HHHfXy1wP85g4zBILPERrF3Ea12LXHWwDhmcolueax3XrS+NyAVFQ2OZiEZR2CZCtv7BztVrg2/mP1DYXBnR03uK1d29kBhyCYuOYTGt++hVYH6jL3PT42npBQ3qd51auV9+ftXZr7uGwK2WPPNWdrzx0b7Ejd7+oJEwa/m7eR5Ya2cygN4Rlv6rDN8pxn5Zg9Cs/QtXbSDxz1M6y+i5U7y84T+47VtrF+yGcjY7hg9nDF6l0bX3CZBAMWz9kUfdPlAzkYaHXPE9Qbg30zoXIYgmv8O+cak9Kj2wQh6Gqt9Kl/FUIqq/3AYRe2EsH9XpTq+NgOL9zR7B89l//HLGGE/h72lJytjW0zbFxrWvio9oNQ9ATxR+rWcgr3PULMbN3/VtsT186csJhxgWWI2NjNCuedwMGXsLuMMbP2DvtojO2aK6CDuHi5s7X/+j3EMwKg6qb2kb1UBLYw2CbCk92J5xA+Gsu/rGqVhfYRrfti4F4t2OvAxFqBJX/lhmwd3XjpduoGAzvJ3bj5V2y2/D1ykfIYMr/cWCcilW8C7MTO+/bx0eHGxb7zvn18VHus/XcAUEsHCKLe194wBwAAbxQAAFBLAwQUAAgACAAAAAAAAAAAAAAAAAAAAAAAHgAAAHdpbmRvd3Mvd2luZG93c2t1YmVsZXRmdW5jLnBzMex8e1cbOfLo//4UtY3PXrihG8hjNuMd7w4BMvGd8FhMknMWuEHulm2Nu6UeSY1xMnz3e0qPftiGGMjs3T9+zDkTu7tUKpVK9ZaHBY81E7z1STJNw90vhaR7gg/ZCL62AABOiCTZest8xr9z84BqKtcPCU+IFnIGXWhrWdCNy3OlJeOjyxK8vbu7v5cyynUv2Xw6kj6NJdWPR3RGOXkaKf1ioGLJcmTaU/CcUiUKGdNfpCjyx6N5L2KCpDwew8fsbJbTx4/vFwNO9RHJnoKDxoVkemaY8TRUH4+eSsypKDQ9I4OUroZnSFJVIwXW4IjSBASnIIagx1RRiM2RKiRNSnTtE8kyIme714SlZMBSpmf9VWl/4pz9mKR05cnuYvUHRQ8JJyOa9BLKNdOzgxtNuWqK4wJ/Pygqd5ViI04Tpxr2Hy86HxTtcaUJj+kh1SQhmjwe2XtBkjckRWSyPykej+jgJk6LhB4Spal8K0XW16gUZPL+zeOR/loM6D6Tj0dwRuSI6gN+zaTgGeXavNqwyp0NYR3CI6Hvk0wId3nShKrLEmw4q4F/eizFFIIDpsdUwj04hYRluLJCaRhQUFQHhsJbS2ibVEbqLUspdOGciSgnenzZ6eyJbMA4XS+5BYGBj35TggcbiyigCz8HLUt2EKeiSIIOBIu8CizfA+1MiIXyX9xL1bAOBqRpMDwgIV72LVTNTC6AWKPXBHPPHKisGxMD2DAvHix1xsJAeMvhX14bO2BeWZPgX6hSwfv1+K8eYF57W7iFpw78uo7Nq2v/UjaUr0HU1MceML9Tnsygu8VtDkFdfOsjG8/dkOJuhRd0VtCHQVFTfV5n9vbNtMu0Ym3eeR3n5luu+oK0qcfcfjefOdz0bk0VdO5VZK3bVvDzkvP0BxwXOjQnM6Q8FgnjI9jt7/V6EA5ZSvGgQjB/ioPWbavVmnNH93b3qNRNT/Sxys/iYkMWE/0E78CpFac58f/tmKykhmISxVIHdtB5f6Y0zaIzeqOjA8ely07H8Cn6heq+Ud7rHm5P8Gsq9WWng5vwhij6w0sH0lzZxkZjAzxqICpmzNPaul1gNdK5zPF/LJ++E7udBf3X/tGJpEN28/iNs5h6J4/HsDuiXP9KZ0/E8GeJ4aTcwdXE0Wx2aRGr0dYghmHYIjn7SCX6cx243mnFaYHujOq0QnCfO2ZwXK0oJIUeC9T7IeojVDwNSbCWXFF5TWUHxlrnqrO11f7qd+e28/LlixYAJxnFwfP7H7RiwTW90ZYK+9lR4UhaOgrJLNTytyFJMsaD+2ctpKRch37GpUATxpMO2D1o4WyGyLuWUk2LoH4N6BOEdYZ6Ns7LTlAfMKEVv72YBpV6rm3uitq5GoGgQUNhvClYmoQnpFAIognjVH4fHf2J8URMFaq36mi096nSjBtn5YyMapp3Dd4WupAUpkJOOnA2Zgo4pYkCLWCAVMKU8ZhoCEFRWorbiOlxMYhikW2ZhMcWmaiQ8hHjdIspVVC1tfPyhx0zR/D29PgQ2ut12jaC5Wy0OtY8PUE27ot4QiXaPItq73Af4iyBrRhyhA81oEuWjoXSD0YJ4W6eU26Dux6/FhMaHtzQuNDoKkH9c5AYQgIId+XoPVMafl4PDH+CTQhCjf+f4zI+ioKNxr4f0WnY40NJvvOue0/9rh2HLgSTYpBSPWV8K0e5C2pSECclCmePRZYXqE34UEAXfqE63Ks9smdiDU7pkErKYwpDIV3MrMlIdSAuZArh+1JeslhGGYulUGKojdhcP9+avFZbsZDUErSFI7dSprTDfjamHCzfQRYcwlBmkOUp0UMhs62McDakSodaiBQYVzmNNSxO1Jyl85Mmo384jZ3QISlSbc5hLyMjVPnBNzHsRM+j7cCiUFOm4zGsNzgWOUF3mr8e0QU7r7dfBPAVyp3CvzbDyY1Ydf2KzSO1jMYwxPUTDcHXr9EpzYVi6APc3na+fo3OyOj2Nmhgx7A05ELXpqmT5P8ecQDyIk1RzBeJDDYgPKVaMqrglf0426cpmfVpLHii4MV2g8bb1hNJ0WR0ByVLTuZGa3Fe3Jkf/2dn/it35kd7ZpZazbBmVZYd3ql9vcUJF85psvjCprqeV5nV/E6kHktANXHIuGKJ4c8qc982TMcZqrpyVsPAgxumtGpWGR4bJRiEm98cPpc1LeHblVth/mlrkyhDm2Uwl/kxBKxrHw/4rAtBB182E1VOCza1IqCRKEfW1SFmqCBCBWiwSKoLyd3pUtGeKLiGcKRhG1nbWoOz4/3jDuzTXFJ0CEGj/0N4ApLmKYkpTJkeQ0b1WKBLNCYaRlQD4wm7ZklBUkC9LzjlWqEJ0pQkmLL+wnJMWONeoZdCr6mc6THjI5wT56A3aK0QJYV/907AuCPoclGIJSWaJjCYgc16qS3jaIROksPJaxWpcRV9onVGB+CExBO0Yd9FHhDhG8YJ6on+bv/D6XvnL5h/2l9YbkjuQhB3LibRF5bbjUNHYL3NoAvbf4c2gzClsGM+PntW3/d9MeWYY0G37Piaynda5xB+kOmyqRtn1fiFnoCSYFSl7X/WZ8C/gaRkUsJUR5qmyvPJ/9l0yXsxgvaBlEKeb19GBzcxNYnI6JAq5cW4QmURHtzkhCfhrozH7JpCmNcJXCR9r3PRONh++zy3m1XD77F/85unaZYnTEIX0B89o1kuJJGzfSZpjHgNcHuA9My8UHUh8OMuJpEmMhp9+VN2fPl2N4j5/73pzj8tFJ5tTSQe3IxxlrEvFBKKIQXlMaPKASKIGosiTWBAgVyTlKG1FBzQG3xmoBAmvPkynOd7uFful583FvkMBl5axDU1BGC4CUORJtT58E5deEAjle64XgSlPv7LujEs5u2yIRv1I5VNUGyWgdWOQ5yXFF8gUZJTTdUFFwm9GDB+8b+XIkBPJC6kom6VkmbimgKKnFtVqRinGBcU/AvLMQQ04AlNy0krTLeVivf6HNVaiqlBIjUKolXwxD93Fb9NcNMf9fuHm8YgFIqaeFiHjAP6ESymwLimI2mseKWO8UzhuL4DWn6c5w3o/ee7lHgfpm1+H0x1VnwnlCdS3MxK/taNxhrWoYrcSGpKbZT3Vwj8ki64UllEb2hgLClJ03JX9joXzsu7sMncF8/9gxMxpbI/pml6cb0TbV/k+F3hd0R1zxyK6hL/bp6Xyq8ZCa80ttw6tcjVFSnYZypPycxUAB2K1Wk/pQr5beIreLW9vb3i0H2jqo65l1Qb36w8uCzTPZBiwxvoH5x+7O0dfN79cHb8uX+2e3q24vDjwW80NnUowAJcaiVixcFYoCun/tQ7evH88/Gno88np8d7B/3+ikh28/xsLIXW6Nu8Wpndu3ne14kojDxPLtwxiFIxehACKmUTAZXywUhEoffQz2SCo+hhCgE38uVDKXkiklOhiaaoiBXsrD63HXbMU8bpw8f5FMTrH14+ZO/spG9mGmndfvn61d9+WNRrOWq/FTWbgf1TdZud4bHarRw9r9+WqPhVMM3ruG/xyvPfwC0oq9V1jh/f1FcPm/0JOstM9HitZYc/WW+Vm/lQzVUOnNdd5sWKiqeOpKG/zIsHaLAS0UM1x9zAB+iOuZEP1R5zw5v6w3iop5STjCYwlCJzYeivpd9cHjNVeZimZSFN61DWgvvYMX9E7Hh+OefIpVTbOtyuHKmac/iI0oSP7VxC/PHIjqjGQtVJWozY09EcimSFlNd9C2t64g9cjCmc7R313jD+XfDgbj0J0d5RD5Mrj2eJI4KNvgOKp1Hy9FYFs73cn6zeExqMLS22yffxWJCePVuf3+vtnz4NkVvV0xC9O+ofiqRIn3iGUqqPRELfkwFNVTNe3CNpXKSYmFUio7bSC5iqsHDtjyItMmq1wb5JaJ337mtjvDbwuYFXrgq0ELF9sxnSOe4m5opytVNH1HSNVkKV45A6slYt1TK3wgbFvsbSXaKvweWZmU0xM840I6lNnOdEKZoA49biVJX7pdgxMR+GmL0JU7NF3av2KBUDknYWdi9w/vByHFijR0sXYspKsoRWmIwqfOfeBy4t73IwISSYVY9FQv95D4V2b0O7uWHCZIV9jomeyt4QZqIAIk3en08wm0cG6OOQxHYOcGEab4kcwZhKumngq0xeLGw9B2HzyWjL9T+4epEKfYQ3EjBksqxrH/N0hjhdQQHbLXzq38t7gh29sZUX7MDQY7CkCE4VJg71mILLgADBbpV6gjFJaGIIjlo+QjmlI3qDs+ARzOHaGmJ8cEh+EzI6ZFzIyNS3wJVTVBGPLYU42VCkqZjiSuMxjSeQCKoAi8qxJGqMY0qkKZtQuIlm0ZeQpPmYGBLOJVJw2Tb/YB7y/55vhz9Gl8+C1l1uAjbn5TlNUMDNsOiQ6HhM1foy6I3oI0mLqtDk2/4csst7ZwhTDfMDgp3odbQd1POfaxCGJGch5v+oxFSvrRspe5LMgAXl1jwE1XDV9c0RV+2v7kQ1ergalbA11xSFvTpOrnD3MC25d9QDp9OwDEI5dq8kUcmJptcEIf3dNXQ31rbkVP28HoRhzFk4cCdqzmXB0roFwLRpE8I5I0432sw5Vl3uIgjXxKl+OEnOaVlGS+mOGICq6L8GY8KTlELChr6BhXHsMkP5HlA9pZTDe8aLG/hrecxk2eximX0n57oLT0Kffi6XuQlBzFkwx5zF1v8PfMLFlAO3HAONceCcGwwx4f/Lp65r8jEnPx+c0v/EOEqMP61i6OWptEHLtGxfo3kNgtayFf8Bb4WkJB6HNsytrWMN+gwbhEaUU8yU8xFwOjXa3Cm9qWRaU6OLiKmDbpYqkaqY5BR+LwQmXciI1LhuBKlJR1/LKKV8pMe2yFuXpTuXtGSdzwD3Z0nZ8AEIroIAnkH7M7aB4NavB1f4bBPMv/hfsOHg3DbdzfSfr5as9GrDFbmrnTVnL3zI5u6JLCM8eY+xcLcWBTuJwBQC4Drm54fwN8E4BNBofjWxKq7ftL9eteeUmpnBa7ja+9LTJjIen7gylbqOI9eOGhm/b9kA55pXpNf89Rp83QGvEWEfzOGtudgl2tqzOeiaH11C157NQc9Zn3LE3PPaKKfcDKRXdNVbpwYwjDUQte81qIMbTSUnqXuLkPSmvuxSTfpp7LcaROnpG4jyW9C6y8UycAtuV4nPkWJfdYOmPqthXQgPusFiyBC0yi7dB1o7B4nEWojyyDfF+ZmRZ5uZQScVguYcZvAmGP2JCs6fngCzO2fYgSKGQ3jLJJ1iVQsVm7HRkJuuEgGapBP8V7lCDOVJLhjXKoJ1t17v39FrynVB0nQGiUC/VW20ONVqDCS5HvopMOtH0jSXAhWqQtI03qUcttbws8QyHHp1bkKk8y3jCTIQH7uLPUCd6Pg2R2+FqGk8itCDti4gFmbRN418fwtTkJjrm+jrCjQAjBvUKRtSzTJ/sRMwsGhdtcdcVbuBHRHvqgd/wD/BlHvCg3/BXWLdwr3/Sx3RhruRVt83W65AP99YoTFX4KBdRTzPRDVFKehWuq7anKBqW0f6jqjeTUiusQHsZDxTLCbpRulyIahvN6K/w7Z9s2Dd3xKWWks3RO4TPoPc4Sp5TewsqnGBzwQvGBBQ5HQmTAhDOG6BH7BpfENkuYk9/OPILSUz16zwBqNRzH5NvZPdJJFUKQjZsMcTemOWjX0R/nvoIN6SjKUz6J1cv9yIynEOvZvNbFu3RIFfDQDafGoCQgg+mDYKN2BuqKnO904wIMMp5+gO3GTZKEPCh5pKt5tmn8ttU1rknwj26dqOF+ekOH9/n5ERF0qzWEV9LfIpQs6PjIxtWy+b5tlNRqFjWExAZSRNqdIgCR/hqVYKGzw2bfQ0ZWlqj4lIU5b4lgPca5GAyklseYJ9A++O+m4FEJ6hq1fKontsdL3fAXsJAoKdH59HOz+8jp6/ehVtb73YDiD8hWg6JbPmux1sX69tTJPXofn/nWYj/EjlQJT9GfCJMO3bvr1+cFGs69PAtgmUP5aX+2cBiLukF1X9Q1dt1t3++5VpGNPYFoOfnz2bPzd377XrUm+IcFNaIDR9Xbvmdhz0WUq5TmfYUcm4ixur43vXNFUQsVqjmUopzSE8ZGnKlKsc7PgK/W1riZiJ3ElZ2bb9LWq+pVbm2EAQjdXZePDu3HCngOruaf3Y9jD+FBO4aq9ftfvlAg5SkiuaRK5MsgF+1Vo4wTAy8a1Zo6B1W7dKXht62RrMAP1ca4g2kVczqsq2HaZrdkjS3wsmLT8muBs4v8fHBQ9zdLuUxiTKgI7JNROytQa/COTOUMgpkQkeZaZg6jM/xrYNZvDuqA+k0CIjGpU2GuXCpIOkbcloWLmHmLm6f2JM2zLLtgZ7KSW8yNHW+35TKq0Ots0ckCsIf4c/cCEYoMFX91xmcNX+DOHwtrVoJRErrqLIQaSJWaVn2FAUPLEycWqYXbfUdSKdpvwVtZ8hjzPb18NjquCv6IZgBE40gZQONXIz5mzJuqwHIynmgc0mejBN1GSC6Ld6h9YHC/FitAlatob3gYQsJ1kd7qodc2aah5dmbMvAKPB34Wto3K14rztsPx166hXWjfmD6ljX0zSroGoHzTxLRTx5JD0YMk1cxmMZUfj620QhlCPqCVz6L2LQn8MbV8S1B74s+W7CdMzisdMZU8L0JhRcs7Shf5jyKs1rrClRMGQ3NlezE+282jR384jEgwdKo8BjfgQLxkIixMtlt/Kqzsv6R7wWsvW31z/sPG85gkMXrlaEo56i/Lqz++8PpwefD44+9k6Pjw4Pjs4+v+29PzjZPXvXNT3fF4avSpN4Yn7zwTA3aLWW5BZMhHZnqst30trEVOSy/72hz1SpIs+F1MgrWxIw6hjvhmFg5Jv2VVUmAFQVspSlui6FLgTp84Fkyegb4V5Vbke/Yt/m9J1btX7VxtKZjzDcNQP7MMIfsLDXyrc33aOUKG2c6ePhOt4JNJmfaKd5JxSnOREJIl73mK/auX0CXYh9z0SsTW8jhKbAgKlXNuqat/azuaKAcZXaMsYZN9NfT8P6SnQm3mMn0frGBoQC4kJpkYUxRutcdXFClshOhPfpIj89VoDCMSWJNy/lmh1EYylWBbq1lGtYYJd73sjZNfB8yBOCv2HgExQVrk3j1VRpnV8+VSxDOeyTLE/9bfxuK3A/ToJJV59lwZ90CLbNXT7/Sw4Bsse++MkJDDrc/6gAMP9qAaaMh06OyreWIvv+AMMzzC+VbxOuggA6NckPgiOSUQzCqbTvzs3UJDNZJCrVP4IALqv6ahDYLFkF63JTsH/Uh34xxHhASLBQgErNYDAIbj0huUgZtqcjoedziteSZPAHwYHLQ5zggFmAej0wVRbz/isEwZnhhwE+LvQb1FFHu2cWsrw9gRlDM1eNYNxE5Cs+Ohxl2ibjDLFwWyf3e5B2evzh7MBOVr9ZYMInt921rJ0jC38O6oDHJDcYsU3AE2Youmz5W0ZXbXvu/o/1H9zvTOCPTITm0aJELgyLuA+UnVtck77quC4OS7iKaiJ0vn1ZRzKfEl0+3srKXUN9TnZxqZEXI0wOGKmo7svgji/BWEukPhzdTgNdPYV7D64dT9rCvkMd21zetvo5p7qv4mBLbTTvP6HbLEMMKDGUuGuUE6EFp/sXWyChiQlsnOY6r6/q0snbbpJ8cxYIDQth3UnjmfCyWOHDaza5HsPzbXNJXctZqUKfYv99fqmunF103rSi926msw3dmlV0mJ2B2mcqxkYCmnTvMjcumPBJTWfKxsQWrmdUwwALfT4dgbEFJlSN7XTpXOOsuXwu5FLELouBJraGdNMnOzgCZblOZ2DiDqarOqy5g72E/nk5umoTVzJeVp1qlfrwqu0J6tr+1PDEfa9+7KB0GlIXGIUnRKmzsSzMHd0CE2l4uqpJK/xr5ta9X7z1XR3x6Isl5QaUI+ryjB4vxpBalJA4GnBrKs9rOsZLON9gTZM98xP1McXiYnXY2Va1vViNBvyz/DOoYGe74kElcsuE0f89RCjdEJ/8cQ3kWuRLRY2LqcsiUhiTawqikIaFm4arRCYuYW/BRaHzQi+RkD8AUzSlgNjf3jgq0rSujv4jxQqc7yEFC3eIl1Ut8E095eC0zDdLB8uNa3lO6yjnz+aa7zs3asSoD5fq8X06JiWF4uhCvKjcjXvTNQ9L2bgBsAaxy5S42ZYexRXzOSvmdJYcl5oMfcdqS2NjH50dL0OGKik+HzrMp77d0pvyUSXAkTr8hdKpZ3olCy6W36yiaS+sBsamv0uBcZj+o2E/TnlP6H/H5q6h0wHmlwVxEZiWs/1oJ8f7ngs27TgfstX4P8921zmwJF1w24pNEaZewDPJe8xZtm6rdAKsYa3UJhQaSQSvNo68/kQtaS+AoO1y3SiYSRgQ7J4QHKbYjFf1Uhkr4rWOMLfAlekVtMmXTZsMB6adhZu0FjMJK/2On2dA2TVa66io2klrbRXonP364c3B56ODs0/Hp7+WfQRObmsF9NU6BO4p769cl20krJ1V/2Y59pOzzyjRHut5HdOlOzLuXEGE/wV3SekDbEGDXEzgtSpViqLiawIJmFBiZjpnVWutdZfOWoNPVZYO1/OwnNwPr398/gJr5D+Zo73ZWjPy94jEnpn8py6iedVylXQbv6N3B3/U0vbVY5e1860/obuWY5JM190XENonYSYS2p3gpKkpXMKyrt6FhFMzU1XOZCOR5k+sNQV+9fNTjatO0PxF7NBNXN7whavSXIbzh3DxWM5DN6dcevn5tvX/BgBQSwcItXOqL6UZAAAdXgAAUEsDBBQACAAIAAAAAAAAAAAAAAAAAAAAAAAaAAAAd2luZG93cy93aW5kb3dzY25pZnVuYy5wczGkUk2L2zAQvftXDGkOWVhbpMdATgltQskHbJcelhxke2wPtWeMNFq3lP73IjfZhLbQ0vXBjEbSe2/eUxW4UBKG96jphv3Rd/PkWwIAcLTOdrOxjN+TV0dcn14a0w37R9fCEiaNau8XxtSkTcizQjqzo8KJl0rNw3pvnB1MZ72iMx9Cjo5R0ZuBuJTBm4Z91vtuPrm/so3sqOhmO8ulVXFfl1N1Ae9Of1Cyf9hJGVoc79+N/7UM3Iot31GLh2d0G9Ue0ij4Ijxdo1diG+c/Wm1gcgWaJN+T5MWcx760iukn4tV++6/2xNNMr3Ooai0ztqZ9mzsqazQFkxmI05/LDL/gf5u22m/j1DeWTQfiginq/YXjdjsaCkt42krWW21Oi8VKupwYZxfI+wvSX5O4evRbGGeIyBazeAMfD+vDAnbyjKANQiFlLKxC4dAq+rF7nqAQrqgObswWqqhYgoJUcH5zn0OOLWpMOOv9HCyX0AcFUmjQ4Y8BAFBLBwiRGOHYdgEAABcDAABQSwMEFAAIAAgAAAAAAAAAAAAAAAAAAAAAAB8AAAB3aW5kb3dzL3dpbmRvd3NhenVyZWNuaWZ1bmMucHMxzFpfc9s2En/Xp9gyukZqTClO59qpM5xGke1E11j2WE7y4HoSiFhJqEmABUA7SqLvfgMQpEiRSvzvbsoHjyjtLhaL3d/+gVutR3B2vH+8BxJjcYXgA0VCIRQUf2/NUh5qJnhrgtp/x1GfROmc8SNBsdNtfWkBAJwQSeKO/Wiec/uOGmXniHBKtJDLoK1lit2Lc6Ul4/OLgrg9+JxKHI5HQ8Fn+0zu3FGMUci+de3fRzBBrcAKh3fjgzMYjkeQWN1BJCiJ2RSJIBYUe5alPWMRjkmMAAGcj0QvIXpxsbc3FPGUcex4m6p6O+DtPvWJWaMXCj6LmNJetn7nFWp/KLhGrteSu/AV/vWl/QF8iUlEQgTvo2c0+Ojt9X7ydkqv8NGze/ro7Xgr+ArHqfYPWYTgIw8FZXwOg8lwNALfqG1UXS/TWrVa64MbcaVJFJUOT/2Dzi0X85Lxe0h5N0Y9HI/c9t6evqm4wlAi0Wg9gDKJoRaSocpOPb6kTG6q0fSL88+Wc699cc0jQWiziznhj+CISSkkzKSIYaF1ovb6/TnTi3TaC0Xct8x960F+KLgmjKP0OeprIS8Zn/clRkgUKrtq+zNLzGlDAN92UOecmdwrjrr3mSXOM3PFjTMdX6F8rXUC/lsZNVgR/H1UmnEbLCfWx5wKVp+DTwnh1B/IcMEMbiRligbeBiNTjIpt5aZ9zzgV1wqoQAVcaOCIFAgoTIjMDzISIpmS8DKP6SmGJFUIeoG5ACfuEiXHCEiqRUw0C0kULSG0LqGArAUxrlHOTFDOhAQk4QLcQQAnMaqEhA4pHsFQJEurRk5hwp/NwZgGCKegUMNUMjrHEsJYdN2wQv+nAjrqvra6BTZbseNMnewEC3x+VHbSXONERCxcgsS/UyZRgU65MVNnQZhMGO9avQuzOmqmAPlMyBApMG6NvRBKZ2apZwjwMqlea1UCJENX3imb/4Pw6I90ivtcTZDIcGFc/j6ChlGqNMrhaP/0rmKOiJEwSacc9X1UmaC8YiHeRxULsvfgPyNyjvqAXzEpeIxcV0D6kEWR8an98QSY8TETrILbYLxMpyaIdQ7abfwUYmKcaUCpRKVQQQAvOjWjb9jPQdxo/7Tbevic3zaBzOb/UYJDAI0FAHyFoeBXKPWhFLFvSDdZe3kGoVz1TDZXKK9QqvOnFxCU/NMe5yj5JnvmxZucuWc7Ewj1MmURHafxFCUE0Jmj9q9jJqZ/YajhmvGfn31wJROfq6XSGHd7JR4rhs2gsyHKjxB2f/31l5+7XwonegQjDu8nz57u/mZxcoqRuAaZRqhyQCnOFkw9BUQizJGbig0pTJdApUgSU/8Y9EkkztgniJDPF9pKtEWs+ZmmScRCky+s+F5ZhxnEaaRZEpnlMLSupOxSKsGQzRhSuGZ6AZTNZihNEScJn6OCawS1EGlEQfBoCYyHUUqztDOVglBUjnSdRUjmo2UFzhZMAVMqRVgQBVNEnpNlyLr722L3CUyNkVWrYGwnUoTG3elBbiTj+K9Qv3Rrn5ql1aGQByRcuOBoCpdC5HvJNPqvhdLgHbJIowl6m9pcSts4jisSpSZbCLBZhEiRcpqfaLYjp6SRk1kejiWbM1NtV4XtNWm2k/MjrZM37N9bW6chiAaUMiOARAM5NzHUe2c20Cvs98b4WNBoWSt4Zf9ipBC+PMxK9S27hVo3FL2bb6JcYGVxAEEN7Vvr8KwBMPgM/wbPYtxEk/ByGImUet3SVgeU+kdoocEf8STVxxksNBjbWJclJAbHcLZMEMZC44k08KGX4Bs8Aw/XKcAD3x4IeDFR3hZDrFHzTFjMBJ9iohfw7OkdOqO8Dvle2GQ2MCVn3HHJ7PziAtpZBLgEUjQXBGKSgJi54F+HyeikiGxXxRvCAF58cRudCWmLzU4m2cBg29pElc/B4mtG4MdEhwt43Dl/6v/2Z+/iSffPvv188aT7uMyT8/1gluy5/kJ12pYf1fnuRXeTPFfQBE6ZcAdedLrdwitK55Q/Zo3zEscFPAngnHF9UXz5bF1nrco+/wgOc6xcWwsURib/GJTPbGoB2bxGZG5gVoUiQeiomESRebfWyXRsS9Sp5JkZjbE73Q1bs8Qa2hjmD1xWTN3OlgvcllhyAV9hIqT2ne9/hYnVLX/3D5lUGnZLQF1Z/0kAZr0n4PU9eOLkVw3g8liVz244FHEsuPEk5MpU8YrxeVR42Bw1ZEymR1JAOBApyRKEXqC8Zso41BWJGIW/TGESkyVMSxnVqpEJgJ3K8puxktWgGdDcoWB/Z3QgGsdCj9MoOpYHcaKXnXUNDu0zcYn8hkXuDcRlCo/ow0k8RSVSGeIR4WSO8oDTRDB+07r8Biq7/m1wMnqHUjFXHebFaioZBOC1O9sU6bY7xa67v5OE+aZyZIIHdckZ2LYXSChKGyNfBqleCMk+24wSeC+RSJTuWDznqm2JKhFcmSHEKWq59Icijk3hVXzwRvxKXKJ/ikofoV4I6oFvkiK8+PJWssDs4zlkvwTeK9Tec3CjMpMyAo8kWeXGBO8bp/Wew+tMzSDXdwW+WZ2hgn9nH5f7GJHlBEPBqYLdp3ne6/xQqFwOcr2Q4hoeH9jxzBy1NnlDWY9xwfi4HKGFjF6SJTMzQHK47kKiGi5ZwVrKrFkLcade9waOc4ac8Af19Uk6VaFktnR5SLmDwf4wYviwyhZCJxjKGzfKN9A2D7RXUqTJw4mtRePDibYuV2qyzZzvFnOMG9hkRJFrppcT2wo+nOameS8mrDnmuXHVKB/OKbMfi4KO/E9H4RfzO9WzoGH1apPKoKnG/A2mkg032/ltNm5q71vrLuuNmIPnP9DjbQourK9qP5X57vPUBOews5f7wPopIKnGVEWWCusG6tRYS+CxuWSv19tOnsHC3nfIK8FelV8FghpnLZ7X3Nsyb2ldN5vLkrnxp7yw2IN2p+aMPbWNvFvTaxBqdmUc3V56LNdyoVEyaSavy91SCG2TK5vJ63LXrna/pyb4HZGMTCP8P8ZGHXn2muCoplAj4u1tQcL/4XY2JbuaxtRJ2lSEpnF1Fwu5O4DzB8fctnRvT98YvPbyemwjf7gZBKEzVRk8VJhv46xGUl+QVC+e9a0MN1aoDXHuukCBbE2rZDs3UxNTy4I/UArjabS0k49sx733OLVUbXuVizRDJzOBXhP0XmudvNUsYnp5sbf3VkYHlrizWevkiXIq6NKYai4J1x+0KaRDi30fQok2Y5NI/ei+YjRYyxnR/GtlFQmqev2YR29wFzBqE1P4B3npn9t8Xf+fCGUagJeCLgO7iWoz8LjcDHzyr6+vfXM34Kcyclo+XpWc7dSV6XfpTjJVb9FXVFb8RnNx47ipthwZdQDVderTh+wathiykdCMSD9YploQH6IOFyaEHZqUbj2zgV4q19cuXFDMQ7mgU2+LLrSWmbbhvCplddXfSPL9nMumVtWvptp+IsUVMy1q/4iFUigx0z2XUvs1SHywntdaz1s1uXDFEvftY5/DcWrLx6AZ3zMNbt1o39qVO2eotG/6hC2Jpru1eZ7d1qUqXt6cYLK0Ym4hCmvbu/XcGyMRkmjMQnf74Y9RDyhJNMrvhscRCd1dA3wFd1GSD/Kq/5rjezteMfZQlJfWcwi1xVbwseiJ6g1B5cdvR7IdaFbo3y9QYqFtyQ69YTHd/VAeUcTFbruw+qaoChtLhmU0MOJTc10w1/A0t8g6DmwgFaJHle87a9PVzV1mM0/pbALYtpFiIfOM1IlkMZHLGkOSfV+lPsmmY0b8i87G4uZx1yjB5rwTPtZIy49vJ5cONb5Hmw/ovmNyc5tSOpFsNNVj9Hvia6DX0IF8T8ZpM4439gzbML+2wujEHaC9rP/25m/gK/njhDad/xXRWKzauOPbuY95VquKoNV6nyv32cXHxo1VOVz8fXt79Uvl8iqf0zT1COAf5FdbRIWMtYr/txlpjNfXa9v5bfk5UqdI6LG5t3a3bm0tU2ytWq3/DgBQSwcIQlaY8bELAAAFKgAAUEsDBBQACAAIAAAAAAAAAAAAAAAAAAAAAAAlAAAAd2luZG93cy93aW5kb3dzaW5zdGFsbG9wZW5zc2hmdW5jLnBzMYxUYW/bNhD97l9xVQ3EDiAt+2qgGDxvXYy1dhA5KIo4SGnybHGhSJV3iqe1yW8fKEuygjRF9MGSrLt3j+/u3ra0krWzg7klFsbEywJtmp7DtwEAwIXwIh/Vj+G6rt+R0Y8+CqsEO1/BOxiyL3F8c03std1d39xAlzFM0/O/saL6j/Ggvg2FyrUtBGfwDiI5WV94t/Mi/0OwWBNlUS9qqw2GqPpFE/tQk25FyZnz+j9Ut3dYUdQAE2UqRX+vZUj6Czlu377Db7AQOUJs9B3CSYg8qZP0Fkb9xGTmSssQ41c4G9cR37rTfPKaMT53xBA1gmm7g0azqIsbapreC23ExrREPmmr3J5mohAbbTRXEC+t0fY5tQbt9GTQ4QWOb/qg4x6pcHHm3R6iJhU0gXUMouPgLHCmCXIhM23xyPThWGSq1E9YxjXFpkASZEX/+Pj4eJacJb8mZzXKQ/2LhvAnurUcDxBATYMUMkpGBTHQnS6KoKs7lNYHpZMkiZoy9S1l4Y8NDh0cdA19M1ohcXwRZiw6zls0Hr/QzZlHEapb3EM9c8IqYPyXQTrLaBmEUqiOwi1wH88Zc4gDcH+mYxuU6o1vzFWBEIXnCOJ7YUqE6MlR+kx6SFtXWpVEz0KmSgV5wuQDu0mv+LpXttOr3UH4DlvnUcgsdpt/UHJPi9D8WXPSH8MNb19inCJz4OPxa6k9KijQ55pIO0sdCS2FNPQC9i8ec3ePEC1WML1anS8v56vP62nJGVrWsu7MFaGn12Bpm6HXLKzEiX9F/M4Ly5B+Tld/fpx8Gb3/Mn510u9X8w+r+WI9feJODcizzl4ihZENUhFlqh39TqLm+9ORDiBvYXmxmi8X0w+wKRk8SpfnaBWqSf097RndYU9DKsT1hpTFKozfybRklwvWsvGVtzBzdqt9DpwhvNce98IY8KXB4B8yfNyVHlUCcwbKXGkUbBBksymixRPGVLCpgJDLIjlY/3Db4h3cb4HcVrgMBQ4sT4my097Sdln9LX1ubW1Ua3NHqlB4V6A3VbtdL5lPYymo6j3vAVApJRJtS2OqaPAw+H8AUEsHCJJfjYYgAwAAIAcAAFBLAQIUABQACAAIAAAAAADHXM9fMAYAAPYSAAAmAAAAAAAAAAAAAAAAAAAAAAB3aW5kb3dzL2t1YmVybmV0ZXN3aW5kb3dzZnVuY3Rpb25zLnBzMVBLAQIUABQACAAIAAAAAACi3tfeMAcAAG8UAAAdAAAAAAAAAAAAAAAAAIQGAAB3aW5kb3dzL3dpbmRvd3Njb25maWdmdW5jLnBzMVBLAQIUABQACAAIAAAAAAC1c6ovpRkAAB1eAAAeAAAAAAAAAAAAAAAAAP8NAAB3aW5kb3dzL3dpbmRvd3NrdWJlbGV0ZnVuYy5wczFQSwECFAAUAAgACAAAAAAAkRjh2HYBAAAXAwAAGgAAAAAAAAAAAAAAAADwJwAAd2luZG93cy93aW5kb3dzY25pZnVuYy5wczFQSwECFAAUAAgACAAAAAAAQlaY8bELAAAFKgAAHwAAAAAAAAAAAAAAAACuKQAAd2luZG93cy93aW5kb3dzYXp1cmVjbmlmdW5jLnBzMVBLAQIUABQACAAIAAAAAACSX42GIAMAACAHAAAlAAAAAAAAAAAAAAAAAKw1AAB3aW5kb3dzL3dpbmRvd3NpbnN0YWxsb3BlbnNzaGZ1bmMucHMxUEsFBgAAAAAGAAYA0wEAAB85AAAAAA=="
+
+# Extract ZIP from script
+[io.file]::WriteAllBytes("scripts.zip", [System.Convert]::FromBase64String($zippedFiles))
+Expand-Archive scripts.zip -DestinationPath "C:\\AzureData\\"
+
+# Dot-source contents of zip. This should match the list in template_generator.go GetKubernetesWindowsAgentFunctions
+. c:\AzureData\k8s\kuberneteswindowsfunctions.ps1
+. c:\AzureData\k8s\windowsconfigfunc.ps1
+. c:\AzureData\k8s\windowskubeletfunc.ps1
+. c:\AzureData\k8s\windowscnifunc.ps1
+. c:\AzureData\k8s\windowsazurecnifunc.ps1
+. c:\AzureData\k8s\windowsinstallopensshfunc.ps1
+
+function
+Update-ServiceFailureActions()
+{
+    sc.exe failure "kubelet" actions= restart/60000/restart/60000/restart/60000 reset= 900
+    sc.exe failure "kubeproxy" actions= restart/60000/restart/60000/restart/60000 reset= 900
+    sc.exe failure "docker" actions= restart/60000/restart/60000/restart/60000 reset= 900
+}
+
+try
+{
+    # Set to false for debugging.  This will output the start script to
+    # c:\AzureData\CustomDataSetupScript.log, and then you can RDP
+    # to the windows machine, and run the script manually to watch
+    # the output.
+    if ($true) {
+        Write-Log "Provisioning $global:DockerServiceName... with IP $MasterIP"
+
+        # Install OpenSSH if SSH enabled
+        $sshEnabled = [System.Convert]::ToBoolean("false")
+
+        if ( $sshEnabled ) {
+            Install-OpenSSH -SSHKeys $SSHKeys
+        }
+
+        Write-Log "Apply telemetry data setting"
+        Set-TelemetrySetting -WindowsTelemetryGUID $global:WindowsTelemetryGUID
+
+        Write-Log "Resize os drive if possible"
+        Resize-OSDrive
+
+        Write-Log "Initialize data disks"
+        Initialize-DataDisks
+
+        Write-Log "Create required data directories as needed"
+        Initialize-DataDirectories
+
+        Write-Log "Install docker"
+        Install-Docker -DockerVersion $global:DockerVersion
+
+        Write-Log "Download kubelet binaries and unzip"
+        Get-KubePackage -KubeBinariesSASURL $global:KubeBinariesPackageSASURL
+
+        # this overwrite the binaries that are download from the custom packge with binaries
+        # The custom package has a few files that are nessary for future steps (nssm.exe)
+        # this is a temporary work around to get the binaries until we depreciate
+        # custom package and nssm.exe as defined in #3851.
+        if ($global:WindowsKubeBinariesURL){
+            Write-Log "Overwriting kube node binaries from $global:WindowsKubeBinariesURL"
+            Get-KubeBinaries -KubeBinariesURL $global:WindowsKubeBinariesURL
+        }
+
+        Write-Log "Write Azure cloud provider config"
+        Write-AzureConfig `
+            -KubeDir $global:KubeDir `
+            -AADClientId $AADClientId `
+            -AADClientSecret $([System.Text.Encoding]::ASCII.GetString([System.Convert]::FromBase64String($AADClientSecret))) `
+            -TenantId $global:TenantId `
+            -SubscriptionId $global:SubscriptionId `
+            -ResourceGroup $global:ResourceGroup `
+            -Location $Location `
+            -VmType $global:VmType `
+            -SubnetName $global:SubnetName `
+            -SecurityGroupName $global:SecurityGroupName `
+            -VNetName $global:VNetName `
+            -RouteTableName $global:RouteTableName `
+            -PrimaryAvailabilitySetName $global:PrimaryAvailabilitySetName `
+            -PrimaryScaleSetName $global:PrimaryScaleSetName `
+            -UseManagedIdentityExtension $global:UseManagedIdentityExtension `
+            -UserAssignedClientID $global:UserAssignedClientID `
+            -UseInstanceMetadata $global:UseInstanceMetadata `
+            -LoadBalancerSku $global:LoadBalancerSku `
+            -ExcludeMasterFromStandardLB $global:ExcludeMasterFromStandardLB `
+            -TargetEnvironment $TargetEnvironment
+
+        
+
+        Write-Log "Write ca root"
+        Write-CACert -CACertificate $global:CACertificate `
+                     -KubeDir $global:KubeDir
+
+        Write-Log "Write kube config"
+        Write-KubeConfig -CACertificate $global:CACertificate `
+                         -KubeDir $global:KubeDir `
+                         -MasterFQDNPrefix $MasterFQDNPrefix `
+                         -MasterIP $MasterIP `
+                         -AgentKey $AgentKey `
+                         -AgentCertificate $global:AgentCertificate
+
+        Write-Log "Create the Pause Container kubletwin/pause"
+        New-InfraContainer -KubeDir $global:KubeDir
+
+        if (-not (Test-ContainerImageExists -Image "kubletwin/pause")) {
+            Write-Log "Could not find container with name kubletwin/pause"
+            $o = docker image list
+            Write-Log $o
+            throw "kubletwin/pause container does not exist!"
+        }
+
+        Write-Log "Configuring networking with NetworkPlugin:$global:NetworkPlugin"
+
+        # Configure network policy.
+        if ($global:NetworkPlugin -eq "azure") {
+            Write-Log "Installing Azure VNet plugins"
+            Install-VnetPlugins -AzureCNIConfDir $global:AzureCNIConfDir `
+                                -AzureCNIBinDir $global:AzureCNIBinDir `
+                                -VNetCNIPluginsURL $global:VNetCNIPluginsURL
+            Set-AzureCNIConfig -AzureCNIConfDir $global:AzureCNIConfDir `
+                               -KubeDnsSearchPath $global:KubeDnsSearchPath `
+                               -KubeClusterCIDR $global:KubeClusterCIDR `
+                               -MasterSubnet $global:MasterSubnet `
+                               -KubeServiceCIDR $global:KubeServiceCIDR `
+                               -VNetCIDR $global:VNetCIDR `
+                               -TargetEnvironment $TargetEnvironment
+
+            if ($TargetEnvironment -ieq "AzureStackCloud") {
+                GenerateAzureStackCNIConfig `
+                    -TenantId $global:TenantId `
+                    -SubscriptionId $global:SubscriptionId `
+                    -ResourceGroup $global:ResourceGroup `
+                    -AADClientId $AADClientId `
+                    -KubeDir $global:KubeDir `
+                    -AADClientSecret $([System.Text.Encoding]::ASCII.GetString([System.Convert]::FromBase64String($AADClientSecret))) `
+                    -NetworkAPIVersion $NetworkAPIVersion `
+                    -AzureEnvironmentFilePath $([io.path]::Combine($global:KubeDir, "azurestackcloud.json")) `
+                    -IdentitySystem "azure_ad"
+            }
+
+        } elseif ($global:NetworkPlugin -eq "kubenet") {
+            Write-Log "Fetching additional files needed for kubenet"
+            Update-WinCNI -CNIPath $global:CNIPath
+            Get-HnsPsm1 -HNSModule $global:HNSModule
+        }
+
+        Write-Log "Write kubelet startfile with pod CIDR of $podCIDR"
+        Install-KubernetesServices `
+            -KubeletConfigArgs $global:KubeletConfigArgs `
+            -KubeBinariesVersion $global:KubeBinariesVersion `
+            -NetworkPlugin $global:NetworkPlugin `
+            -NetworkMode $global:NetworkMode `
+            -KubeDir $global:KubeDir `
+            -AzureCNIBinDir $global:AzureCNIBinDir `
+            -AzureCNIConfDir $global:AzureCNIConfDir `
+            -CNIPath $global:CNIPath `
+            -CNIConfig $global:CNIConfig `
+            -CNIConfigPath $global:CNIConfigPath `
+            -MasterIP $MasterIP `
+            -KubeDnsServiceIp $KubeDnsServiceIp `
+            -MasterSubnet $global:MasterSubnet `
+            -KubeClusterCIDR $global:KubeClusterCIDR `
+            -KubeServiceCIDR $global:KubeServiceCIDR `
+            -HNSModule $global:HNSModule `
+            -KubeletNodeLabels $global:KubeletNodeLabels
+
+        Get-NetworkLogCollectionScripts
+
+        Write-Log "Disable Internet Explorer compat mode and set homepage"
+        Set-Explorer
+
+        Write-Log "Adjust pagefile size"
+        Adjust-PageFileSize
+
+        Write-Log "Start preProvisioning script"
+        
+
+        Write-Log "Update service failure actions"
+        Update-ServiceFailureActions
+
+        if (Test-Path $CacheDir)
+        {
+            Write-Log "Removing aks-engine bits cache directory"
+            Remove-Item $CacheDir -Recurse -Force
+        }
+
+        Write-Log "Setup Complete, reboot computer"
+        Restart-Computer
+    }
+    else
+    {
+        # keep for debugging purposes
+        Write-Log ".\CustomDataSetupScript.ps1 -MasterIP $MasterIP -KubeDnsServiceIp $KubeDnsServiceIp -MasterFQDNPrefix $MasterFQDNPrefix -Location $Location -AgentKey $AgentKey -AADClientId $AADClientId -AADClientSecret $AADClientSecret"
+    }
+}
+catch
+{
+    Write-Error $_
+    exit 1
+}
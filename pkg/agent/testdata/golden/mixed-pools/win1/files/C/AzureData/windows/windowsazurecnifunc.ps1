@@ -0,0 +1,257 @@
+
+
+# TODO: remove - dead code?
+function
+Set-VnetPluginMode()
+{
+    Param(
+        [Parameter(Mandatory=$true)][string]
+        $AzureCNIConfDir,
+        [Parameter(Mandatory=$true)][string]
+        $Mode
+    )
+    # Sets Azure VNET CNI plugin operational mode.
+    $fileName  = [Io.path]::Combine("$AzureCNIConfDir", "10-azure.conflist")
+    (Get-Content $fileName) | %{$_ -replace "`"mode`":.*", "`"mode`": `"$Mode`","} | Out-File -encoding ASCII -filepath $fileName
+}
+
+
+function
+Install-VnetPlugins
+{
+    Param(
+        [Parameter(Mandatory=$true)][string]
+        $AzureCNIConfDir,
+        [Parameter(Mandatory=$true)][string]
+        $AzureCNIBinDir,
+        [Parameter(Mandatory=$true)][string]
+        $VNetCNIPluginsURL
+    )
+    # Create CNI directories.
+    mkdir $AzureCNIBinDir
+    mkdir $AzureCNIConfDir
+
+    # Download Azure VNET CNI plugins.
+    # Mirror from https://github.com/Azure/azure-container-networking/releases
+    $zipfile =  [Io.path]::Combine("$AzureCNIDir", "azure-vnet.zip")
+    DownloadFileOverHttp -Url $VNetCNIPluginsURL -DestinationPath $zipfile
+    Expand-Archive -path $zipfile -DestinationPath $AzureCNIBinDir
+    del $zipfile
+
+    # Windows does not need a separate CNI loopback plugin because the Windows
+    # kernel automatically creates a loopback interface for each network namespace.
+    # Copy CNI network config file and set bridge mode.
+    move $AzureCNIBinDir/*.conflist $AzureCNIConfDir
+}
+
+# TODO: remove - dead code?
+function
+Set-AzureNetworkPlugin()
+{
+    # Azure VNET network policy requires tunnel (hairpin) mode because policy is enforced in the host.
+    Set-VnetPluginMode "tunnel"
+}
+
+function
+Set-AzureCNIConfig
+{
+    Param(
+        [Parameter(Mandatory=$true)][string]
+        $AzureCNIConfDir,
+        [Parameter(Mandatory=$true)][string]
+        $KubeDnsSearchPath,
+        [Parameter(Mandatory=$true)][string]
+        $KubeClusterCIDR,
+        [Parameter(Mandatory=$true)][string]
+        $MasterSubnet,
+        [Parameter(Mandatory=$true)][string]
+        $KubeServiceCIDR,
+        [Parameter(Mandatory=$true)][string]
+        $VNetCIDR,
+        [Parameter(Mandatory=$true)][string]
+        $TargetEnvironment
+    )
+    # Fill in DNS information for kubernetes.
+    $exceptionAddresses = @($KubeClusterCIDR, $MasterSubnet, $VNetCIDR)
+
+    $fileName  = [Io.path]::Combine("$AzureCNIConfDir", "10-azure.conflist")
+    $configJson = Get-Content $fileName | ConvertFrom-Json
+    $configJson.plugins.dns.Nameservers[0] = $KubeDnsServiceIp
+    $configJson.plugins.dns.Search[0] = $KubeDnsSearchPath
+
+    $osBuildNumber = (get-wmiobject win32_operatingsystem).BuildNumber
+    if ($osBuildNumber -le 17763){
+        # In WS2019 and below rules in the exception list are generated by dropping the prefix lenght and removing duplicate rules.
+        # If multiple execptions are specified with different ranges we should only include the broadest range for each address.
+        # This issue has been addressed in 19h1+ builds
+
+        $processedExceptions = GetBroadestRangesForEachAddress $exceptionAddresses
+        Write-Host "Filtering CNI config exception list values to work around WS2019 issue processing rules. Original exception list: $exceptionAddresses, processed exception list: $processedExceptions"
+        $configJson.plugins.AdditionalArgs[0].Value.ExceptionList = $processedExceptions
+    }
+    else {
+        $configJson.plugins.AdditionalArgs[0].Value.ExceptionList = $exceptionAddresses
+    }
+
+    $configJson.plugins.AdditionalArgs[1].Value.DestinationPrefix  = $KubeServiceCIDR
+
+    if ($TargetEnvironment -ieq "AzureStackCloud") {
+        Add-Member -InputObject $configJson.plugins[0].ipam -MemberType NoteProperty -Name "environment" -Value "mas"
+    }
+
+    $configJson | ConvertTo-Json -depth 20 | Out-File -encoding ASCII -filepath $fileName
+}
+
+function GetBroadestRangesForEachAddress{
+    param([string[]] $values)
+
+    # Create a map of range values to IP addresses
+    $map = @{}
+
+    foreach ($value in $Values) {
+        if ($value -match '([0-9\.]+)\/([0-9]+)') {
+            if (!$map.contains($matches[1])) {
+                $map.Add($matches[1], @())
+            }
+
+            $map[$matches[1]] += [int]$matches[2]
+        }
+    }
+
+    # For each IP address select the range with the lagest scope (smallest value)
+    $returnValues = @()
+    foreach ($ip in $map.Keys) {
+        $range = $map[$ip] | Sort-Object | Select-Object -First 1
+
+        $returnValues += $ip + "/" + $range
+    }
+
+    # prefix $returnValues with common to ensure single values get returned as an array otherwise invalid json may be generated
+    return ,$returnValues
+}
+
+function GetSubnetPrefix
+{
+    Param(
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $Token,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $SubnetId,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $ResourceManagerEndpoint,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $NetworkAPIVersion
+    )
+
+    $uri = "$($ResourceManagerEndpoint)$($SubnetId)?api-version=$NetworkAPIVersion"
+    $headers = @{Authorization="Bearer $Token"}
+
+    $response = Retry-Command -Command "Invoke-RestMethod" -Args @{Uri=$uri; Method="Get"; ContentType="application/json"; Headers=$headers} -Retries 5 -RetryDelaySeconds 10
+
+    if(!$response) {
+        throw 'Error getting subnet prefix'
+    }
+
+    $response.properties.addressPrefix
+}
+
+function GenerateAzureStackCNIConfig
+{
+    Param(
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $TenantId,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $SubscriptionId,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $AADClientId,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $AADClientSecret,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $ResourceGroup,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $NetworkAPIVersion,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $AzureEnvironmentFilePath,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $IdentitySystem,
+        [Parameter(Mandatory=$true)][ValidateNotNullOrEmpty()][string] $KubeDir
+
+    )
+
+    $networkInterfacesFile = "$KubeDir\network-interfaces.json"
+    $azureCNIConfigFile = "$KubeDir\interfaces.json"
+    $azureEnvironment = Get-Content $AzureEnvironmentFilePath | ConvertFrom-Json
+
+    Write-Log "------------------------------------------------------------------------"
+    Write-Log "Parameters"
+    Write-Log "------------------------------------------------------------------------"
+    Write-Log "TenantId:                  $TenantId"
+    Write-Log "SubscriptionId:            $SubscriptionId"
+    Write-Log "AADClientId:               ..."
+    Write-Log "AADClientSecret:           ..."
+    Write-Log "ResourceGroup:             $ResourceGroup"
+    Write-Log "NetworkAPIVersion:         $NetworkAPIVersion"
+    Write-Log "ServiceManagementEndpoint: $($azureEnvironment.serviceManagementEndpoint)"
+    Write-Log "ActiveDirectoryEndpoint:   $($azureEnvironment.activeDirectoryEndpoint)"
+    Write-Log "ResourceManagerEndpoint:   $($azureEnvironment.resourceManagerEndpoint)"
+    Write-Log "------------------------------------------------------------------------"
+    Write-Log "Variables"
+    Write-Log "------------------------------------------------------------------------"
+    Write-Log "azureCNIConfigFile: $azureCNIConfigFile"
+    Write-Log "networkInterfacesFile: $networkInterfacesFile"
+    Write-Log "------------------------------------------------------------------------"
+
+    Write-Log "Generating token for Azure Resource Manager"
+
+    $tokenURL = ""
+    if($IdentitySystem -ieq "adfs") {
+        $tokenURL = "$($azureEnvironment.activeDirectoryEndpoint)adfs/oauth2/token"
+    } else {
+        $tokenURL = "$($azureEnvironment.activeDirectoryEndpoint)$TenantId/oauth2/token"
+    }
+
+    Add-Type -AssemblyName System.Web
+    $encodedSecret = [System.Web.HttpUtility]::UrlEncode($AADClientSecret)
+
+    $body = "grant_type=client_credentials&client_id=$AADClientId&client_secret=$encodedSecret&resource=$($azureEnvironment.serviceManagementEndpoint)"
+    $args = @{Uri=$tokenURL; Method="Post"; Body=$body; ContentType='application/x-www-form-urlencoded'}
+    $tokenResponse = Retry-Command -Command "Invoke-RestMethod" -Args $args -Retries 5 -RetryDelaySeconds 10
+
+    if(!$tokenResponse) {
+        throw 'Error generating token for Azure Resource Manager'
+    }
+
+    $token = $tokenResponse | Select-Object -ExpandProperty access_token
+
+    Write-Log "Fetching network interface configuration for node"
+
+    $interfacesUri = "$($azureEnvironment.resourceManagerEndpoint)subscriptions/$SubscriptionId/resourceGroups/$ResourceGroup/providers/Microsoft.Network/networkInterfaces?api-version=$NetworkAPIVersion"
+    $headers = @{Authorization="Bearer $token"}
+    $args = @{Uri=$interfacesUri; Method="Get"; ContentType="application/json"; Headers=$headers; OutFile=$networkInterfacesFile}
+    Retry-Command -Command "Invoke-RestMethod" -Args $args -Retries 5 -RetryDelaySeconds 10
+
+    if(!$(Test-Path $networkInterfacesFile)) {
+        throw 'Error fetching network interface configuration for node'
+    }
+
+    Write-Log "Generating Azure CNI interface file"
+
+    $localNics = Get-NetAdapter | Select-Object -ExpandProperty MacAddress | ForEach-Object {$_ -replace "-",""}
+
+    $sdnNics = Get-Content $networkInterfacesFile `
+        | ConvertFrom-Json `
+        | Select-Object -ExpandProperty value `
+        | Where-Object { $localNics.Contains($_.properties.macAddress) } `
+        | Where-Object { $_.properties.ipConfigurations.Count -gt 0}
+
+    $interfaces = @{
+        Interfaces = @( $sdnNics | ForEach-Object { @{
+            MacAddress = $_.properties.macAddress
+            IsPrimary = $_.properties.primary
+            IPSubnets = @(@{
+                Prefix = GetSubnetPrefix `
+                            -Token $token `
+                            -SubnetId $_.properties.ipConfigurations[0].properties.subnet.id `
+                            -NetworkAPIVersion $NetworkAPIVersion `
+                            -ResourceManagerEndpoint $($azureEnvironment.resourceManagerEndpoint)
+                IPAddresses = $_.properties.ipConfigurations | ForEach-Object { @{
+                    Address = $_.properties.privateIPAddress
+                    IsPrimary = $_.properties.primary
+                }}
+            })
+        }})
+    }
+
+    ConvertTo-Json $interfaces -Depth 6 | Out-File -FilePath $azureCNIConfigFile -Encoding ascii
+
+    Set-ItemProperty -Path $azureCNIConfigFile -Name IsReadOnly -Value $true
+}
+
@@ -0,0 +1,59 @@
+function
+Install-OpenSSH {
+    Param(
+        [Parameter(Mandatory = $true)][string[]] 
+        $SSHKeys
+    )
+
+    $adminpath = "c:\ProgramData\ssh"
+    $adminfile = "administrators_authorized_keys"
+
+    $sshdService = Get-Service | ? Name -like 'sshd'
+    if ($sshdService.Count -eq 0)
+    {
+        Write-Host "Installing OpenSSH"
+        $isAvailable = Get-WindowsCapability -Online | ? Name -like 'OpenSSH*'
+
+        if (!$isAvailable) {
+            throw "OpenSSH is not available on this machine"
+        }
+
+        Add-WindowsCapability -Online -Name OpenSSH.Server~~~~0.0.1.0
+    }
+    else
+    {
+        Write-Host "OpenSSH Server service detected - skipping online install..."
+    }
+
+    Start-Service sshd
+
+    if (!(Test-Path "$adminpath")) {
+        Write-Host "Created new file and text content added"
+        New-Item -path $adminpath -name $adminfile -type "file" -value ""
+    }
+
+    Write-Host "$adminpath found."
+    Write-Host "Adding keys to: $adminpath\$adminfile ..."
+    $SSHKeys | foreach-object {
+        Add-Content $adminpath\$adminfile $_
+    }
+
+    Write-Host "Setting required permissions..."
+    icacls $adminpath\$adminfile /remove "NT AUTHORITY\Authenticated Users"
+    icacls $adminpath\$adminfile /inheritance:r
+    icacls $adminpath\$adminfile /grant SYSTEM:`(F`)
+    icacls $adminpath\$adminfile /grant BUILTIN\Administrators:`(F`)
+
+    Write-Host "Restarting sshd service..."
+    Restart-Service sshd
+    # OPTIONAL but recommended:
+    Set-Service -Name sshd -StartupType 'Automatic'
+
+    # Confirm the Firewall rule is configured. It should be created automatically by setup. 
+    $firewall = Get-NetFirewallRule -Name *ssh*
+
+    if (!$firewall) {
+        throw "OpenSSH is firewall is not configured properly"
+    }
+    Write-Host "OpenSSH installed and configured successfully"
+}
@@ -4,21 +4,16 @@
 package agent
 
 import (
+	"archive/zip"
 	"bytes"
-	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/Azure/agentbaker/pkg/templates"
 	"github.com/blang/semver"
-	"io/ioutil"
-	"log"
-	"net"
-	"net/http"
+	"path"
 	"regexp"
-	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/pkg/errors"
@@ -32,46 +27,6 @@ func init() {
 
 type paramsMap map[string]interface{}
 
-// validateDistro checks if the requested orchestrator type is supported on the requested Linux distro.
-func validateDistro(cs *api.ContainerService) bool {
-	// Check Master distro
-	if cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.Distro == api.RHEL &&
-		(cs.Properties.OrchestratorProfile.OrchestratorType != api.SwarmMode) {
-		log.Printf("Orchestrator type %s not suported on RHEL Master", cs.Properties.OrchestratorProfile.OrchestratorType)
-		return false
-	}
-	// Check Agent distros
-	for _, agentProfile := range cs.Properties.AgentPoolProfiles {
-		if agentProfile.Distro == api.RHEL &&
-			(cs.Properties.OrchestratorProfile.OrchestratorType != api.SwarmMode) {
-			log.Printf("Orchestrator type %s not suported on RHEL Agent", cs.Properties.OrchestratorProfile.OrchestratorType)
-			return false
-		}
-	}
-	return true
-}
-
-// generateConsecutiveIPsList takes a starting IP address and returns a string slice of length "count" of subsequent, consecutive IP addresses
-func generateConsecutiveIPsList(count int, firstAddr string) ([]string, error) {
-	ipaddr := net.ParseIP(firstAddr).To4()
-	if ipaddr == nil {
-		return nil, errors.Errorf("IPAddr '%s' is an invalid IP address", firstAddr)
-	}
-	if int(ipaddr[3])+count >= 255 {
-		return nil, errors.Errorf("IPAddr '%s' + %d will overflow the fourth octet", firstAddr, count)
-	}
-	ret := make([]string, count)
-	for i := 0; i < count; i++ {
-		nextAddress := fmt.Sprintf("%d.%d.%d.%d", ipaddr[0], ipaddr[1], ipaddr[2], ipaddr[3]+byte(i))
-		ipaddr := net.ParseIP(nextAddress).To4()
-		if ipaddr == nil {
-			return nil, errors.Errorf("IPAddr '%s' is an invalid IP address", nextAddress)
-		}
-		ret[i] = nextAddress
-	}
-	return ret, nil
-}
-
 func addValue(m paramsMap, k string, v interface{}) {
 	m[k] = paramsMap{
 		"value": v,
@@ -90,6 +45,23 @@ func addKeyvaultReference(m paramsMap, k string, vaultID, secretName, secretVers
 	}
 }
 
+// addKeyvaultRef replaces the parameter k with a Key Vault reference if ref is not nil
+func addKeyvaultRef(m paramsMap, k string, ref *KeyVaultRef) {
+	if ref != nil {
+		addKeyvaultReference(m, k, ref.KeyVault.ID, ref.SecretName, ref.SecretVersion)
+	}
+}
+
+// getKeyvaultReference returns the Key Vault reference of the parameter k, nil if it has a value
+func getKeyvaultReference(m paramsMap, k string) *KeyVaultRef {
+	if v, ok := m[k].(paramsMap); ok {
+		if ref, ok := v["reference"].(*KeyVaultRef); ok {
+			return ref
+		}
+	}
+	return nil
+}
+
 func addSecret(m paramsMap, k string, v interface{}, encode bool) {
 	str, ok := v.(string)
 	if !ok {
@@ -108,240 +80,79 @@ func addSecret(m paramsMap, k string, v interface{}, encode bool) {
 	addKeyvaultReference(m, k, parts[1], parts[2], parts[4])
 }
 
-func makeAgentExtensionScriptCommands(cs *api.ContainerService, profile *api.AgentPoolProfile) string {
+// makeAgentExtensionScriptCommands returns the commands running the preprovision extension of the agent pool. The
+// node downloads the extension script at boot unless source is set, then the script is read from source and
+// embedded in the commands
+func makeAgentExtensionScriptCommands(config *NodeBootstrappingConfiguration, profile *api.AgentPoolProfile, source ExtensionSource) (string, error) {
 	if profile.OSType == api.Windows {
 		return makeWindowsExtensionScriptCommands(profile.PreprovisionExtension,
-			cs.Properties.ExtensionProfiles)
+			config.ExtensionProfiles, source)
 	}
 	curlCaCertOpt := ""
-	if cs.Properties.IsAzureStackCloud() {
+	if config.isAzureStackCloud() {
 		curlCaCertOpt = fmt.Sprintf("--cacert %s", AzureStackCaCertLocation)
 	}
 	return makeExtensionScriptCommands(profile.PreprovisionExtension,
-		curlCaCertOpt, cs.Properties.ExtensionProfiles)
+		curlCaCertOpt, config.ExtensionProfiles, source)
 }
 
-func makeExtensionScriptCommands(extension *api.Extension, curlCaCertOpt string, extensionProfiles []*api.ExtensionProfile) string {
-	var extensionProfile *api.ExtensionProfile
+// getExtensionProfile returns the extension profile of the extension
+func getExtensionProfile(extension *api.Extension, extensionProfiles []*api.ExtensionProfile) (*api.ExtensionProfile, error) {
 	for _, eP := range extensionProfiles {
 		if strings.EqualFold(eP.Name, extension.Name) {
-			extensionProfile = eP
-			break
+			return eP, nil
 		}
 	}
-
-	if extensionProfile == nil {
-		panic(fmt.Sprintf("%s extension referenced was not found in the extension profile", extension.Name))
-	}
-
-	extensionsParameterReference := fmt.Sprintf("parameters('%sParameters')", extensionProfile.Name)
-	scriptURL := getExtensionURL(extensionProfile.RootURL, extensionProfile.Name, extensionProfile.Version, extensionProfile.Script, extensionProfile.URLQuery)
-	scriptFilePath := fmt.Sprintf("/opt/azure/containers/extensions/%s/%s", extensionProfile.Name, extensionProfile.Script)
-	return fmt.Sprintf("- sudo /usr/bin/curl --retry 5 --retry-delay 10 --retry-max-time 30 -o %s --create-dirs %s \"%s\" \n- sudo /bin/chmod 744 %s \n- sudo %s ',%s,' > /var/log/%s-output.log",
-		scriptFilePath, curlCaCertOpt, scriptURL, scriptFilePath, scriptFilePath, extensionsParameterReference, extensionProfile.Name)
+	return nil, errors.Errorf("%s extension referenced was not found in the extension profile", extension.Name)
 }
 
-func makeWindowsExtensionScriptCommands(extension *api.Extension, extensionProfiles []*api.ExtensionProfile) string {
-	var extensionProfile *api.ExtensionProfile
-	for _, eP := range extensionProfiles {
-		if strings.EqualFold(eP.Name, extension.Name) {
-			extensionProfile = eP
-			break
-		}
-	}
-
-	if extensionProfile == nil {
-		panic(fmt.Sprintf("%s extension referenced was not found in the extension profile", extension.Name))
-	}
-
-	scriptURL := getExtensionURL(extensionProfile.RootURL, extensionProfile.Name, extensionProfile.Version, extensionProfile.Script, extensionProfile.URLQuery)
-	scriptFileDir := fmt.Sprintf("$env:SystemDrive:/AzureData/extensions/%s", extensionProfile.Name)
-	scriptFilePath := fmt.Sprintf("%s/%s", scriptFileDir, extensionProfile.Script)
-	return fmt.Sprintf("New-Item -ItemType Directory -Force -Path \"%s\" ; Invoke-WebRequest -Uri \"%s\" -OutFile \"%s\" ; powershell \"%s `\"',parameters('%sParameters'),'`\"\"\n", scriptFileDir, scriptURL, scriptFilePath, scriptFilePath, extensionProfile.Name)
-}
-
-func getVNETAddressPrefixes(properties *api.Properties) string {
-	visitedSubnets := make(map[string]bool)
-	var buf bytes.Buffer
-	buf.WriteString(`"[variables('masterSubnet')]"`)
-	visitedSubnets[properties.MasterProfile.Subnet] = true
-	for _, profile := range properties.AgentPoolProfiles {
-		if _, ok := visitedSubnets[profile.Subnet]; !ok {
-			buf.WriteString(fmt.Sprintf(",\n            \"[variables('%sSubnet')]\"", profile.Name))
-		}
-	}
-	return buf.String()
-}
-
-func getVNETSubnetDependencies(properties *api.Properties) string {
-	agentString := `        "[concat('Microsoft.Network/networkSecurityGroups/', variables('%sNSGName'))]"`
-	var buf bytes.Buffer
-	for index, agentProfile := range properties.AgentPoolProfiles {
-		if index > 0 {
-			buf.WriteString(",\n")
-		}
-		buf.WriteString(fmt.Sprintf(agentString, agentProfile.Name))
-	}
-	return buf.String()
-}
-
-func getVNETSubnets(properties *api.Properties, addNSG bool) string {
-	masterString := `{
-            "name": "[variables('masterSubnetName')]",
-            "properties": {
-              "addressPrefix": "[variables('masterSubnet')]"
-            }
-          }`
-	agentString := `          {
-            "name": "[variables('%sSubnetName')]",
-            "properties": {
-              "addressPrefix": "[variables('%sSubnet')]"
-            }
-          }`
-	agentStringNSG := `          {
-            "name": "[variables('%sSubnetName')]",
-            "properties": {
-              "addressPrefix": "[variables('%sSubnet')]",
-              "networkSecurityGroup": {
-                "id": "[resourceId('Microsoft.Network/networkSecurityGroups', variables('%sNSGName'))]"
-              }
-            }
-          }`
-	var buf bytes.Buffer
-	buf.WriteString(masterString)
-	for _, agentProfile := range properties.AgentPoolProfiles {
-		buf.WriteString(",\n")
-		if addNSG {
-			buf.WriteString(fmt.Sprintf(agentStringNSG, agentProfile.Name, agentProfile.Name, agentProfile.Name))
-		} else {
-			buf.WriteString(fmt.Sprintf(agentString, agentProfile.Name, agentProfile.Name))
-		}
-
+// getExtensionScript returns the base64 encoded script of the extension profile read from source
+func getExtensionScript(extensionProfile *api.ExtensionProfile, source ExtensionSource) (string, error) {
+	b, err := source.GetExtensionFile(extensionProfile, extensionProfile.Script)
+	if err != nil {
+		return "", err
 	}
-	return buf.String()
+	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-func getLBRule(name string, port int) string {
-	return fmt.Sprintf(`	          {
-            "name": "LBRule%d",
-            "properties": {
-              "backendAddressPool": {
-                "id": "[concat(variables('%sLbID'), '/backendAddressPools/', variables('%sLbBackendPoolName'))]"
-              },
-              "backendPort": %d,
-              "enableFloatingIP": false,
-              "frontendIPConfiguration": {
-                "id": "[variables('%sLbIPConfigID')]"
-              },
-              "frontendPort": %d,
-              "idleTimeoutInMinutes": 5,
-              "loadDistribution": "Default",
-              "probe": {
-                "id": "[concat(variables('%sLbID'),'/probes/tcp%dProbe')]"
-              },
-              "protocol": "Tcp"
-            }
-          }`, port, name, name, port, name, port, name, port)
-}
-
-func getLBRules(name string, ports []int) string {
-	var buf bytes.Buffer
-	for index, port := range ports {
-		if index > 0 {
-			buf.WriteString(",\n")
-		}
-		buf.WriteString(getLBRule(name, port))
+func makeExtensionScriptCommands(extension *api.Extension, curlCaCertOpt string, extensionProfiles []*api.ExtensionProfile, source ExtensionSource) (string, error) {
+	extensionProfile, err := getExtensionProfile(extension, extensionProfiles)
+	if err != nil {
+		return "", err
 	}
-	return buf.String()
-}
-
-func getProbe(port int) string {
-	return fmt.Sprintf(`          {
-            "name": "tcp%dProbe",
-            "properties": {
-              "intervalInSeconds": 5,
-              "numberOfProbes": 2,
-              "port": %d,
-              "protocol": "Tcp"
-            }
-          }`, port, port)
-}
 
-func getProbes(ports []int) string {
-	var buf bytes.Buffer
-	for index, port := range ports {
-		if index > 0 {
-			buf.WriteString(",\n")
+	extensionsParameterReference := fmt.Sprintf("parameters('%sParameters')", extensionProfile.Name)
+	scriptFilePath := fmt.Sprintf("/opt/azure/containers/extensions/%s/%s", extensionProfile.Name, extensionProfile.Script)
+	if source != nil {
+		script, err := getExtensionScript(extensionProfile, source)
+		if err != nil {
+			return "", err
 		}
-		buf.WriteString(getProbe(port))
+		return fmt.Sprintf("- sudo /bin/mkdir -p %s \n- echo %s | /usr/bin/base64 -d | sudo /usr/bin/tee %s > /dev/null \n- sudo /bin/chmod 744 %s \n- sudo %s ',%s,' > /var/log/%s-output.log",
+			path.Dir(scriptFilePath), script, scriptFilePath, scriptFilePath, scriptFilePath, extensionsParameterReference, extensionProfile.Name), nil
 	}
-	return buf.String()
-}
-
-func getSecurityRule(port int, portIndex int) string {
-	// BaseLBPriority specifies the base lb priority.
-	BaseLBPriority := 200
-	return fmt.Sprintf(`          {
-            "name": "Allow_%d",
-            "properties": {
-              "access": "Allow",
-              "description": "Allow traffic from the Internet to port %d",
-              "destinationAddressPrefix": "*",
-              "destinationPortRange": "%d",
-              "direction": "Inbound",
-              "priority": %d,
-              "protocol": "*",
-              "sourceAddressPrefix": "Internet",
-              "sourcePortRange": "*"
-            }
-          }`, port, port, port, BaseLBPriority+portIndex)
+	scriptURL := GetExtensionURL(extensionProfile.RootURL, extensionProfile.Name, extensionProfile.Version, extensionProfile.Script, extensionProfile.URLQuery)
+	return fmt.Sprintf("- sudo /usr/bin/curl --retry 5 --retry-delay 10 --retry-max-time 30 -o %s --create-dirs %s \"%s\" \n- sudo /bin/chmod 744 %s \n- sudo %s ',%s,' > /var/log/%s-output.log",
+		scriptFilePath, curlCaCertOpt, scriptURL, scriptFilePath, scriptFilePath, extensionsParameterReference, extensionProfile.Name), nil
 }
 
-func getDataDisks(a *api.AgentPoolProfile) string {
-	if !a.HasDisks() {
-		return ""
-	}
-	var buf bytes.Buffer
-	buf.WriteString("\"dataDisks\": [\n")
-	dataDisks := `            {
-              "createOption": "Empty",
-              "diskSizeGB": "%d",
-              "lun": %d,
-              "caching": "ReadOnly",
-              "name": "[concat(variables('%sVMNamePrefix'), copyIndex(),'-datadisk%d')]",
-              "vhd": {
-                "uri": "[concat('http://',variables('storageAccountPrefixes')[mod(add(add(div(copyIndex(),variables('maxVMsPerStorageAccount')),variables('%sStorageAccountOffset')),variables('dataStorageAccountPrefixSeed')),variables('storageAccountPrefixesCount'))],variables('storageAccountPrefixes')[div(add(add(div(copyIndex(),variables('maxVMsPerStorageAccount')),variables('%sStorageAccountOffset')),variables('dataStorageAccountPrefixSeed')),variables('storageAccountPrefixesCount'))],variables('%sDataAccountName'),'.blob.core.windows.net/vhds/',variables('%sVMNamePrefix'),copyIndex(), '--datadisk%d.vhd')]"
-              }
-            }`
-	managedDataDisks := `            {
-              "diskSizeGB": "%d",
-              "lun": %d,
-              "caching": "ReadOnly",
-              "createOption": "Empty"
-            }`
-	for i, diskSize := range a.DiskSizesGB {
-		if i > 0 {
-			buf.WriteString(",\n")
-		}
-		if a.StorageProfile == api.StorageAccount {
-			buf.WriteString(fmt.Sprintf(dataDisks, diskSize, i, a.Name, i, a.Name, a.Name, a.Name, a.Name, i))
-		} else if a.StorageProfile == api.ManagedDisks {
-			buf.WriteString(fmt.Sprintf(managedDataDisks, diskSize, i))
-		}
+func makeWindowsExtensionScriptCommands(extension *api.Extension, extensionProfiles []*api.ExtensionProfile, source ExtensionSource) (string, error) {
+	extensionProfile, err := getExtensionProfile(extension, extensionProfiles)
+	if err != nil {
+		return "", err
 	}
-	buf.WriteString("\n          ],")
-	return buf.String()
-}
 
-func getSecurityRules(ports []int) string {
-	var buf bytes.Buffer
-	for index, port := range ports {
-		if index > 0 {
-			buf.WriteString(",\n")
+	scriptFileDir := fmt.Sprintf("$env:SystemDrive:/AzureData/extensions/%s", extensionProfile.Name)
+	scriptFilePath := fmt.Sprintf("%s/%s", scriptFileDir, extensionProfile.Script)
+	if source != nil {
+		script, err := getExtensionScript(extensionProfile, source)
+		if err != nil {
+			return "", err
 		}
-		buf.WriteString(getSecurityRule(port, index))
+		return fmt.Sprintf("New-Item -ItemType Directory -Force -Path \"%s\" ; [IO.File]::WriteAllBytes(\"%s\", [Convert]::FromBase64String(\"%s\")) ; powershell \"%s `\"',parameters('%sParameters'),'`\"\"\n", scriptFileDir, scriptFilePath, script, scriptFilePath, extensionProfile.Name), nil
 	}
-	return buf.String()
+	scriptURL := GetExtensionURL(extensionProfile.RootURL, extensionProfile.Name, extensionProfile.Version, extensionProfile.Script, extensionProfile.URLQuery)
+	return fmt.Sprintf("New-Item -ItemType Directory -Force -Path \"%s\" ; Invoke-WebRequest -Uri \"%s\" -OutFile \"%s\" ; powershell \"%s `\"',parameters('%sParameters'),'`\"\"\n", scriptFileDir, scriptURL, scriptFilePath, scriptFilePath, extensionProfile.Name), nil
 }
 
 func escapeSingleLine(escapedStr string) string {
@@ -353,335 +164,50 @@ func escapeSingleLine(escapedStr string) string {
 	return escapedStr
 }
 
-// getBase64EncodedGzippedCustomScript will return a base64 of the CSE
-func getBase64EncodedGzippedCustomScript(csFilename string, cs *api.ContainerService) string {
-	b, err := templates.Asset(csFilename)
-	if err != nil {
-		// this should never happen and this is a bug
-		panic(fmt.Sprintf("BUG: %s", err.Error()))
-	}
-	// translate the parameters
-	templ := template.New("ContainerService template").Option("missingkey=error").Funcs(getContainerServiceFuncMap(cs))
-	_, err = templ.Parse(string(b))
-	if err != nil {
-		// this should never happen and this is a bug
-		panic(fmt.Sprintf("BUG: %s", err.Error()))
-	}
-	var buffer bytes.Buffer
-	templ.Execute(&buffer, cs)
-	csStr := buffer.String()
-	csStr = strings.Replace(csStr, "\r\n", "\n", -1)
-	return getBase64EncodedGzippedCustomScriptFromStr(csStr)
-}
-
-func getStringFromBase64(str string) (string, error) {
-	decodedBytes, err := base64.StdEncoding.DecodeString(str)
-	return string(decodedBytes), err
-}
-
-// getBase64EncodedGzippedCustomScriptFromStr will return a base64-encoded string of the gzip'd source data
-func getBase64EncodedGzippedCustomScriptFromStr(str string) string {
-	var gzipB bytes.Buffer
-	w := gzip.NewWriter(&gzipB)
-	w.Write([]byte(str))
-	w.Close()
-	return base64.StdEncoding.EncodeToString(gzipB.Bytes())
-}
-
-func getAddonFuncMap(addon api.KubernetesAddon) template.FuncMap {
-	return template.FuncMap{
-		"ContainerImage": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].Image
-		},
-
-		"ContainerCPUReqs": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].CPURequests
-		},
-
-		"ContainerCPULimits": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].CPULimits
-		},
-
-		"ContainerMemReqs": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].MemoryRequests
-		},
-
-		"ContainerMemLimits": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].MemoryLimits
-		},
-		"ContainerConfig": func(name string) string {
-			return addon.Config[name]
-		},
-	}
-}
-
-func getClusterAutoscalerAddonFuncMap(addon api.KubernetesAddon, cs *api.ContainerService) template.FuncMap {
-	return template.FuncMap{
-		"ContainerImage": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].Image
-		},
-
-		"ContainerCPUReqs": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].CPURequests
-		},
-
-		"ContainerCPULimits": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].CPULimits
-		},
-
-		"ContainerMemReqs": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].MemoryRequests
-		},
-
-		"ContainerMemLimits": func(name string) string {
-			i := addon.GetAddonContainersIndexByName(name)
-			return addon.Containers[i].MemoryLimits
-		},
-		"ContainerConfig": func(name string) string {
-			return addon.Config[name]
-		},
-		"GetMode": func() string {
-			return addon.Mode
-		},
-		"GetClusterAutoscalerNodesConfig": func() string {
-			return api.GetClusterAutoscalerNodesConfig(addon, cs)
-		},
-		"GetVolumeMounts": func() string {
-			if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
-				return fmt.Sprintf("\n        - mountPath: /var/lib/waagent/\n          name: waagent\n          readOnly: true")
-			}
-			return ""
-		},
-		"GetVolumes": func() string {
-			if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
-				return fmt.Sprintf("\n      - hostPath:\n          path: /var/lib/waagent/\n        name: waagent")
-			}
-			return ""
-		},
-		"GetHostNetwork": func() string {
-			if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
-				return fmt.Sprintf("\n      hostNetwork: true")
-			}
-			return ""
-		},
-		"GetCloud": func() string {
-			cloudSpecConfig := cs.GetCloudSpecConfig()
-			return cloudSpecConfig.CloudName
-		},
-		"UseManagedIdentity": func() string {
-			if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
-				return "true"
-			}
-			return "false"
-		},
-	}
-}
-
-func buildYamlFileWithWriteFiles(files []string, cs *api.ContainerService) string {
-	clusterYamlFile := `#cloud-config
-
-write_files:
-%s
-`
-	writeFileBlock := ` -  encoding: gzip
-    content: !!binary |
-        %s
-    path: /opt/azure/containers/%s
-    permissions: "0744"
-`
-
-	filelines := ""
-	for _, file := range files {
-		b64GzipString := getBase64EncodedGzippedCustomScript(file, cs)
-		fileNoPath := strings.TrimPrefix(file, "swarm/")
-		filelines += fmt.Sprintf(writeFileBlock, b64GzipString, fileNoPath)
-	}
-	return fmt.Sprintf(clusterYamlFile, filelines)
-}
-
-func getKubernetesSubnets(properties *api.Properties) string {
-	subnetString := `{
-            "name": "podCIDR%d",
-            "properties": {
-              "addressPrefix": "10.244.%d.0/24",
-              "networkSecurityGroup": {
-                "id": "[variables('nsgID')]"
-              },
-              "routeTable": {
-                "id": "[variables('routeTableID')]"
-              }
-            }
-          }`
-	var buf bytes.Buffer
-
-	cidrIndex := getKubernetesPodStartIndex(properties)
-	for _, agentProfile := range properties.AgentPoolProfiles {
-		if agentProfile.OSType == api.Windows {
-			for i := 0; i < agentProfile.Count; i++ {
-				buf.WriteString(",\n")
-				buf.WriteString(fmt.Sprintf(subnetString, cidrIndex, cidrIndex))
-				cidrIndex++
-			}
+// getBase64EncodedWindowsAgentFunctions returns a base64 encoded zip of the Windows powershell function scripts
+func getBase64EncodedWindowsAgentFunctions() (string, error) {
+	// Collect all the parts into a zip
+	var parts = []string{
+		kubernetesWindowsAgentFunctionsPS1,
+		kubernetesWindowsConfigFunctionsPS1,
+		kubernetesWindowsKubeletFunctionsPS1,
+		kubernetesWindowsCniFunctionsPS1,
+		kubernetesWindowsAzureCniFunctionsPS1,
+		kubernetesWindowsOpenSSHFunctionPS1}
+
+	// Create a buffer, new zip
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+
+	for _, part := range parts {
+		// a zero Modified leaves the modification time out of the entry so the archive only depends on the parts
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: part, Method: zip.Deflate})
+		if err != nil {
+			return "", errors.Wrapf(err, "adding %s to zip archive", part)
 		}
-	}
-	return buf.String()
-}
-
-func getKubernetesPodStartIndex(properties *api.Properties) int {
-	nodeCount := 0
-	nodeCount += properties.MasterProfile.Count
-	for _, agentProfile := range properties.AgentPoolProfiles {
-		if agentProfile.OSType != api.Windows {
-			nodeCount += agentProfile.Count
+		partContents, err := templates.Asset(part)
+		if err != nil {
+			return "", &TemplateError{Template: part, Op: TemplateOpLoad, Err: err}
 		}
-	}
-
-	return nodeCount + 1
-}
-
-func getMasterLinkedTemplateText(orchestratorType string, extensionProfile *api.ExtensionProfile, singleOrAll string) (string, error) {
-	extTargetVMNamePrefix := "variables('masterVMNamePrefix')"
-
-	loopCount := "[variables('masterCount')]"
-	loopOffset := ""
-	if orchestratorType == api.Kubernetes {
-		// Due to upgrade k8s sometimes needs to install just some of the nodes.
-		loopCount = "[sub(variables('masterCount'), variables('masterOffset'))]"
-		loopOffset = "variables('masterOffset')"
-	}
-
-	if strings.EqualFold(singleOrAll, "single") {
-		loopCount = "1"
-	}
-	return internalGetPoolLinkedTemplateText(extTargetVMNamePrefix, orchestratorType, loopCount,
-		loopOffset, extensionProfile)
-}
-
-func getAgentPoolLinkedTemplateText(agentPoolProfile *api.AgentPoolProfile, orchestratorType string, extensionProfile *api.ExtensionProfile, singleOrAll string) (string, error) {
-	extTargetVMNamePrefix := fmt.Sprintf("variables('%sVMNamePrefix')", agentPoolProfile.Name)
-	loopCount := fmt.Sprintf("[variables('%sCount'))]", agentPoolProfile.Name)
-	loopOffset := ""
-
-	// Availability sets can have an offset since we don't redeploy vms.
-	// So we don't want to rerun these extensions in scale up scenarios.
-	if agentPoolProfile.IsAvailabilitySets() {
-		loopCount = fmt.Sprintf("[sub(variables('%sCount'), variables('%sOffset'))]",
-			agentPoolProfile.Name, agentPoolProfile.Name)
-		loopOffset = fmt.Sprintf("variables('%sOffset')", agentPoolProfile.Name)
-	}
-
-	if strings.EqualFold(singleOrAll, "single") {
-		loopCount = "1"
-	}
-
-	return internalGetPoolLinkedTemplateText(extTargetVMNamePrefix, orchestratorType, loopCount,
-		loopOffset, extensionProfile)
-}
-
-func internalGetPoolLinkedTemplateText(extTargetVMNamePrefix, orchestratorType, loopCount, loopOffset string, extensionProfile *api.ExtensionProfile) (string, error) {
-	dta, e := getLinkedTemplateTextForURL(extensionProfile.RootURL, orchestratorType, extensionProfile.Name, extensionProfile.Version, extensionProfile.URLQuery)
-	if e != nil {
-		return "", e
-	}
-	if strings.Contains(extTargetVMNamePrefix, "master") {
-		dta = strings.Replace(dta, "EXTENSION_TARGET_VM_TYPE", "master", -1)
-	} else {
-		dta = strings.Replace(dta, "EXTENSION_TARGET_VM_TYPE", "agent", -1)
-	}
-	extensionsParameterReference := fmt.Sprintf("[parameters('%sParameters')]", extensionProfile.Name)
-	dta = strings.Replace(dta, "EXTENSION_PARAMETERS_REPLACE", extensionsParameterReference, -1)
-	dta = strings.Replace(dta, "EXTENSION_URL_REPLACE", extensionProfile.RootURL, -1)
-	dta = strings.Replace(dta, "EXTENSION_TARGET_VM_NAME_PREFIX", extTargetVMNamePrefix, -1)
-	if _, err := strconv.Atoi(loopCount); err == nil {
-		dta = strings.Replace(dta, "\"EXTENSION_LOOP_COUNT\"", loopCount, -1)
-	} else {
-		dta = strings.Replace(dta, "EXTENSION_LOOP_COUNT", loopCount, -1)
-	}
-
-	dta = strings.Replace(dta, "EXTENSION_LOOP_OFFSET", loopOffset, -1)
-	return dta, nil
-}
-
-func validateProfileOptedForExtension(extensionName string, profileExtensions []api.Extension) (bool, string) {
-	for _, extension := range profileExtensions {
-		if extensionName == extension.Name {
-			return true, extension.SingleOrAll
+		_, err = f.Write(partContents)
+		if err != nil {
+			return "", errors.Wrapf(err, "writing %s to zip archive", part)
 		}
 	}
-	return false, ""
-}
-
-// getLinkedTemplateTextForURL returns the string data from
-// template-link.json in the following directory:
-// extensionsRootURL/extensions/extensionName/version
-// It returns an error if the extension cannot be found
-// or loaded.  getLinkedTemplateTextForURL provides the ability
-// to pass a root extensions url for testing
-func getLinkedTemplateTextForURL(rootURL, orchestrator, extensionName, version, query string) (string, error) {
-	supportsExtension, err := orchestratorSupportsExtension(rootURL, orchestrator, extensionName, version, query)
-	if !supportsExtension {
-		return "", errors.Wrap(err, "Extension not supported for orchestrator")
-	}
-
-	templateLinkBytes, err := getExtensionResource(rootURL, extensionName, version, "template-link.json", query)
-	if err != nil {
-		return "", err
-	}
-
-	return string(templateLinkBytes), nil
-}
-
-func orchestratorSupportsExtension(rootURL, orchestrator, extensionName, version, query string) (bool, error) {
-	orchestratorBytes, err := getExtensionResource(rootURL, extensionName, version, "supported-orchestrators.json", query)
-	if err != nil {
-		return false, err
-	}
-
-	var supportedOrchestrators []string
-	err = json.Unmarshal(orchestratorBytes, &supportedOrchestrators)
+	err := zw.Close()
 	if err != nil {
-		return false, errors.Errorf("Unable to parse supported-orchestrators.json for Extension %s Version %s", extensionName, version)
-	}
-
-	if !stringInSlice(orchestrator, supportedOrchestrators) {
-		return false, errors.Errorf("Orchestrator: %s not in list of supported orchestrators for Extension: %s Version %s", orchestrator, extensionName, version)
+		return "", errors.Wrap(err, "closing zip archive")
 	}
-
-	return true, nil
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
 }
 
-func getExtensionResource(rootURL, extensionName, version, fileName, query string) ([]byte, error) {
-	requestURL := getExtensionURL(rootURL, extensionName, version, fileName, query)
-
-	res, err := http.Get(requestURL)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Unable to GET extension resource for extension: %s with version %s with filename %s at URL: %s", extensionName, version, fileName, requestURL)
-	}
-
-	defer res.Body.Close()
-
-	if res.StatusCode != 200 {
-		return nil, errors.Errorf("Unable to GET extension resource for extension: %s with version %s with filename %s at URL: %s StatusCode: %s: Status: %s", extensionName, version, fileName, requestURL, strconv.Itoa(res.StatusCode), res.Status)
-	}
-
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Unable to GET extension resource for extension: %s with version %s  with filename %s at URL: %s", extensionName, version, fileName, requestURL)
-	}
-
-	return body, nil
+func getStringFromBase64(str string) (string, error) {
+	decodedBytes, err := base64.StdEncoding.DecodeString(str)
+	return string(decodedBytes), err
 }
 
-func getExtensionURL(rootURL, extensionName, version, fileName, query string) string {
+// GetExtensionURL returns the URL of a file of an extension under rootURL
+func GetExtensionURL(rootURL, extensionName, version, fileName, query string) string {
 	extensionsDir := "extensions"
 	url := rootURL + extensionsDir + "/" + extensionName + "/" + version + "/" + fileName
 	if query != "" {
@@ -690,15 +216,6 @@ func getExtensionURL(rootURL, extensionName, version, fileName, query string) st
 	return url
 }
 
-func stringInSlice(a string, list []string) bool {
-	for _, b := range list {
-		if b == a {
-			return true
-		}
-	}
-	return false
-}
-
 func getSSHPublicKeysPowerShell(linuxProfile *api.LinuxProfile) string {
 	str := ""
 	if linuxProfile != nil {
@@ -713,13 +230,6 @@ func getSSHPublicKeysPowerShell(linuxProfile *api.LinuxProfile) string {
 	return str
 }
 
-func getWindowsMasterSubnetARMParam(masterProfile *api.MasterProfile) string {
-	if masterProfile != nil && masterProfile.IsCustomVNET() {
-		return fmt.Sprintf("',parameters('vnetCidr'),'")
-	}
-	return fmt.Sprintf("',parameters('masterSubnet'),'")
-}
-
 // IsNvidiaEnabledSKU determines if an VM SKU has nvidia driver support
 func IsNvidiaEnabledSKU(vmSize string) bool {
 	/* If a new GPU sku becomes available, add a key to this map, but only if you have a confirmation
@@ -796,11 +306,39 @@ func IsKubernetesVersionGe(actualVersion, version string) bool {
 	return v1.GE(v2)
 }
 
-func getCustomDataFromJSON(jsonStr string) string {
+var cseEnvironmentVariableRe = regexp.MustCompile(`^([A-Z][A-Z0-9_]*)=(.*)$`)
+
+// getNodeBootstrappingCSEFromCommand splits a CSE command rendered from cse_cmd.sh into
+// its environment variable assignments and the remaining script invocation
+func getNodeBootstrappingCSEFromCommand(cmd string) *NodeBootstrappingCSE {
+	environment := map[string]string{}
+	var invocation []string
+	for _, line := range strings.Split(cmd, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := cseEnvironmentVariableRe.FindStringSubmatch(line); m != nil {
+			value := m[2]
+			if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+				value = value[1 : len(value)-1]
+			}
+			environment[m[1]] = value
+			continue
+		}
+		invocation = append(invocation, line)
+	}
+	return &NodeBootstrappingCSE{
+		Environment: environment,
+		Command:     strings.Join(invocation, " "),
+	}
+}
+
+func getCustomDataFromJSON(jsonStr string) (string, error) {
 	var customDataObj map[string]string
 	err := json.Unmarshal([]byte(jsonStr), &customDataObj)
 	if err != nil {
-		panic(err)
+		return "", errors.Wrap(err, "error unmarshalling customData JSON object")
 	}
-	return customDataObj["customData"]
+	return customDataObj["customData"], nil
 }
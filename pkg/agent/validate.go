@@ -0,0 +1,169 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+// networkPluginPolicy is a network plugin and policy combination of the kubernetes config
+type networkPluginPolicy struct {
+	plugin string
+	policy string
+}
+
+// supportedNetworkPluginPolicies are the network plugin and policy combinations the templates can render,
+// the same combinations aks-engine accepts in the vlabs api model
+var supportedNetworkPluginPolicies = map[networkPluginPolicy]bool{
+	{"", ""}:                     true,
+	{api.NetworkPluginAzure, ""}: true,
+	{api.NetworkPluginAzure, api.NetworkPolicyAzure}:    true,
+	{api.NetworkPluginKubenet, ""}:                      true,
+	{api.NetworkPluginFlannel, ""}:                      true,
+	{api.NetworkPluginCilium, api.NetworkPolicyCilium}:  true,
+	{api.NetworkPluginKubenet, api.NetworkPolicyCalico}: true,
+	{api.NetworkPluginAzure, api.NetworkPolicyCalico}:   true,
+	{"", api.NetworkPolicyCalico}:                       true,
+	{"", api.NetworkPolicyCilium}:                       true,
+	{api.NetworkPluginAntrea, api.NetworkPolicyAntrea}:  true,
+	{"", api.NetworkPolicyAntrea}:                       true,
+	{"", api.NetworkPolicyAzure}:                        true,
+	{"", api.NetworkPolicyNone}:                         true,
+}
+
+// FieldError is a validation error of a field of the node bootstrapping configuration
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. agentPoolProfile.distro
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors are all the field errors of a node bootstrapping configuration
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks that the configuration holds everything the node bootstrapping templates assume,
+// it returns ValidationErrors listing every invalid field. The TemplateGenerator render methods only check
+// the fields they cannot render without, callers validate the configurations they accept, as the generate
+// command and the server do
+func (config *NodeBootstrappingConfiguration) Validate() error {
+	errs := config.validate()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateRequired returns the errors of the fields rendering dereferences, it is the only check of the
+// TemplateGenerator render methods
+func (config *NodeBootstrappingConfiguration) validateRequired() error {
+	errs := config.requiredFieldErrors()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (config *NodeBootstrappingConfiguration) requiredFieldErrors() ValidationErrors {
+	errs := ValidationErrors{}
+	if config == nil {
+		return append(errs, &FieldError{Message: "node bootstrapping configuration must not be nil"})
+	}
+	if config.AgentPoolProfile == nil {
+		errs = append(errs, &FieldError{Field: "agentPoolProfile", Message: "must not be nil"})
+	}
+	if config.KubernetesConfig == nil {
+		errs = append(errs, &FieldError{Field: "kubernetesConfig", Message: "must not be nil"})
+	}
+	return errs
+}
+
+func (config *NodeBootstrappingConfiguration) validate() ValidationErrors {
+	errs := config.requiredFieldErrors()
+	if len(errs) > 0 {
+		// the remaining checks read the required fields
+		return errs
+	}
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if config.KubernetesVersion == "" {
+		add("kubernetesVersion", "must not be empty")
+	} else if _, ok := api.K8sComponentsByVersionMap[config.KubernetesVersion]; !ok {
+		add("kubernetesVersion", "%s is not a supported Kubernetes version", config.KubernetesVersion)
+	}
+
+	if config.isWindows() {
+		if config.WindowsProfile == nil {
+			add("windowsProfile", "must not be nil for a Windows agent pool")
+		}
+	} else if _, ok := config.cloudSpecConfig().OSImageConfig[config.AgentPoolProfile.Distro]; !ok {
+		add("agentPoolProfile.distro", "%q has no OS image config", config.AgentPoolProfile.Distro)
+	}
+
+	config.validateCertificateProfile(add)
+
+	kubernetesConfig := config.KubernetesConfig
+	if !supportedNetworkPluginPolicies[networkPluginPolicy{kubernetesConfig.NetworkPlugin, kubernetesConfig.NetworkPolicy}] {
+		add("kubernetesConfig.networkPolicy", "%q is not supported with networkPlugin %q", kubernetesConfig.NetworkPolicy, kubernetesConfig.NetworkPlugin)
+	}
+
+	if s := config.KeyVaultSecrets; s != nil {
+		for field, ref := range map[string]*KeyVaultRef{
+			"keyVaultSecrets.clientPrivateKey":     s.ClientPrivateKey,
+			"keyVaultSecrets.caPrivateKey":         s.CaPrivateKey,
+			"keyVaultSecrets.kubeConfigPrivateKey": s.KubeConfigPrivateKey,
+			"keyVaultSecrets.etcdEncryptionKey":    s.EtcdEncryptionKey,
+		} {
+			if ref == nil {
+				continue
+			}
+			if ref.KeyVault.ID == "" {
+				add(field+".keyVault.id", "must not be empty")
+			}
+			if ref.SecretName == "" {
+				add(field+".secretName", "must not be empty")
+			}
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// validateCertificateProfile checks the certificates the node uses to join the cluster
+func (config *NodeBootstrappingConfiguration) validateCertificateProfile(add func(field, format string, args ...interface{})) {
+	profile := config.CertificateProfile
+	if profile == nil {
+		add("certificateProfile", "must not be nil")
+		return
+	}
+	for field, value := range map[string]string{
+		"certificateProfile.caCertificate":        profile.CaCertificate,
+		"certificateProfile.apiServerCertificate": profile.APIServerCertificate,
+		"certificateProfile.clientCertificate":    profile.ClientCertificate,
+	} {
+		if value == "" {
+			add(field, "must not be empty")
+		}
+	}
+	if profile.ClientPrivateKey == "" && (config.KeyVaultSecrets == nil || config.KeyVaultSecrets.ClientPrivateKey == nil) {
+		add("certificateProfile.clientPrivateKey", "must not be empty unless keyVaultSecrets.clientPrivateKey is set")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestValidateGoldenConfigurations(t *testing.T) {
+	cases, err := ioutil.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatalf("reading %s: %v", goldenDir, err)
+	}
+	for _, c := range cases {
+		cs := loadGoldenContainerService(t, filepath.Join(goldenDir, c.Name(), "apimodel.json"))
+		for _, profile := range cs.Properties.AgentPoolProfiles {
+			config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, profile, "tenant", "sub", "rg", "")
+			if err != nil {
+				t.Fatalf("%s: converting the api model: %v", c.Name(), err)
+			}
+			if err := config.Validate(); err != nil {
+				t.Errorf("%s: agent pool %s: unexpected error: %v", c.Name(), profile.Name, err)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(config *NodeBootstrappingConfiguration)
+		expected []string
+	}{
+		{
+			name: "nil agent pool profile and kubernetes config",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.AgentPoolProfile = nil
+				config.KubernetesConfig = nil
+			},
+			expected: []string{"agentPoolProfile", "kubernetesConfig"},
+		},
+		{
+			name: "unsupported kubernetes version",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.KubernetesVersion = "1.0.0"
+			},
+			expected: []string{"kubernetesVersion"},
+		},
+		{
+			name: "unknown distro",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.AgentPoolProfile.Distro = "unknown"
+			},
+			expected: []string{"agentPoolProfile.distro"},
+		},
+		{
+			name: "windows agent pool without windows profile",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.AgentPoolProfile.OSType = api.Windows
+				config.WindowsProfile = nil
+			},
+			expected: []string{"windowsProfile"},
+		},
+		{
+			name: "missing certificates",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.CertificateProfile.CaCertificate = ""
+				config.CertificateProfile.ClientPrivateKey = ""
+			},
+			expected: []string{"certificateProfile.caCertificate", "certificateProfile.clientPrivateKey"},
+		},
+		{
+			name: "client private key in key vault",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.CertificateProfile.ClientPrivateKey = ""
+				config.KeyVaultSecrets = &NodeKeyVaultSecrets{ClientPrivateKey: &KeyVaultRef{SecretName: "client-key"}}
+			},
+			expected: []string{"keyVaultSecrets.clientPrivateKey.keyVault.id"},
+		},
+		{
+			name: "unsupported network plugin and policy",
+			modify: func(config *NodeBootstrappingConfiguration) {
+				config.KubernetesConfig.NetworkPlugin = api.NetworkPluginKubenet
+				config.KubernetesConfig.NetworkPolicy = api.NetworkPolicyCilium
+			},
+			expected: []string{"kubernetesConfig.networkPolicy"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cs := loadGoldenContainerService(t, filepath.Join(goldenDir, "custom-search-domain", "apimodel.json"))
+			config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+			if err != nil {
+				t.Fatalf("converting the api model: %v", err)
+			}
+			c.modify(config)
+
+			err = config.Validate()
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+			fields := []string{}
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, c.expected) {
+				t.Errorf("expected errors for %v, got %v", c.expected, errs)
+			}
+		})
+	}
+
+	var config *NodeBootstrappingConfiguration
+	if config.Validate() == nil {
+		t.Errorf("expected a nil configuration to be invalid")
+	}
+}
+
+func TestRenderDoesNotValidate(t *testing.T) {
+	cs := loadGoldenContainerService(t, filepath.Join(goldenDir, "custom-search-domain", "apimodel.json"))
+	config, err := ConvertContainerServiceToNodeBootstrappingConfiguration(cs, cs.Properties.AgentPoolProfiles[0], "tenant", "sub", "rg", "")
+	if err != nil {
+		t.Fatalf("converting the api model: %v", err)
+	}
+	config.KubernetesConfig.NetworkPlugin = api.NetworkPluginKubenet
+	config.KubernetesConfig.NetworkPolicy = api.NetworkPolicyCilium
+	if config.Validate() == nil {
+		t.Fatalf("expected the configuration to be invalid")
+	}
+
+	// validation is up to the caller, rendering only requires the fields it dereferences
+	tg := InitializeTemplateGenerator()
+	if _, err := tg.GetNodeBootstrappingPayloadFromConfig(config); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := tg.GetNodeBootstrapping(config); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	config.AgentPoolProfile = nil
+	if _, err := tg.GetNodeBootstrappingCmdFromConfig(config); err == nil {
+		t.Errorf("expected an error for a configuration without an agent pool profile")
+	}
+}
@@ -8,63 +8,119 @@ import (
 	"github.com/Azure/aks-engine/pkg/api/common"
 	"github.com/Azure/go-autorest/autorest/to"
 	"strconv"
+	"strings"
 )
 
-func getCustomDataVariables(cs *api.ContainerService) paramsMap {
-	cloudInitFiles := map[string]interface{}{
-		"cloudInitData": paramsMap{
-			"provisionScript":           getBase64EncodedGzippedCustomScript(kubernetesCSEMainScript, cs),
-			"provisionSource":           getBase64EncodedGzippedCustomScript(kubernetesCSEHelpersScript, cs),
-			"provisionInstalls":         getBase64EncodedGzippedCustomScript(kubernetesCSEInstall, cs),
-			"provisionConfigs":          getBase64EncodedGzippedCustomScript(kubernetesCSEConfig, cs),
-			"customSearchDomainsScript": getBase64EncodedGzippedCustomScript(kubernetesCustomSearchDomainsScript, cs),
-			"dhcpv6SystemdService":      getBase64EncodedGzippedCustomScript(dhcpv6SystemdService, cs),
-			"dhcpv6ConfigurationScript": getBase64EncodedGzippedCustomScript(dhcpv6ConfigurationScript, cs),
-			"kubeletSystemdService":     getBase64EncodedGzippedCustomScript(kubeletSystemdService, cs),
-			"systemdBPFMount":           getBase64EncodedGzippedCustomScript(systemdBPFMount, cs),
-		},
+// linuxCloudInitFiles are the artifacts written by the customData of every Linux node, keyed by their
+// cloudInitData variable property
+var linuxCloudInitFiles = map[string]string{
+	"provisionScript":           kubernetesCSEMainScript,
+	"provisionSource":           kubernetesCSEHelpersScript,
+	"provisionInstalls":         kubernetesCSEInstall,
+	"provisionConfigs":          kubernetesCSEConfig,
+	"customSearchDomainsScript": kubernetesCustomSearchDomainsScript,
+	"dhcpv6SystemdService":      dhcpv6SystemdService,
+	"dhcpv6ConfigurationScript": dhcpv6ConfigurationScript,
+	"kubeletSystemdService":     kubeletSystemdService,
+	"systemdBPFMount":           systemdBPFMount,
+}
+
+// nonVHDCloudInitFiles are the artifacts only written by the customData of Linux nodes not running a VHD distro,
+// the VHD already has them
+var nonVHDCloudInitFiles = map[string]string{
+	"provisionCIS":                     kubernetesCISScript,
+	"kmsSystemdService":                kmsSystemdService,
+	"aptPreferences":                   aptPreferences,
+	"healthMonitorScript":              kubernetesHealthMonitorScript,
+	"kubeletMonitorSystemdService":     kubernetesKubeletMonitorSystemdService,
+	"dockerMonitorSystemdService":      kubernetesDockerMonitorSystemdService,
+	"dockerMonitorSystemdTimer":        kubernetesDockerMonitorSystemdTimer,
+	"dockerClearMountPropagationFlags": dockerClearMountPropagationFlags,
+	"auditdRules":                      auditdRules,
+}
+
+func getCustomDataVariables(config *NodeBootstrappingConfiguration, format outputFormat) (paramsMap, error) {
+	cloudInitFiles := map[string]string{}
+	for k, file := range linuxCloudInitFiles {
+		cloudInitFiles[k] = file
+	}
+	if !config.AgentPoolProfile.IsVHDDistro() {
+		for k, file := range nonVHDCloudInitFiles {
+			cloudInitFiles[k] = file
+		}
 	}
 
-	cloudInitData := cloudInitFiles["cloudInitData"].(paramsMap)
-	if !cs.Properties.IsVHDDistroForAllNodes() {
-		cloudInitData["provisionCIS"] = getBase64EncodedGzippedCustomScript(kubernetesCISScript, cs)
-		cloudInitData["kmsSystemdService"] = getBase64EncodedGzippedCustomScript(kmsSystemdService, cs)
-		cloudInitData["labelNodesScript"] = getBase64EncodedGzippedCustomScript(labelNodesScript, cs)
-		cloudInitData["labelNodesSystemdService"] = getBase64EncodedGzippedCustomScript(labelNodesSystemdService, cs)
-		cloudInitData["aptPreferences"] = getBase64EncodedGzippedCustomScript(aptPreferences, cs)
-		cloudInitData["healthMonitorScript"] = getBase64EncodedGzippedCustomScript(kubernetesHealthMonitorScript, cs)
-		cloudInitData["kubeletMonitorSystemdService"] = getBase64EncodedGzippedCustomScript(kubernetesKubeletMonitorSystemdService, cs)
-		cloudInitData["dockerMonitorSystemdService"] = getBase64EncodedGzippedCustomScript(kubernetesDockerMonitorSystemdService, cs)
-		cloudInitData["dockerMonitorSystemdTimer"] = getBase64EncodedGzippedCustomScript(kubernetesDockerMonitorSystemdTimer, cs)
-		cloudInitData["dockerClearMountPropagationFlags"] = getBase64EncodedGzippedCustomScript(dockerClearMountPropagationFlags, cs)
-		cloudInitData["auditdRules"] = getBase64EncodedGzippedCustomScript(auditdRules, cs)
+	artifacts, err := getBase64EncodedGzippedCustomScripts(cloudInitFiles, config)
+	if err != nil {
+		return nil, err
+	}
+	cloudInitData := paramsMap{}
+	for k, b64GzipString := range artifacts {
+		cloudInitData[k] = b64GzipString
 	}
 
-	return cloudInitFiles
+	return paramsMap{
+		"cloudInitData":      cloudInitData,
+		"labelResourceGroup": getLabelResourceGroup(config, format),
+	}, nil
+}
+
+// getWindowsCustomDataVariables returns the variables of the Windows node setup script, the customData variables
+// and the cloud provider config the script writes to azure.json
+func getWindowsCustomDataVariables(config *NodeBootstrappingConfiguration, format outputFormat) (paramsMap, error) {
+	variables, err := getCustomDataVariables(config, format)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range getCSECommandVariables(config) {
+		variables[k] = v
+	}
+	variables["userAssignedClientID"] = config.Identity.UserAssignedIdentityClientID
+	return variables, nil
+}
+
+// getLabelResourceGroup returns the resource group used in the node labels. ARM output references
+// the labelResourceGroup template variable, plain output applies the same truncation rules in place.
+func getLabelResourceGroup(config *NodeBootstrappingConfiguration, format outputFormat) string {
+	if format == outputFormatARM {
+		return "',variables('labelResourceGroup'),'"
+	}
+	rg := strings.NewReplacer("(", "-", ")", "-").Replace(config.Identity.ResourceGroupName)
+	if len(rg) > 63 {
+		rg = rg[:63]
+	}
+	if strings.HasSuffix(rg, "-") || strings.HasSuffix(rg, "_") || strings.HasSuffix(rg, ".") {
+		if len(rg) > 62 {
+			rg = rg[:62]
+		}
+		rg += "z"
+	}
+	return rg
 }
 
-func getCSECommandVariables(cs *api.ContainerService, profile *api.AgentPoolProfile,
-	tenantID, subscriptionID, resourceGroupName, userAssignedIdentityID string) paramsMap {
+func getCSECommandVariables(config *NodeBootstrappingConfiguration) paramsMap {
+	profile := config.AgentPoolProfile
+	cloudProviderConfig := config.CloudProviderConfig
 	return map[string]interface{}{
-		"outBoundCmd":                     getOutBoundCmd(cs),
-		"tenantID":                        tenantID,
-		"subscriptionId":                  subscriptionID,
-		"resourceGroup":                   resourceGroupName,
-		"location":                        cs.Location,
-		"vmType":                          cs.Properties.GetVMType(),
-		"subnetName":                      cs.Properties.GetSubnetName(),
-		"nsgName":                         cs.Properties.GetNSGName(),
-		"virtualNetworkName":              cs.Properties.GetVirtualNetworkName(),
-		"virtualNetworkResourceGroupName": cs.Properties.GetVNetResourceGroupName(),
-		"routeTableName":                  cs.Properties.GetRouteTableName(),
-		"primaryAvailabilitySetName":      cs.Properties.GetPrimaryAvailabilitySetName(),
-		"primaryScaleSetName":             cs.Properties.GetPrimaryScaleSetName(),
-		"useManagedIdentityExtension":     useManagedIdentity(cs),
-		"useInstanceMetadata":             useInstanceMetadata(cs),
-		"loadBalancerSku":                 cs.Properties.OrchestratorProfile.KubernetesConfig.LoadBalancerSku,
+		"outBoundCmd":                     getOutBoundCmd(config),
+		"tenantID":                        config.Identity.TenantID,
+		"subscriptionId":                  config.Identity.SubscriptionID,
+		"resourceGroup":                   config.Identity.ResourceGroupName,
+		"location":                        config.Location,
+		"vmType":                          cloudProviderConfig.VMType,
+		"subnetName":                      cloudProviderConfig.SubnetName,
+		"nsgName":                         cloudProviderConfig.NSGName,
+		"virtualNetworkName":              cloudProviderConfig.VirtualNetworkName,
+		"virtualNetworkResourceGroupName": cloudProviderConfig.VirtualNetworkResourceGroupName,
+		"routeTableName":                  cloudProviderConfig.RouteTableName,
+		"primaryAvailabilitySetName":      cloudProviderConfig.PrimaryAvailabilitySetName,
+		"primaryScaleSetName":             cloudProviderConfig.PrimaryScaleSetName,
+		"useManagedIdentityExtension":     useManagedIdentity(config),
+		"useInstanceMetadata":             useInstanceMetadata(config),
+		"loadBalancerSku":                 config.KubernetesConfig.LoadBalancerSku,
 		"excludeMasterFromStandardLB":     true,
-		"maximumLoadBalancerRuleCount":    getMaximumLoadBalancerRuleCount(cs),
-		"userAssignedIdentityID":          userAssignedIdentityID,
+		"maximumLoadBalancerRuleCount":    getMaximumLoadBalancerRuleCount(config),
+		"userAssignedIdentityID":          config.Identity.UserAssignedIdentityClientID,
 		"isVHD":                           isVHD(profile),
 		"gpuNode":                         strconv.FormatBool(common.IsNvidiaEnabledSKU(profile.VMSize)),
 		"sgxNode":                         strconv.FormatBool(common.IsSgxEnabledSKU(profile.VMSize)),
@@ -72,22 +128,22 @@ func getCSECommandVariables(cs *api.ContainerService, profile *api.AgentPoolProf
 	}
 }
 
-func useManagedIdentity(cs *api.ContainerService) string {
-	useManagedIdentity := cs.Properties.OrchestratorProfile.KubernetesConfig != nil &&
-		cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity
+func useManagedIdentity(config *NodeBootstrappingConfiguration) string {
+	useManagedIdentity := config.KubernetesConfig != nil &&
+		config.KubernetesConfig.UseManagedIdentity
 	return strconv.FormatBool(useManagedIdentity)
 }
 
-func useInstanceMetadata(cs *api.ContainerService) string {
-	useInstanceMetadata := cs.Properties.OrchestratorProfile.KubernetesConfig != nil &&
-		cs.Properties.OrchestratorProfile.KubernetesConfig.UseInstanceMetadata != nil &&
-		*cs.Properties.OrchestratorProfile.KubernetesConfig.UseInstanceMetadata
+func useInstanceMetadata(config *NodeBootstrappingConfiguration) string {
+	useInstanceMetadata := config.KubernetesConfig != nil &&
+		config.KubernetesConfig.UseInstanceMetadata != nil &&
+		*config.KubernetesConfig.UseInstanceMetadata
 	return strconv.FormatBool(useInstanceMetadata)
 }
 
-func getMaximumLoadBalancerRuleCount(cs *api.ContainerService) int {
-	if cs.Properties.OrchestratorProfile.KubernetesConfig != nil {
-		return cs.Properties.OrchestratorProfile.KubernetesConfig.MaximumLoadBalancerRuleCount
+func getMaximumLoadBalancerRuleCount(config *NodeBootstrappingConfiguration) int {
+	if config.KubernetesConfig != nil {
+		return config.KubernetesConfig.MaximumLoadBalancerRuleCount
 	}
 	return 0
 }
@@ -97,13 +153,13 @@ func isVHD(profile *api.AgentPoolProfile) string {
 	return strconv.FormatBool(profile.IsVHDDistro())
 }
 
-func getOutBoundCmd(cs *api.ContainerService) string {
-	if cs.Properties.FeatureFlags.IsFeatureEnabled("BlockOutboundInternet") {
+func getOutBoundCmd(config *NodeBootstrappingConfiguration) string {
+	if config.FeatureFlags.IsFeatureEnabled("BlockOutboundInternet") {
 		return ""
 	}
 	registry := ""
 	ncBinary := "nc"
-	if cs.GetCloudSpecConfig().CloudName == api.AzureChinaCloud {
+	if config.cloudSpecConfig().CloudName == api.AzureChinaCloud {
 		registry = `gcr.azk8s.cn 443`
 	} else {
 		registry = `mcr.microsoft.com 443`
@@ -0,0 +1,120 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"regexp"
+
+	"github.com/Azure/agentbaker/pkg/vhd"
+	"github.com/pkg/errors"
+)
+
+// kubeletPodInfraContainerImageRe matches the pause image flag of the kubelet defaults the customData writes
+var kubeletPodInfraContainerImageRe = regexp.MustCompile(`--pod-infra-container-image=(\S+)`)
+
+// kubeletDefaultsPath is the file holding the kubelet flags on the node
+const kubeletDefaultsPath = "/etc/default/kubelet"
+
+// VHDArtifactKind is the kind of a VHDArtifact, the component manifest list it is looked up in
+type VHDArtifactKind string
+
+const (
+	// VHDArtifactPackage is installed from a package repository, see vhd.Manifest Packages
+	VHDArtifactPackage VHDArtifactKind = "package"
+	// VHDArtifactFile is an archive downloaded by URL, see vhd.Manifest Files
+	VHDArtifactFile VHDArtifactKind = "file"
+	// VHDArtifactContainerImage is a pulled container image, see vhd.Manifest ContainerImages
+	VHDArtifactContainerImage VHDArtifactKind = "containerImage"
+	// VHDArtifactKubernetesBinaries are kubelet and kubectl, see vhd.Manifest KubernetesBinaries
+	VHDArtifactKubernetesBinaries VHDArtifactKind = "kubernetesBinaries"
+)
+
+// VHDArtifact is a binary or container image the node fetches while it bootstraps unless the VHD has it
+type VHDArtifact struct {
+	Kind VHDArtifactKind `json:"kind"`
+	Name string          `json:"name"`
+	// Version of a package or of the Kubernetes binaries
+	Version string `json:"version,omitempty"`
+	// URL of a file or reference of a container image, the hyperkube image the Kubernetes binaries are extracted from
+	URL string `json:"url,omitempty"`
+	// Cached is true if the VHD has the artifact and the node does not download it
+	Cached bool `json:"cached"`
+}
+
+// VHDCacheAnalysis lists the artifacts the node bootstrapping of an agent pool fetches, split by whether
+// the VHD has them, in the order the node fetches them
+type VHDCacheAnalysis struct {
+	// VHD is false for an agent pool not running a VHD distro, it caches nothing
+	VHD    bool           `json:"vhd"`
+	Hits   []*VHDArtifact `json:"hits"`
+	Misses []*VHDArtifact `json:"misses"`
+}
+
+func (a *VHDCacheAnalysis) add(artifact *VHDArtifact) {
+	artifact.Cached = a.VHD && artifact.Cached
+	if artifact.Cached {
+		a.Hits = append(a.Hits, artifact)
+	} else {
+		a.Misses = append(a.Misses, artifact)
+	}
+}
+
+// GetVHDCacheAnalysis renders the node bootstrapping of a Linux agent pool and looks up every binary and
+// container image its CSE fetches in the component manifest of the VHD. The CSE downloads the misses,
+// which takes minutes per node and fails in clusters with restricted egress. The analysis covers moby,
+// the CNI plugins, the containerd tarball, kubelet and the pause image. It does not cover the GPU and
+// SGX drivers, kata-runtime and img, the manifest does not list them
+func (t *TemplateGenerator) GetVHDCacheAnalysis(config *NodeBootstrappingConfiguration, manifest *vhd.Manifest) (*VHDCacheAnalysis, error) {
+	if config.isWindows() {
+		return nil, errors.New("the VHD cache analysis only supports Linux agent pools")
+	}
+	nodeBootstrapping, err := t.GetNodeBootstrapping(config)
+	if err != nil {
+		return nil, err
+	}
+	content, err := DecodeNodeBootstrapping(nodeBootstrapping)
+	if err != nil {
+		return nil, err
+	}
+	variables := content.Variables
+	analysis := &VHDCacheAnalysis{
+		VHD:    variables["IS_VHD"] == "true",
+		Hits:   []*VHDArtifact{},
+		Misses: []*VHDArtifact{},
+	}
+
+	// the steps of cse_main.sh fetching artifacts, see installContainerRuntime, installNetworkPlugin,
+	// installContainerd and installKubeletAndKubectl
+	if variables["CONTAINER_RUNTIME"] == "docker" && !config.properties().HasCoreOS() {
+		version := variables["MOBY_VERSION"]
+		moby := manifest.Package("moby")
+		analysis.add(&VHDArtifact{Kind: VHDArtifactPackage, Name: "moby", Version: version, Cached: moby != nil && moby.HasVersion(version)})
+	}
+	if variables["NETWORK_PLUGIN"] == NetworkPluginAzure {
+		url := variables["VNET_CNI_PLUGINS_URL"]
+		analysis.add(&VHDArtifact{Kind: VHDArtifactFile, Name: "azure-cni", URL: url, Cached: manifest.HasFile(url)})
+	}
+	url := variables["CNI_PLUGINS_URL"]
+	analysis.add(&VHDArtifact{Kind: VHDArtifactFile, Name: "cni-plugins", URL: url, Cached: manifest.HasFile(url)})
+	if config.KubernetesConfig.NeedsContainerd() {
+		version := variables["CONTAINERD_VERSION"]
+		url := variables["CONTAINERD_DOWNLOAD_URL_BASE"] + "cri-containerd-" + version + ".linux-amd64.tar.gz"
+		analysis.add(&VHDArtifact{Kind: VHDArtifactFile, Name: "containerd", Version: version, URL: url, Cached: manifest.HasFile(url)})
+	}
+
+	version, hyperkube := variables["KUBERNETES_VERSION"], variables["HYPERKUBE_URL"]
+	binaries := &VHDArtifact{Kind: VHDArtifactKubernetesBinaries, Name: "kubelet", Version: version, URL: hyperkube, Cached: manifest.HasKubernetesBinaries(version)}
+	analysis.add(binaries)
+	if !binaries.Cached {
+		// the binaries are extracted from the hyperkube image, the VHD may still have the image
+		analysis.add(&VHDArtifact{Kind: VHDArtifactContainerImage, Name: "hyperkube", URL: hyperkube, Cached: manifest.HasContainerImage(hyperkube)})
+	}
+
+	// kubelet pulls the pause image before it runs the first pod
+	if m := kubeletPodInfraContainerImageRe.FindSubmatch(content.Files[kubeletDefaultsPath]); m != nil {
+		image := string(m[1])
+		analysis.add(&VHDArtifact{Kind: VHDArtifactContainerImage, Name: "pause", URL: image, Cached: manifest.HasContainerImage(image)})
+	}
+	return analysis, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Azure/agentbaker/pkg/vhd"
+)
+
+// vhdManifestPath is the component manifest of the Linux VHD build
+const vhdManifestPath = "../../vhdbuilder/packer/components.json"
+
+func artifactNames(artifacts []*VHDArtifact) []string {
+	names := []string{}
+	for _, a := range artifacts {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func TestGetVHDCacheAnalysis(t *testing.T) {
+	manifest, err := vhd.LoadManifest(vhdManifestPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tg := InitializeTemplateGenerator()
+
+	config := loadBenchmarkConfiguration(t, "azurecni-containerd")
+	analysis, err := tg.GetVHDCacheAnalysis(config, manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"azure-cni", "cni-plugins", "containerd", "kubelet", "pause"}
+	if actual := artifactNames(analysis.Hits); !analysis.VHD || len(analysis.Misses) != 0 || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected the hits %v and no misses, got %v and %v", expected, actual, artifactNames(analysis.Misses))
+	}
+
+	// a containerd version missing from the VHD is downloaded
+	config.KubernetesConfig.ContainerdVersion = "1.3.3"
+	analysis, err = tg.GetVHDCacheAnalysis(config, manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := artifactNames(analysis.Misses); !reflect.DeepEqual(actual, []string{"containerd"}) || analysis.Misses[0].URL != "https://storage.googleapis.com/cri-containerd-release/cri-containerd-1.3.3.linux-amd64.tar.gz" {
+		t.Errorf("expected the containerd 1.3.3 miss, got %v", analysis.Misses)
+	}
+	config.KubernetesConfig.ContainerdVersion = "1.2.4"
+
+	// a kubelet missing from the VHD is extracted from the hyperkube image
+	config.KubernetesVersion = "1.16.5"
+	config.KubernetesConfig.CustomHyperkubeImage = "example.azurecr.io/hyperkube:v1.16.5"
+	analysis, err = tg.GetVHDCacheAnalysis(config, manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := artifactNames(analysis.Misses); !reflect.DeepEqual(actual, []string{"kubelet", "hyperkube"}) {
+		t.Errorf("expected the kubelet and hyperkube misses, got %v", actual)
+	}
+
+	// a distro other than a VHD caches nothing
+	config = loadBenchmarkConfiguration(t, "kubenet-docker")
+	config.AgentPoolProfile.Distro = "ubuntu"
+	analysis, err = tg.GetVHDCacheAnalysis(config, manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []string{"moby", "cni-plugins", "kubelet", "hyperkube", "pause"}
+	if actual := artifactNames(analysis.Misses); analysis.VHD || len(analysis.Hits) != 0 || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected the misses %v and no hits, got %v and %v", expected, actual, artifactNames(analysis.Hits))
+	}
+
+	if _, err = tg.GetVHDCacheAnalysis(loadBenchmarkConfiguration(t, "windows"), manifest); err == nil {
+		t.Errorf("expected an error for a Windows agent pool")
+	}
+}
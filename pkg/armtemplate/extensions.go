@@ -0,0 +1,142 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armtemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// GetMasterLinkedTemplateText returns the linked template of an extension installed on the masters. The
+// extension files are read from source, nil downloads them from the extension rootURL
+func GetMasterLinkedTemplateText(orchestratorType string, extensionProfile *api.ExtensionProfile, singleOrAll string, source agent.ExtensionSource) (string, error) {
+	extTargetVMNamePrefix := "variables('masterVMNamePrefix')"
+
+	loopCount := "[variables('masterCount')]"
+	loopOffset := ""
+	if orchestratorType == api.Kubernetes {
+		// Due to upgrade k8s sometimes needs to install just some of the nodes.
+		loopCount = "[sub(variables('masterCount'), variables('masterOffset'))]"
+		loopOffset = "variables('masterOffset')"
+	}
+
+	if strings.EqualFold(singleOrAll, "single") {
+		loopCount = "1"
+	}
+	return internalGetPoolLinkedTemplateText(extTargetVMNamePrefix, orchestratorType, loopCount,
+		loopOffset, extensionProfile, source)
+}
+
+// GetAgentPoolLinkedTemplateText returns the linked template of an extension installed on the agent pool. The
+// extension files are read from source, nil downloads them from the extension rootURL
+func GetAgentPoolLinkedTemplateText(agentPoolProfile *api.AgentPoolProfile, orchestratorType string, extensionProfile *api.ExtensionProfile, singleOrAll string, source agent.ExtensionSource) (string, error) {
+	extTargetVMNamePrefix := fmt.Sprintf("variables('%sVMNamePrefix')", agentPoolProfile.Name)
+	loopCount := fmt.Sprintf("[variables('%sCount'))]", agentPoolProfile.Name)
+	loopOffset := ""
+
+	// Availability sets can have an offset since we don't redeploy vms.
+	// So we don't want to rerun these extensions in scale up scenarios.
+	if agentPoolProfile.IsAvailabilitySets() {
+		loopCount = fmt.Sprintf("[sub(variables('%sCount'), variables('%sOffset'))]",
+			agentPoolProfile.Name, agentPoolProfile.Name)
+		loopOffset = fmt.Sprintf("variables('%sOffset')", agentPoolProfile.Name)
+	}
+
+	if strings.EqualFold(singleOrAll, "single") {
+		loopCount = "1"
+	}
+
+	return internalGetPoolLinkedTemplateText(extTargetVMNamePrefix, orchestratorType, loopCount,
+		loopOffset, extensionProfile, source)
+}
+
+func internalGetPoolLinkedTemplateText(extTargetVMNamePrefix, orchestratorType, loopCount, loopOffset string, extensionProfile *api.ExtensionProfile, source agent.ExtensionSource) (string, error) {
+	if source == nil {
+		source = &agent.HTTPExtensionSource{}
+	}
+	dta, e := getLinkedTemplateText(source, orchestratorType, extensionProfile)
+	if e != nil {
+		return "", e
+	}
+	if strings.Contains(extTargetVMNamePrefix, "master") {
+		dta = strings.Replace(dta, "EXTENSION_TARGET_VM_TYPE", "master", -1)
+	} else {
+		dta = strings.Replace(dta, "EXTENSION_TARGET_VM_TYPE", "agent", -1)
+	}
+	extensionsParameterReference := fmt.Sprintf("[parameters('%sParameters')]", extensionProfile.Name)
+	dta = strings.Replace(dta, "EXTENSION_PARAMETERS_REPLACE", extensionsParameterReference, -1)
+	dta = strings.Replace(dta, "EXTENSION_URL_REPLACE", extensionProfile.RootURL, -1)
+	dta = strings.Replace(dta, "EXTENSION_TARGET_VM_NAME_PREFIX", extTargetVMNamePrefix, -1)
+	if _, err := strconv.Atoi(loopCount); err == nil {
+		dta = strings.Replace(dta, "\"EXTENSION_LOOP_COUNT\"", loopCount, -1)
+	} else {
+		dta = strings.Replace(dta, "EXTENSION_LOOP_COUNT", loopCount, -1)
+	}
+
+	dta = strings.Replace(dta, "EXTENSION_LOOP_OFFSET", loopOffset, -1)
+	return dta, nil
+}
+
+// ValidateProfileOptedForExtension returns true and the singleOrAll of the extension if the profile extensions include it
+func ValidateProfileOptedForExtension(extensionName string, profileExtensions []api.Extension) (bool, string) {
+	for _, extension := range profileExtensions {
+		if extensionName == extension.Name {
+			return true, extension.SingleOrAll
+		}
+	}
+	return false, ""
+}
+
+// getLinkedTemplateText returns the string data from
+// template-link.json in the following directory:
+// extensionsRootURL/extensions/extensionName/version
+// It returns an error if the extension cannot be found
+// or loaded.
+func getLinkedTemplateText(source agent.ExtensionSource, orchestrator string, extensionProfile *api.ExtensionProfile) (string, error) {
+	supportsExtension, err := orchestratorSupportsExtension(source, orchestrator, extensionProfile)
+	if !supportsExtension {
+		return "", errors.Wrap(err, "Extension not supported for orchestrator")
+	}
+
+	templateLinkBytes, err := source.GetExtensionFile(extensionProfile, "template-link.json")
+	if err != nil {
+		return "", err
+	}
+
+	return string(templateLinkBytes), nil
+}
+
+func orchestratorSupportsExtension(source agent.ExtensionSource, orchestrator string, extensionProfile *api.ExtensionProfile) (bool, error) {
+	orchestratorBytes, err := source.GetExtensionFile(extensionProfile, "supported-orchestrators.json")
+	if err != nil {
+		return false, err
+	}
+
+	var supportedOrchestrators []string
+	err = json.Unmarshal(orchestratorBytes, &supportedOrchestrators)
+	if err != nil {
+		return false, errors.Errorf("Unable to parse supported-orchestrators.json for Extension %s Version %s", extensionProfile.Name, extensionProfile.Version)
+	}
+
+	if !stringInSlice(orchestrator, supportedOrchestrators) {
+		return false, errors.Errorf("Orchestrator: %s not in list of supported orchestrators for Extension: %s Version %s", orchestrator, extensionProfile.Name, extensionProfile.Version)
+	}
+
+	return true, nil
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package armtemplate holds the ARM template and master helpers aks-engine renders its cluster templates with.
+// Node bootstrapping does not use any of them, the node func map lives in package agent
+package armtemplate
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// GetFuncMap returns the funcs of the master and ARM templates, they are a thin wrapper over the container
+// service and the helpers of this package
+func GetFuncMap(cs *api.ContainerService) template.FuncMap {
+	return template.FuncMap{
+		"IsMultiMasterCluster": func() bool {
+			return cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.HasMultipleNodes()
+		},
+		"IsMasterVirtualMachineScaleSets": func() bool {
+			return cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.IsVirtualMachineScaleSets()
+		},
+		"IsHostedMaster": func() bool {
+			return cs.Properties.IsHostedMasterProfile()
+		},
+		"IsKubernetesVersionLt": func(version string) bool {
+			return cs.Properties.OrchestratorProfile.IsKubernetes() && !agent.IsKubernetesVersionGe(cs.Properties.OrchestratorProfile.OrchestratorVersion, version)
+		},
+		"HasPrivateRegistry": func() bool {
+			if cs.Properties.OrchestratorProfile.DcosConfig != nil {
+				return cs.Properties.OrchestratorProfile.DcosConfig.HasPrivateRegistry()
+			}
+			return false
+		},
+		"IsSwarmMode": func() bool {
+			return cs.Properties.OrchestratorProfile.IsSwarmMode()
+		},
+		"IsKubernetes": func() bool {
+			return cs.Properties.OrchestratorProfile.IsKubernetes()
+		},
+		"HasCosmosEtcd": func() bool {
+			return cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.HasCosmosEtcd()
+		},
+		"GetCosmosEndPointUri": func() string {
+			if cs.Properties.MasterProfile != nil {
+				return cs.Properties.MasterProfile.GetCosmosEndPointURI()
+			}
+			return ""
+		},
+		"IsPrivateCluster": func() bool {
+			return cs.Properties.OrchestratorProfile.IsPrivateCluster()
+		},
+		"ProvisionJumpbox": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateJumpboxProvision()
+		},
+		"UseManagedIdentity": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity
+		},
+		"GetVNETSubnetDependencies": func() string {
+			return getVNETSubnetDependencies(cs.Properties)
+		},
+		"GetLBRules": func(name string, ports []int) string {
+			return getLBRules(name, ports)
+		},
+		"GetProbes": func(ports []int) string {
+			return getProbes(ports)
+		},
+		"GetSecurityRules": func(ports []int) string {
+			return getSecurityRules(ports)
+		},
+		"GetUniqueNameSuffix": func() string {
+			return cs.Properties.GetClusterID()
+		},
+		"GetVNETAddressPrefixes": func() string {
+			return getVNETAddressPrefixes(cs.Properties)
+		},
+		"GetVNETSubnets": func(addNSG bool) string {
+			return getVNETSubnets(cs.Properties, addNSG)
+		},
+		"GetKubernetesSubnets": func() string {
+			return getKubernetesSubnets(cs.Properties)
+		},
+		"GetDataDisks": func(profile *api.AgentPoolProfile) string {
+			return getDataDisks(profile)
+		},
+		"HasBootstrap": func() bool {
+			return cs.Properties.OrchestratorProfile.DcosConfig != nil && cs.Properties.OrchestratorProfile.DcosConfig.HasBootstrap()
+		},
+		"GetDefaultVNETCIDR": func() string {
+			return agent.DefaultVNETCIDR
+		},
+		"GetDefaultVNETCIDRIPv6": func() string {
+			return agent.DefaultVNETCIDRIPv6
+		},
+		"GetWindowsMasterSubnetARMParam": func() string {
+			return getWindowsMasterSubnetARMParam(cs.Properties.MasterProfile)
+		},
+		"GetLocation": func() string {
+			return cs.Location
+		},
+		"AnyAgentIsLinux": func() bool {
+			return cs.Properties.AnyAgentIsLinux()
+		},
+		"HasAvailabilityZones": func(profile *api.AgentPoolProfile) bool {
+			return profile.HasAvailabilityZones()
+		},
+		"HasLinuxSecrets": func() bool {
+			return cs.Properties.LinuxProfile.HasSecrets()
+		},
+		"HasCustomNodesDNS": func() bool {
+			return cs.Properties.LinuxProfile != nil && cs.Properties.LinuxProfile.HasCustomNodesDNS()
+		},
+		"HasWindowsSecrets": func() bool {
+			return cs.Properties.WindowsProfile.HasSecrets()
+		},
+		"HasWindowsCustomImage": func() bool {
+			return cs.Properties.WindowsProfile.HasCustomImage()
+		},
+		"GetConfigurationScriptRootURL": func() string {
+			linuxProfile := cs.Properties.LinuxProfile
+			if linuxProfile == nil || linuxProfile.ScriptRootURL == "" {
+				return agent.DefaultConfigurationScriptRootURL
+			}
+			return linuxProfile.ScriptRootURL
+		},
+		"GetMasterOSImageOffer": func() string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[cs.Properties.MasterProfile.Distro].ImageOffer)
+		},
+		"GetMasterOSImagePublisher": func() string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[cs.Properties.MasterProfile.Distro].ImagePublisher)
+		},
+		"GetMasterOSImageSKU": func() string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[cs.Properties.MasterProfile.Distro].ImageSku)
+		},
+		"GetMasterOSImageVersion": func() string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[cs.Properties.MasterProfile.Distro].ImageVersion)
+		},
+		"GetAgentOSImageOffer": func(profile *api.AgentPoolProfile) string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[profile.Distro].ImageOffer)
+		},
+		"GetAgentOSImagePublisher": func(profile *api.AgentPoolProfile) string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[profile.Distro].ImagePublisher)
+		},
+		"GetAgentOSImageSKU": func(profile *api.AgentPoolProfile) string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[profile.Distro].ImageSku)
+		},
+		"GetAgentOSImageVersion": func(profile *api.AgentPoolProfile) string {
+			cloudSpecConfig := cs.GetCloudSpecConfig()
+			return fmt.Sprintf("\"%s\"", cloudSpecConfig.OSImageConfig[profile.Distro].ImageVersion)
+		},
+		"UseCloudControllerManager": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.UseCloudControllerManager != nil && *cs.Properties.OrchestratorProfile.KubernetesConfig.UseCloudControllerManager
+		},
+		"AdminGroupID": func() bool {
+			return cs.Properties.AADProfile != nil && cs.Properties.AADProfile.AdminGroupID != ""
+		},
+		"EnableDataEncryptionAtRest": func() bool {
+			return to.Bool(cs.Properties.OrchestratorProfile.KubernetesConfig.EnableDataEncryptionAtRest)
+		},
+		"EnableAggregatedAPIs": func() bool {
+			if cs.Properties.OrchestratorProfile.KubernetesConfig.EnableAggregatedAPIs {
+				return true
+			} else if agent.IsKubernetesVersionGe(cs.Properties.OrchestratorProfile.OrchestratorVersion, "1.9.0") {
+				return true
+			}
+			return false
+		},
+		"EnablePodSecurityPolicy": func() bool {
+			return to.Bool(cs.Properties.OrchestratorProfile.KubernetesConfig.EnablePodSecurityPolicy)
+		},
+		"IsCustomVNET": func() bool {
+			return cs.Properties.AreAgentProfilesCustomVNET()
+		},
+		"RequiresDocker": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.RequiresDocker()
+		},
+		"GetComponentImageReference": func(name string) string {
+			k := cs.Properties.OrchestratorProfile.KubernetesConfig
+			switch name {
+			case "kube-apiserver":
+				if k.CustomKubeAPIServerImage != "" {
+					return k.CustomKubeAPIServerImage
+				}
+			case "kube-controller-manager":
+				if k.CustomKubeControllerManagerImage != "" {
+					return k.CustomKubeControllerManagerImage
+				}
+			case "kube-scheduler":
+				if k.CustomKubeSchedulerImage != "" {
+					return k.CustomKubeSchedulerImage
+				}
+			}
+			kubernetesImageBase := k.KubernetesImageBase
+			if cs.Properties.IsAzureStackCloud() {
+				kubernetesImageBase = cs.GetCloudSpecConfig().KubernetesSpecConfig.KubernetesImageBase
+			}
+			k8sComponents := api.K8sComponentsByVersionMap[cs.Properties.OrchestratorProfile.OrchestratorVersion]
+			return kubernetesImageBase + k8sComponents[name]
+		},
+		"HasTelemetryEnabled": func() bool {
+			return cs.Properties.FeatureFlags != nil && cs.Properties.FeatureFlags.EnableTelemetry
+		},
+		"GetApplicationInsightsTelemetryKey": func() string {
+			return cs.Properties.TelemetryProfile.ApplicationInsightsKey
+		},
+	}
+}
+
+// GetAddonFuncMap returns the funcs of an addon manifest template
+func GetAddonFuncMap(addon api.KubernetesAddon) template.FuncMap {
+	return template.FuncMap{
+		"ContainerImage": func(name string) string {
+			i := addon.GetAddonContainersIndexByName(name)
+			return addon.Containers[i].Image
+		},
+
+		"ContainerCPUReqs": func(name string) string {
+			i := addon.GetAddonContainersIndexByName(name)
+			return addon.Containers[i].CPURequests
+		},
+
+		"ContainerCPULimits": func(name string) string {
+			i := addon.GetAddonContainersIndexByName(name)
+			return addon.Containers[i].CPULimits
+		},
+
+		"ContainerMemReqs": func(name string) string {
+			i := addon.GetAddonContainersIndexByName(name)
+			return addon.Containers[i].MemoryRequests
+		},
+
+		"ContainerMemLimits": func(name string) string {
+			i := addon.GetAddonContainersIndexByName(name)
+			return addon.Containers[i].MemoryLimits
+		},
+		"ContainerConfig": func(name string) string {
+			return addon.Config[name]
+		},
+	}
+}
+
+// GetClusterAutoscalerAddonFuncMap returns the funcs of the cluster autoscaler addon manifest template
+func GetClusterAutoscalerAddonFuncMap(addon api.KubernetesAddon, cs *api.ContainerService) template.FuncMap {
+	funcMap := GetAddonFuncMap(addon)
+	funcMap["GetMode"] = func() string {
+		return addon.Mode
+	}
+	funcMap["GetClusterAutoscalerNodesConfig"] = func() string {
+		return api.GetClusterAutoscalerNodesConfig(addon, cs)
+	}
+	funcMap["GetVolumeMounts"] = func() string {
+		if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
+			return fmt.Sprintf("\n        - mountPath: /var/lib/waagent/\n          name: waagent\n          readOnly: true")
+		}
+		return ""
+	}
+	funcMap["GetVolumes"] = func() string {
+		if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
+			return fmt.Sprintf("\n      - hostPath:\n          path: /var/lib/waagent/\n        name: waagent")
+		}
+		return ""
+	}
+	funcMap["GetHostNetwork"] = func() string {
+		if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
+			return fmt.Sprintf("\n      hostNetwork: true")
+		}
+		return ""
+	}
+	funcMap["GetCloud"] = func() string {
+		cloudSpecConfig := cs.GetCloudSpecConfig()
+		return cloudSpecConfig.CloudName
+	}
+	funcMap["UseManagedIdentity"] = func() string {
+		if cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity {
+			return "true"
+		}
+		return "false"
+	}
+	return funcMap
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armtemplate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestGetFuncMap(t *testing.T) {
+	cs := &api.ContainerService{
+		Location: "westus2",
+		Properties: &api.Properties{
+			OrchestratorProfile: &api.OrchestratorProfile{
+				OrchestratorType: api.Kubernetes,
+				KubernetesConfig: &api.KubernetesConfig{},
+			},
+			MasterProfile: &api.MasterProfile{Count: 3, Subnet: "10.240.0.0/16"},
+			AgentPoolProfiles: []*api.AgentPoolProfile{
+				{Name: "agentpool1", Count: 2, OSType: api.Linux},
+				{Name: "agentpool2", Count: 2, OSType: api.Windows},
+			},
+		},
+	}
+	funcMap := GetFuncMap(cs)
+
+	if !funcMap["IsMultiMasterCluster"].(func() bool)() {
+		t.Errorf("expected a multi master cluster")
+	}
+	if actual := funcMap["GetLocation"].(func() string)(); actual != "westus2" {
+		t.Errorf("expected the location westus2, got %s", actual)
+	}
+	if actual := funcMap["GetWindowsMasterSubnetARMParam"].(func() string)(); actual != "',parameters('masterSubnet'),'" {
+		t.Errorf("expected the masterSubnet parameter, got %s", actual)
+	}
+	lbRules := funcMap["GetLBRules"].(func(string, []int) string)("master", []int{80, 443})
+	if !strings.Contains(lbRules, `"name": "LBRule80"`) || !strings.Contains(lbRules, `"name": "LBRule443"`) {
+		t.Errorf("expected an LB rule per port, got %s", lbRules)
+	}
+	if actual := strings.Count(funcMap["GetVNETSubnets"].(func(bool) string)(true), `"networkSecurityGroup":`); actual != 2 {
+		t.Errorf("expected a network security group per agent pool subnet, got %d", actual)
+	}
+	// the pod CIDRs of the Windows nodes follow the 3 masters and 2 Linux nodes
+	subnets := funcMap["GetKubernetesSubnets"].(func() string)()
+	if !strings.Contains(subnets, `"name": "podCIDR6"`) || !strings.Contains(subnets, `"name": "podCIDR7"`) {
+		t.Errorf("expected a pod CIDR per Windows node, got %s", subnets)
+	}
+}
+
+func TestGenerateConsecutiveIPsList(t *testing.T) {
+	ips, err := GenerateConsecutiveIPsList(3, "10.240.255.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"10.240.255.5", "10.240.255.6", "10.240.255.7"}; !reflect.DeepEqual(ips, expected) {
+		t.Errorf("expected %v, got %v", expected, ips)
+	}
+	if _, err := GenerateConsecutiveIPsList(10, "10.240.255.250"); err == nil {
+		t.Errorf("expected an error overflowing the fourth octet")
+	}
+}
+
+func TestGetAgentPoolLinkedTemplateText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/extensions/hello-world/v1/supported-orchestrators.json":
+			fmt.Fprint(w, `["Kubernetes"]`)
+		case "/extensions/hello-world/v1/template-link.json":
+			fmt.Fprint(w, `{"name": "EXTENSION_TARGET_VM_NAME_PREFIX", "count": "EXTENSION_LOOP_COUNT", "type": "EXTENSION_TARGET_VM_TYPE"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	profile := &api.AgentPoolProfile{Name: "agentpool1", AvailabilityProfile: api.VirtualMachineScaleSets}
+	extension := &api.ExtensionProfile{Name: "hello-world", Version: "v1", RootURL: server.URL + "/"}
+	text, err := GetAgentPoolLinkedTemplateText(profile, api.Kubernetes, extension, "single", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"name": "variables('agentpool1VMNamePrefix')", "count": 1, "type": "agent"}`
+	if text != expected {
+		t.Errorf("expected %s, got %s", expected, text)
+	}
+
+	if _, err := GetAgentPoolLinkedTemplateText(profile, api.DCOS, extension, "single", nil); err == nil {
+		t.Errorf("expected an error for an orchestrator the extension does not support")
+	}
+}
+
+func TestGetMasterLinkedTemplateTextFromSource(t *testing.T) {
+	root, err := ioutil.TempDir("", "extensions")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "extensions", "hello-world", "v1")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := map[string]string{
+		"supported-orchestrators.json": `["Kubernetes"]`,
+		"template-link.json":           `{"name": "EXTENSION_TARGET_VM_NAME_PREFIX", "type": "EXTENSION_TARGET_VM_TYPE"}`,
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the rootURL is unreachable, the files must come from the source
+	extension := &api.ExtensionProfile{Name: "hello-world", Version: "v1", RootURL: "http://127.0.0.1:0/"}
+	text, err := GetMasterLinkedTemplateText(api.Kubernetes, extension, "all", &agent.DirExtensionSource{Root: root})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"name": "variables('masterVMNamePrefix')", "type": "master"}`
+	if text != expected {
+		t.Errorf("expected %s, got %s", expected, text)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armtemplate
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidateDistro checks if the requested orchestrator type is supported on the requested Linux distro.
+func ValidateDistro(cs *api.ContainerService) bool {
+	// Check Master distro
+	if cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.Distro == api.RHEL &&
+		(cs.Properties.OrchestratorProfile.OrchestratorType != api.SwarmMode) {
+		log.Warnf("Orchestrator type %s not supported on RHEL Master", cs.Properties.OrchestratorProfile.OrchestratorType)
+		return false
+	}
+	// Check Agent distros
+	for _, agentProfile := range cs.Properties.AgentPoolProfiles {
+		if agentProfile.Distro == api.RHEL &&
+			(cs.Properties.OrchestratorProfile.OrchestratorType != api.SwarmMode) {
+			log.Warnf("Orchestrator type %s not supported on RHEL Agent", cs.Properties.OrchestratorProfile.OrchestratorType)
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateConsecutiveIPsList takes a starting IP address and returns a string slice of length "count" of subsequent, consecutive IP addresses
+func GenerateConsecutiveIPsList(count int, firstAddr string) ([]string, error) {
+	ipaddr := net.ParseIP(firstAddr).To4()
+	if ipaddr == nil {
+		return nil, errors.Errorf("IPAddr '%s' is an invalid IP address", firstAddr)
+	}
+	if int(ipaddr[3])+count >= 255 {
+		return nil, errors.Errorf("IPAddr '%s' + %d will overflow the fourth octet", firstAddr, count)
+	}
+	ret := make([]string, count)
+	for i := 0; i < count; i++ {
+		nextAddress := fmt.Sprintf("%d.%d.%d.%d", ipaddr[0], ipaddr[1], ipaddr[2], ipaddr[3]+byte(i))
+		ipaddr := net.ParseIP(nextAddress).To4()
+		if ipaddr == nil {
+			return nil, errors.Errorf("IPAddr '%s' is an invalid IP address", nextAddress)
+		}
+		ret[i] = nextAddress
+	}
+	return ret, nil
+}
+
+func getVNETAddressPrefixes(properties *api.Properties) string {
+	visitedSubnets := make(map[string]bool)
+	var buf bytes.Buffer
+	buf.WriteString(`"[variables('masterSubnet')]"`)
+	visitedSubnets[properties.MasterProfile.Subnet] = true
+	for _, profile := range properties.AgentPoolProfiles {
+		if _, ok := visitedSubnets[profile.Subnet]; !ok {
+			buf.WriteString(fmt.Sprintf(",\n            \"[variables('%sSubnet')]\"", profile.Name))
+		}
+	}
+	return buf.String()
+}
+
+func getVNETSubnetDependencies(properties *api.Properties) string {
+	agentString := `        "[concat('Microsoft.Network/networkSecurityGroups/', variables('%sNSGName'))]"`
+	var buf bytes.Buffer
+	for index, agentProfile := range properties.AgentPoolProfiles {
+		if index > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(fmt.Sprintf(agentString, agentProfile.Name))
+	}
+	return buf.String()
+}
+
+func getVNETSubnets(properties *api.Properties, addNSG bool) string {
+	masterString := `{
+            "name": "[variables('masterSubnetName')]",
+            "properties": {
+              "addressPrefix": "[variables('masterSubnet')]"
+            }
+          }`
+	agentString := `          {
+            "name": "[variables('%sSubnetName')]",
+            "properties": {
+              "addressPrefix": "[variables('%sSubnet')]"
+            }
+          }`
+	agentStringNSG := `          {
+            "name": "[variables('%sSubnetName')]",
+            "properties": {
+              "addressPrefix": "[variables('%sSubnet')]",
+              "networkSecurityGroup": {
+                "id": "[resourceId('Microsoft.Network/networkSecurityGroups', variables('%sNSGName'))]"
+              }
+            }
+          }`
+	var buf bytes.Buffer
+	buf.WriteString(masterString)
+	for _, agentProfile := range properties.AgentPoolProfiles {
+		buf.WriteString(",\n")
+		if addNSG {
+			buf.WriteString(fmt.Sprintf(agentStringNSG, agentProfile.Name, agentProfile.Name, agentProfile.Name))
+		} else {
+			buf.WriteString(fmt.Sprintf(agentString, agentProfile.Name, agentProfile.Name))
+		}
+
+	}
+	return buf.String()
+}
+
+func getLBRule(name string, port int) string {
+	return fmt.Sprintf(`	          {
+            "name": "LBRule%d",
+            "properties": {
+              "backendAddressPool": {
+                "id": "[concat(variables('%sLbID'), '/backendAddressPools/', variables('%sLbBackendPoolName'))]"
+              },
+              "backendPort": %d,
+              "enableFloatingIP": false,
+              "frontendIPConfiguration": {
+                "id": "[variables('%sLbIPConfigID')]"
+              },
+              "frontendPort": %d,
+              "idleTimeoutInMinutes": 5,
+              "loadDistribution": "Default",
+              "probe": {
+                "id": "[concat(variables('%sLbID'),'/probes/tcp%dProbe')]"
+              },
+              "protocol": "Tcp"
+            }
+          }`, port, name, name, port, name, port, name, port)
+}
+
+func getLBRules(name string, ports []int) string {
+	var buf bytes.Buffer
+	for index, port := range ports {
+		if index > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(getLBRule(name, port))
+	}
+	return buf.String()
+}
+
+func getProbe(port int) string {
+	return fmt.Sprintf(`          {
+            "name": "tcp%dProbe",
+            "properties": {
+              "intervalInSeconds": 5,
+              "numberOfProbes": 2,
+              "port": %d,
+              "protocol": "Tcp"
+            }
+          }`, port, port)
+}
+
+func getProbes(ports []int) string {
+	var buf bytes.Buffer
+	for index, port := range ports {
+		if index > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(getProbe(port))
+	}
+	return buf.String()
+}
+
+func getSecurityRule(port int, portIndex int) string {
+	// BaseLBPriority specifies the base lb priority.
+	BaseLBPriority := 200
+	return fmt.Sprintf(`          {
+            "name": "Allow_%d",
+            "properties": {
+              "access": "Allow",
+              "description": "Allow traffic from the Internet to port %d",
+              "destinationAddressPrefix": "*",
+              "destinationPortRange": "%d",
+              "direction": "Inbound",
+              "priority": %d,
+              "protocol": "*",
+              "sourceAddressPrefix": "Internet",
+              "sourcePortRange": "*"
+            }
+          }`, port, port, port, BaseLBPriority+portIndex)
+}
+
+func getSecurityRules(ports []int) string {
+	var buf bytes.Buffer
+	for index, port := range ports {
+		if index > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(getSecurityRule(port, index))
+	}
+	return buf.String()
+}
+
+func getDataDisks(a *api.AgentPoolProfile) string {
+	if !a.HasDisks() {
+		return ""
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\"dataDisks\": [\n")
+	dataDisks := `            {
+              "createOption": "Empty",
+              "diskSizeGB": "%d",
+              "lun": %d,
+              "caching": "ReadOnly",
+              "name": "[concat(variables('%sVMNamePrefix'), copyIndex(),'-datadisk%d')]",
+              "vhd": {
+                "uri": "[concat('http://',variables('storageAccountPrefixes')[mod(add(add(div(copyIndex(),variables('maxVMsPerStorageAccount')),variables('%sStorageAccountOffset')),variables('dataStorageAccountPrefixSeed')),variables('storageAccountPrefixesCount'))],variables('storageAccountPrefixes')[div(add(add(div(copyIndex(),variables('maxVMsPerStorageAccount')),variables('%sStorageAccountOffset')),variables('dataStorageAccountPrefixSeed')),variables('storageAccountPrefixesCount'))],variables('%sDataAccountName'),'.blob.core.windows.net/vhds/',variables('%sVMNamePrefix'),copyIndex(), '--datadisk%d.vhd')]"
+              }
+            }`
+	managedDataDisks := `            {
+              "diskSizeGB": "%d",
+              "lun": %d,
+              "caching": "ReadOnly",
+              "createOption": "Empty"
+            }`
+	for i, diskSize := range a.DiskSizesGB {
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		if a.StorageProfile == api.StorageAccount {
+			buf.WriteString(fmt.Sprintf(dataDisks, diskSize, i, a.Name, i, a.Name, a.Name, a.Name, a.Name, i))
+		} else if a.StorageProfile == api.ManagedDisks {
+			buf.WriteString(fmt.Sprintf(managedDataDisks, diskSize, i))
+		}
+	}
+	buf.WriteString("\n          ],")
+	return buf.String()
+}
+
+func getKubernetesSubnets(properties *api.Properties) string {
+	subnetString := `{
+            "name": "podCIDR%d",
+            "properties": {
+              "addressPrefix": "10.244.%d.0/24",
+              "networkSecurityGroup": {
+                "id": "[variables('nsgID')]"
+              },
+              "routeTable": {
+                "id": "[variables('routeTableID')]"
+              }
+            }
+          }`
+	var buf bytes.Buffer
+
+	cidrIndex := getKubernetesPodStartIndex(properties)
+	for _, agentProfile := range properties.AgentPoolProfiles {
+		if agentProfile.OSType == api.Windows {
+			for i := 0; i < agentProfile.Count; i++ {
+				buf.WriteString(",\n")
+				buf.WriteString(fmt.Sprintf(subnetString, cidrIndex, cidrIndex))
+				cidrIndex++
+			}
+		}
+	}
+	return buf.String()
+}
+
+func getKubernetesPodStartIndex(properties *api.Properties) int {
+	nodeCount := 0
+	nodeCount += properties.MasterProfile.Count
+	for _, agentProfile := range properties.AgentPoolProfiles {
+		if agentProfile.OSType != api.Windows {
+			nodeCount += agentProfile.Count
+		}
+	}
+
+	return nodeCount + 1
+}
+
+func getWindowsMasterSubnetARMParam(masterProfile *api.MasterProfile) string {
+	if masterProfile != nil && masterProfile.IsCustomVNET() {
+		return fmt.Sprintf("',parameters('vnetCidr'),'")
+	}
+	return fmt.Sprintf("',parameters('masterSubnet'),'")
+}
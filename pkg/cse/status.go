@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package cse parses the provisioning status reported by the Linux custom script extension and maps
+// its exit codes to errors.
+package cse
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/pkg/errors"
+)
+
+// StatusFilePath is where provision.sh writes its ProvisionStatus when it exits
+const StatusFilePath = "/var/log/azure/cluster-provision-status.json"
+
+// ProvisionStatus is the machine readable status written by provision.sh
+type ProvisionStatus struct {
+	ExitCode   int               `json:"exitCode"`
+	FailedStep string            `json:"failedStep,omitempty"`
+	StartTime  time.Time         `json:"startTime"`
+	EndTime    time.Time         `json:"endTime"`
+	Phases     []Phase           `json:"phases"`
+	Versions   map[string]string `json:"versions"`
+}
+
+// Phase is a provisioning step and how long it took
+type Phase struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Duration returns how long the phase took
+func (p Phase) Duration() time.Duration {
+	return time.Duration(p.DurationMs) * time.Millisecond
+}
+
+// ParseProvisionStatus parses the content of StatusFilePath
+func ParseProvisionStatus(data []byte) (*ProvisionStatus, error) {
+	status := &ProvisionStatus{}
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, errors.Wrap(err, "parsing provisioning status")
+	}
+	return status, nil
+}
+
+// Duration returns how long provisioning took
+func (s *ProvisionStatus) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// Err returns nil if provisioning succeeded and a *ProvisionError otherwise
+func (s *ProvisionStatus) Err() error {
+	if s.ExitCode == 0 {
+		return nil
+	}
+	return &ProvisionError{Step: s.FailedStep, ExitCode: agent.CSEExitCode(s.ExitCode)}
+}
+
+// ProvisionError is a failed provisioning run. It unwraps to its exit code so callers can match
+// specific failures with errors.Is(err, agent.CSEExitCodeOutboundConnFail)
+type ProvisionError struct {
+	// Step is the provisioning phase that failed, it is empty when the failure happened before provision.sh ran
+	Step     string
+	ExitCode agent.CSEExitCode
+}
+
+func (e *ProvisionError) Error() string {
+	if e.Step == "" {
+		return e.ExitCode.Error()
+	}
+	return fmt.Sprintf("provisioning step %s failed: %s", e.Step, e.ExitCode.Error())
+}
+
+// Unwrap returns the exit code of the failure
+func (e *ProvisionError) Unwrap() error {
+	return e.ExitCode
+}
+
+var exitStatusRe = regexp.MustCompile(`exit status[ =](\d+)`)
+
+// ErrorFromExitStatus maps a custom script extension failure message, e.g. "Enable failed: exit status 50",
+// to a *ProvisionError. It returns nil if the message does not contain an exit status. Use it when the
+// status file is not available, e.g. when the outbound connectivity check fails before provision.sh runs
+func ErrorFromExitStatus(message string) error {
+	m := exitStatusRe.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+	code, err := strconv.Atoi(m[1])
+	if err != nil || code == 0 {
+		return nil
+	}
+	return &ProvisionError{ExitCode: agent.CSEExitCode(code)}
+}
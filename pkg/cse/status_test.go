@@ -0,0 +1,90 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cse
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+)
+
+func TestParseProvisionStatus(t *testing.T) {
+	data := []byte(`{
+  "exitCode": 34,
+  "failedStep": "ensureKubelet",
+  "startTime": "2020-05-01T10:00:00Z",
+  "endTime": "2020-05-01T10:01:30Z",
+  "phases": [{"name":"prepareNode","durationMs":1500},{"name":"ensureKubelet","durationMs":60000}],
+  "versions": {
+    "kubernetes": "1.16.9",
+    "containerRuntime": "docker",
+    "moby": "3.0.11",
+    "containerd": "",
+    "os": "UBUNTU",
+    "kernel": "5.4.0-1025-azure"
+  }
+}`)
+	status, err := ParseProvisionStatus(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Duration() != 90*time.Second {
+		t.Errorf("expected a 90s duration, got %s", status.Duration())
+	}
+	if len(status.Phases) != 2 || status.Phases[1].Name != "ensureKubelet" || status.Phases[1].Duration() != time.Minute {
+		t.Errorf("unexpected phases %+v", status.Phases)
+	}
+	if status.Versions["kubernetes"] != "1.16.9" {
+		t.Errorf("expected kubernetes version 1.16.9, got %s", status.Versions["kubernetes"])
+	}
+
+	err = status.Err()
+	if !errors.Is(err, agent.CSEExitCodeKubeletStartFail) {
+		t.Fatalf("expected %v to match CSEExitCodeKubeletStartFail", err)
+	}
+	expected := "provisioning step ensureKubelet failed: kubelet could not be started by systemctl (ERR_KUBELET_START_FAIL, exit status 34)"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+	var provisionErr *ProvisionError
+	if !errors.As(err, &provisionErr) || provisionErr.Step != "ensureKubelet" {
+		t.Errorf("expected a *ProvisionError for step ensureKubelet, got %#v", err)
+	}
+}
+
+func TestParseProvisionStatusSucceeded(t *testing.T) {
+	status, err := ParseProvisionStatus([]byte(`{"exitCode": 0, "failedStep": "", "phases": []}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := status.Err(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if _, err := ParseProvisionStatus([]byte(`{"exitCode": `)); err == nil {
+		t.Errorf("expected an error for a truncated status file")
+	}
+}
+
+func TestErrorFromExitStatus(t *testing.T) {
+	err := ErrorFromExitStatus("Enable failed: failed to execute command: command terminated with exit status=50")
+	if !errors.Is(err, agent.CSEExitCodeOutboundConnFail) {
+		t.Fatalf("expected %v to match CSEExitCodeOutboundConnFail", err)
+	}
+	expected := "outbound connectivity to mcr.microsoft.com (gcr.azk8s.cn in Azure China) failed (ERR_OUTBOUND_CONN_FAIL, exit status 50)"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	if err := ErrorFromExitStatus("exit status 231"); err == nil || err.Error() != "unknown custom script extension exit code 231" {
+		t.Errorf("unexpected error for an unknown exit code: %v", err)
+	}
+	for _, message := range []string{"Enable succeeded", "exit status 0"} {
+		if err := ErrorFromExitStatus(message); err != nil {
+			t.Errorf("%s: expected no error, got %v", message, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package server exposes node bootstrapping generation over HTTP. Every endpoint takes an
+// agent.NodeBootstrappingConfiguration as its JSON request body and returns JSON. The format query parameter
+// selects FormatARM or FormatPlain, redact=true replaces the secrets with their fingerprints.
+// protectedSettings=true moves the secrets of the plain CSE command of CSECommandPath to its protected settings.
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CustomDataPath returns the customData payload
+	CustomDataPath = "/v1/nodebootstrapping/customdata"
+	// CSECommandPath returns the custom script extension command
+	CSECommandPath = "/v1/nodebootstrapping/csecmd"
+	// ParametersPath returns the parameters map the templates are rendered with
+	ParametersPath = "/v1/nodebootstrapping/parameters"
+	// VariablesPath returns the variables map the templates are rendered with
+	VariablesPath = "/v1/nodebootstrapping/variables"
+	// HealthzPath reports whether the server is up
+	HealthzPath = "/healthz"
+
+	// FormatARM selects ARM template expressions, the format of GetNodeBootstrappingPayload and GetNodeBootstrappingCmd
+	FormatARM = "arm"
+	// FormatPlain selects the plain customData document and the structured CSE command
+	FormatPlain = "plain"
+
+	// maxRequestBodyBytes caps the size of a NodeBootstrappingConfiguration request body
+	maxRequestBodyBytes = 4 << 20
+)
+
+// CustomDataResponse is the response of CustomDataPath
+type CustomDataResponse struct {
+	CustomData string `json:"customData"`
+	// ContentHash is the hex encoded SHA-256 of the payload, of the customData and the CSE command for the plain format
+	ContentHash string `json:"contentHash"`
+}
+
+// CSECommandResponse is the response of CSECommandPath, CSECommand is set for the arm format and CSE for the plain format
+type CSECommandResponse struct {
+	CSECommand string                      `json:"cseCmd,omitempty"`
+	CSE        *agent.NodeBootstrappingCSE `json:"cse,omitempty"`
+	// ContentHash is the hex encoded SHA-256 of the payload, of the customData and the CSE command for the plain format
+	// and of the CSE command with protected settings
+	ContentHash string `json:"contentHash"`
+}
+
+// ParametersResponse is the response of ParametersPath
+type ParametersResponse struct {
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// VariablesResponse is the response of VariablesPath
+type VariablesResponse struct {
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// ErrorResponse is returned with every non 2xx status code
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server is an http.Handler serving node bootstrapping generation
+type Server struct {
+	templateGenerator *agent.TemplateGenerator
+	mux               *http.ServeMux
+}
+
+// NewServer creates a new node bootstrapping server
+func NewServer() *Server {
+	s := &Server{
+		templateGenerator: agent.InitializeTemplateGenerator(),
+		mux:               http.NewServeMux(),
+	}
+	s.mux.HandleFunc(CustomDataPath, s.handleNodeBootstrapping(s.getCustomData))
+	s.mux.HandleFunc(CSECommandPath, s.handleNodeBootstrapping(s.getCSECommand))
+	s.mux.HandleFunc(ParametersPath, s.handleNodeBootstrapping(s.getParameters))
+	s.mux.HandleFunc(VariablesPath, s.handleNodeBootstrapping(s.getVariables))
+	s.mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// requestOptions are the query parameters of a request
+type requestOptions struct {
+	format            string
+	protectedSettings bool
+}
+
+// nodeBootstrappingFunc generates the response of an endpoint from a validated configuration and the request options
+type nodeBootstrappingFunc func(config *agent.NodeBootstrappingConfiguration, opts requestOptions) (interface{}, error)
+
+func (s *Server) handleNodeBootstrapping(fn nodeBootstrappingFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s is not allowed", r.Method))
+			return
+		}
+
+		opts := requestOptions{format: r.URL.Query().Get("format")}
+		if opts.format == "" {
+			opts.format = FormatARM
+		}
+		if opts.format != FormatARM && opts.format != FormatPlain {
+			writeError(w, http.StatusBadRequest, errors.Errorf("format must be %s or %s, got %s", FormatARM, FormatPlain, opts.format))
+			return
+		}
+
+		redact, err := parseBoolQuery(r, "redact")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if opts.protectedSettings, err = parseBoolQuery(r, "protectedSettings"); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if opts.protectedSettings && opts.format != FormatPlain {
+			writeError(w, http.StatusBadRequest, errors.Errorf("protectedSettings requires format %s", FormatPlain))
+			return
+		}
+
+		config := &agent.NodeBootstrappingConfiguration{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBodyBytes)).Decode(config); err != nil {
+			writeError(w, http.StatusBadRequest, errors.Wrap(err, "decoding node bootstrapping configuration"))
+			return
+		}
+		if err := config.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, errors.Wrap(err, "validating node bootstrapping configuration"))
+			return
+		}
+
+		if redact {
+			config.Redact()
+		}
+
+		resp, err := fn(config, opts)
+		if err != nil {
+			log.Errorf("%s: %v", r.URL.Path, err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *Server) getCustomData(config *agent.NodeBootstrappingConfiguration, opts requestOptions) (interface{}, error) {
+	if opts.format == FormatPlain {
+		nodeBootstrapping, err := s.templateGenerator.GetNodeBootstrapping(config)
+		if err != nil {
+			return nil, err
+		}
+		return &CustomDataResponse{CustomData: nodeBootstrapping.CustomData, ContentHash: nodeBootstrapping.ContentHash}, nil
+	}
+	customData, err := s.templateGenerator.GetNodeBootstrappingPayloadFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomDataResponse{CustomData: customData, ContentHash: agent.HashContent([]byte(customData))}, nil
+}
+
+func (s *Server) getCSECommand(config *agent.NodeBootstrappingConfiguration, opts requestOptions) (interface{}, error) {
+	if opts.protectedSettings {
+		cse, err := s.templateGenerator.GetNodeBootstrappingCmdWithProtectedSettings(config)
+		if err != nil {
+			return nil, err
+		}
+		cseJSON, err := json.Marshal(cse)
+		if err != nil {
+			return nil, errors.Wrap(err, "hashing the CSE command")
+		}
+		return &CSECommandResponse{CSE: cse, ContentHash: agent.HashContent(cseJSON)}, nil
+	}
+	if opts.format == FormatPlain {
+		nodeBootstrapping, err := s.templateGenerator.GetNodeBootstrapping(config)
+		if err != nil {
+			return nil, err
+		}
+		return &CSECommandResponse{CSE: nodeBootstrapping.CSE, ContentHash: nodeBootstrapping.ContentHash}, nil
+	}
+	cseCmd, err := s.templateGenerator.GetNodeBootstrappingCmdFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &CSECommandResponse{CSECommand: cseCmd, ContentHash: agent.HashContent([]byte(cseCmd))}, nil
+}
+
+func (s *Server) getParameters(config *agent.NodeBootstrappingConfiguration, opts requestOptions) (interface{}, error) {
+	parameters, err := s.templateGenerator.GetNodeBootstrappingParameters(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ParametersResponse{Parameters: parameters}, nil
+}
+
+func (s *Server) getVariables(config *agent.NodeBootstrappingConfiguration, opts requestOptions) (interface{}, error) {
+	variables, err := s.templateGenerator.GetNodeBootstrappingVariables(config)
+	if err != nil {
+		return nil, err
+	}
+	return &VariablesResponse{Variables: variables}, nil
+}
+
+// parseBoolQuery returns the boolean query parameter name of the request, false if it is not set
+func parseBoolQuery(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.Errorf("%s must be true or false, got %s", name, v)
+	}
+	return b, nil
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("writing response: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/agentbaker/pkg/agent"
+)
+
+func loadConfig(t *testing.T) []byte {
+	b, err := ioutil.ReadFile("testdata/nodebootstrappingconfiguration.json")
+	if err != nil {
+		t.Fatalf("reading test configuration: %v", err)
+	}
+	return b
+}
+
+func post(t *testing.T, ts *httptest.Server, path string, body []byte, v interface{}) int {
+	resp, err := http.Post(ts.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("POST %s: expected Content-Type application/json, got %s", path, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("POST %s: decoding response: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestCustomData(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+	config := loadConfig(t)
+
+	arm := &CustomDataResponse{}
+	if status := post(t, ts, CustomDataPath, config, arm); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if !strings.HasPrefix(arm.CustomData, "[base64(concat('") {
+		t.Errorf("expected an ARM customData expression, got %.50s", arm.CustomData)
+	}
+
+	plain := &CustomDataResponse{}
+	if status := post(t, ts, CustomDataPath+"?format=plain", config, plain); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if !strings.HasPrefix(plain.CustomData, "#cloud-config") {
+		t.Errorf("expected a cloud-init document, got %.50s", plain.CustomData)
+	}
+
+	again := &CustomDataResponse{}
+	post(t, ts, CustomDataPath+"?format=plain", config, again)
+	if plain.ContentHash == "" || again.ContentHash != plain.ContentHash || arm.ContentHash == plain.ContentHash {
+		t.Errorf("expected identical requests to return the same content hash, got %q and %q", plain.ContentHash, again.ContentHash)
+	}
+}
+
+func TestCSECommand(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+	config := loadConfig(t)
+
+	arm := &CSECommandResponse{}
+	if status := post(t, ts, CSECommandPath, config, arm); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if !strings.Contains(arm.CSECommand, "TENANT_ID=tenant ") || arm.CSE != nil {
+		t.Errorf("unexpected arm response %+v", arm)
+	}
+
+	plain := &CSECommandResponse{}
+	if status := post(t, ts, CSECommandPath+"?format=plain", config, plain); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if plain.CSE == nil || plain.CSE.Environment["TENANT_ID"] != "tenant" || !strings.Contains(plain.CSE.Command, "provision.sh") {
+		t.Errorf("unexpected plain response %+v", plain)
+	}
+
+	protected := &CSECommandResponse{}
+	if status := post(t, ts, CSECommandPath+"?format=plain&protectedSettings=true", config, protected); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	secret := plain.CSE.Environment["SERVICE_PRINCIPAL_CLIENT_SECRET"]
+	if protected.CSE == nil || protected.CSE.ProtectedSettings["SERVICE_PRINCIPAL_CLIENT_SECRET"] != secret || protected.CSE.ProtectedSettingsFile == nil {
+		t.Fatalf("expected the service principal secret in the protected settings, got %+v", protected.CSE)
+	}
+	if _, ok := protected.CSE.Environment["SERVICE_PRINCIPAL_CLIENT_SECRET"]; ok || protected.ContentHash == "" {
+		t.Errorf("expected the secret to be moved out of the environment, got %+v", protected)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+	config := loadConfig(t)
+
+	plain := &CSECommandResponse{}
+	if status := post(t, ts, CSECommandPath+"?format=plain", config, plain); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	redacted := &CSECommandResponse{}
+	if status := post(t, ts, CSECommandPath+"?format=plain&redact=true", config, redacted); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	secret := plain.CSE.Environment["SERVICE_PRINCIPAL_CLIENT_SECRET"]
+	if secret == "" {
+		t.Fatalf("expected a service principal secret, got %+v", plain.CSE.Environment)
+	}
+	if actual := redacted.CSE.Environment["SERVICE_PRINCIPAL_CLIENT_SECRET"]; actual != agent.Fingerprint(secret) {
+		t.Errorf("expected the secret to be replaced with %s, got %s", agent.Fingerprint(secret), actual)
+	}
+	if redacted.CSE.Environment["TENANT_ID"] != plain.CSE.Environment["TENANT_ID"] {
+		t.Errorf("expected non secret variables to be kept")
+	}
+}
+
+func TestParametersAndVariables(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+	config := loadConfig(t)
+
+	parameters := &ParametersResponse{}
+	if status := post(t, ts, ParametersPath, config, parameters); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if _, ok := parameters.Parameters["kubernetesVersion"]; !ok {
+		t.Errorf("expected a kubernetesVersion parameter, got %v", parameters.Parameters)
+	}
+
+	variables := &VariablesResponse{}
+	if status := post(t, ts, VariablesPath, config, variables); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	for _, k := range []string{"cloudInitData", "tenantID", "labelResourceGroup"} {
+		if _, ok := variables.Variables[k]; !ok {
+			t.Errorf("expected a %s variable", k)
+		}
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+
+	cases := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"malformed json", CustomDataPath, "{"},
+		{"missing agent pool profile", CSECommandPath, `{"kubernetesConfig": {}}`},
+		{"unknown format", CustomDataPath + "?format=xml", string(loadConfig(t))},
+		{"invalid redact", CustomDataPath + "?redact=maybe", string(loadConfig(t))},
+		{"protected settings of the arm format", CSECommandPath + "?protectedSettings=true", string(loadConfig(t))},
+	}
+	for _, c := range cases {
+		errResp := &ErrorResponse{}
+		if status := post(t, ts, c.path, []byte(c.body), errResp); status != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", c.name, status)
+		}
+		if errResp.Error == "" {
+			t.Errorf("%s: expected an error message", c.name)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + CustomDataPath)
+	if err != nil {
+		t.Fatalf("GET %s: %v", CustomDataPath, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// OSSKU is the OS a VHD is built from
+type OSSKU string
+
+const (
+	// OSSKUUbuntu is built from the Canonical UbuntuServer image of UbuntuRelease
+	OSSKUUbuntu OSSKU = "Ubuntu"
+	// OSSKUWindows is built from the Windows Server 2019 Datacenter Core image
+	OSSKUWindows OSSKU = "Windows"
+)
+
+// HyperVGeneration is the Hyper-V generation of the VM a VHD boots
+type HyperVGeneration string
+
+const (
+	// HyperVGenerationV1 boots with BIOS
+	HyperVGenerationV1 HyperVGeneration = "V1"
+	// HyperVGenerationV2 boots with UEFI, only Ubuntu 18.04 has a Gen2 image
+	HyperVGenerationV2 HyperVGeneration = "V2"
+)
+
+// Storage is where packer captures a VHD
+type Storage string
+
+const (
+	// StorageClassic captures the VHD to a storage account, it is copied from there to the classic storage account
+	// the marketplace publishes from
+	StorageClassic Storage = "classic"
+	// StorageSharedImageGallery captures a managed image and publishes it as a version of a Shared Image Gallery image
+	StorageSharedImageGallery Storage = "sig"
+)
+
+// ubuntuReleases are the Ubuntu releases VHDs are built from
+var ubuntuReleases = map[string]bool{"16.04": true, "18.04": true}
+
+var (
+	// buildFilePathRe matches the upload name and the destination of a Linux file, PACKER_FILES separates them
+	// with colons and spaces
+	buildFilePathRe = regexp.MustCompile(`^[^\s:]+$`)
+	// buildFileModeRe matches an octal file mode
+	buildFileModeRe = regexp.MustCompile(`^[0-7]{3,4}$`)
+	// windowsPathRe matches an absolute Windows path
+	windowsPathRe = regexp.MustCompile(`^[a-zA-Z]:\\`)
+	// imageVersionRe matches the version of a Shared Image Gallery image
+	imageVersionRe = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+)
+
+// BuildSpec describes a VHD build, PackerTemplate renders the packer template building it.
+// vhdbuilder/packer/vhd-build-spec.yaml and windows-vhd-build-spec.yaml are the specs of the published VHDs
+type BuildSpec struct {
+	OSSKU OSSKU `json:"osSKU" yaml:"osSKU"`
+	// UbuntuRelease is 16.04 or 18.04, Windows has none
+	UbuntuRelease    string           `json:"ubuntuRelease,omitempty" yaml:"ubuntuRelease,omitempty"`
+	HyperVGeneration HyperVGeneration `json:"hyperVGeneration" yaml:"hyperVGeneration"`
+	Storage          Storage          `json:"storage" yaml:"storage"`
+	// SharedImageGallery is the image a VHD captured to StorageSharedImageGallery is published as
+	SharedImageGallery *SharedImageGallery `json:"sharedImageGallery,omitempty" yaml:"sharedImageGallery,omitempty"`
+	// Files are uploaded to the VM the VHD is built on
+	Files []*BuildFile `json:"files" yaml:"files"`
+}
+
+// SharedImageGallery is the Shared Image Gallery image a VHD is published as
+type SharedImageGallery struct {
+	ResourceGroup string `json:"resourceGroup" yaml:"resourceGroup"`
+	GalleryName   string `json:"galleryName" yaml:"galleryName"`
+	ImageName     string `json:"imageName" yaml:"imageName"`
+	// ImageVersion is a version like 2020.3.24
+	ImageVersion string `json:"imageVersion" yaml:"imageVersion"`
+	// ReplicationRegions are replicated to in addition to the region the VHD is built in
+	ReplicationRegions []string `json:"replicationRegions,omitempty" yaml:"replicationRegions,omitempty"`
+}
+
+// BuildFile is a file of the repository packer uploads to the VM a VHD is built on
+type BuildFile struct {
+	// Source is the path of the file relative to the root of the repository
+	Source string `json:"source" yaml:"source"`
+	// Name is the name of a Linux file in /home/packer, the base name of Source by default
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Destination is the path copyPackerFiles copies a Linux file to and the path a Windows file is uploaded to.
+	// A Linux file without Destination is only uploaded, e.g. a script of the build
+	Destination string `json:"destination,omitempty" yaml:"destination,omitempty"`
+	// Mode is the octal mode of the Destination of a Linux file
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// UbuntuRelease limits the file to a release, e.g. the sshd_config of 16.04
+	UbuntuRelease string `json:"ubuntuRelease,omitempty" yaml:"ubuntuRelease,omitempty"`
+}
+
+// UploadPath returns the path a Linux file is uploaded to
+func (f *BuildFile) UploadPath() string {
+	if f.Name != "" {
+		return packerHome + f.Name
+	}
+	return packerHome + path.Base(f.Source)
+}
+
+// LoadBuildSpec reads a JSON or YAML build spec
+func LoadBuildSpec(path string) (*BuildSpec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading build spec")
+	}
+	return ParseBuildSpec(b)
+}
+
+// ParseBuildSpec parses a JSON or YAML build spec, unknown fields are an error. The spec is not validated, the
+// caller may override fields of it first
+func ParseBuildSpec(data []byte) (*BuildSpec, error) {
+	s := &BuildSpec{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, errors.Wrap(err, "parsing build spec")
+	}
+	return s, nil
+}
+
+// Validate checks that the OS, Hyper-V generation and storage of the spec can be built, and that its files are
+// uploaded to and copied to unique paths
+func (s *BuildSpec) Validate() error {
+	switch s.OSSKU {
+	case OSSKUUbuntu:
+		if !ubuntuReleases[s.UbuntuRelease] {
+			return errors.Errorf(`build spec has ubuntuRelease "%s", expected 16.04 or 18.04`, s.UbuntuRelease)
+		}
+	case OSSKUWindows:
+		if s.UbuntuRelease != "" {
+			return errors.New("build spec of Windows must not have an ubuntuRelease")
+		}
+	default:
+		return errors.Errorf(`build spec has osSKU "%s", expected %s or %s`, s.OSSKU, OSSKUUbuntu, OSSKUWindows)
+	}
+
+	switch s.HyperVGeneration {
+	case HyperVGenerationV1:
+	case HyperVGenerationV2:
+		if s.OSSKU != OSSKUUbuntu || s.UbuntuRelease != "18.04" {
+			return errors.Errorf("build spec has hyperVGeneration %s, which only Ubuntu 18.04 supports", s.HyperVGeneration)
+		}
+	default:
+		return errors.Errorf(`build spec has hyperVGeneration "%s", expected %s or %s`, s.HyperVGeneration, HyperVGenerationV1, HyperVGenerationV2)
+	}
+
+	switch s.Storage {
+	case StorageClassic:
+		if s.SharedImageGallery != nil {
+			return errors.Errorf("build spec with storage %s must not have a sharedImageGallery", s.Storage)
+		}
+	case StorageSharedImageGallery:
+		if err := s.SharedImageGallery.validate(); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf(`build spec has storage "%s", expected %s or %s`, s.Storage, StorageClassic, StorageSharedImageGallery)
+	}
+
+	return s.validateFiles()
+}
+
+func (g *SharedImageGallery) validate() error {
+	switch {
+	case g == nil:
+		return errors.Errorf("build spec with storage %s has no sharedImageGallery", StorageSharedImageGallery)
+	case g.ResourceGroup == "" || g.GalleryName == "" || g.ImageName == "":
+		return errors.New("build spec sharedImageGallery must have a resourceGroup, a galleryName and an imageName")
+	case !imageVersionRe.MatchString(g.ImageVersion):
+		return errors.Errorf(`build spec sharedImageGallery has imageVersion "%s", expected <major>.<minor>.<patch>`, g.ImageVersion)
+	}
+	return nil
+}
+
+func (s *BuildSpec) validateFiles() error {
+	uploads, destinations := map[string]bool{}, map[string]bool{}
+	for i, f := range s.Files {
+		if f == nil || f.Source == "" {
+			return errors.Errorf("build spec files[%d] has no source", i)
+		}
+		if f.UbuntuRelease != "" && (s.OSSKU != OSSKUUbuntu || !ubuntuReleases[f.UbuntuRelease]) {
+			return errors.Errorf(`build spec file %s has ubuntuRelease "%s", expected 16.04 or 18.04 of Ubuntu`, f.Source, f.UbuntuRelease)
+		}
+		if f.UbuntuRelease != "" && f.UbuntuRelease != s.UbuntuRelease {
+			continue
+		}
+		if s.OSSKU == OSSKUWindows {
+			switch {
+			case !windowsPathRe.MatchString(f.Destination):
+				return errors.Errorf(`build spec file %s has destination "%s", expected an absolute Windows path`, f.Source, f.Destination)
+			case f.Name != "" || f.Mode != "":
+				return errors.Errorf("build spec file %s of Windows must not have a name or a mode", f.Source)
+			case destinations[strings.ToLower(f.Destination)]:
+				return errors.Errorf("build spec has destination %s twice", f.Destination)
+			}
+			destinations[strings.ToLower(f.Destination)] = true
+			continue
+		}
+
+		upload := f.UploadPath()
+		switch {
+		case !buildFilePathRe.MatchString(upload):
+			return errors.Errorf(`build spec file %s has name "%s", which must not contain spaces or colons`, f.Source, path.Base(upload))
+		case uploads[upload]:
+			return errors.Errorf("build spec uploads %s twice", upload)
+		}
+		uploads[upload] = true
+		if f.Destination == "" {
+			if f.Mode != "" {
+				return errors.Errorf("build spec file %s has a mode but no destination", f.Source)
+			}
+			continue
+		}
+		switch {
+		case !strings.HasPrefix(f.Destination, "/") || !buildFilePathRe.MatchString(f.Destination):
+			return errors.Errorf(`build spec file %s has destination "%s", expected an absolute path without spaces or colons`, f.Source, f.Destination)
+		case !buildFileModeRe.MatchString(f.Mode):
+			return errors.Errorf(`build spec file %s has mode "%s", expected an octal mode like 644`, f.Source, f.Mode)
+		case destinations[f.Destination]:
+			return errors.Errorf("build spec has destination %s twice", f.Destination)
+		}
+		destinations[f.Destination] = true
+	}
+	return nil
+}
+
+// BuildFiles returns the files uploaded for the Ubuntu release of the spec
+func (s *BuildSpec) BuildFiles() []*BuildFile {
+	files := []*BuildFile{}
+	for _, f := range s.Files {
+		if f.UbuntuRelease == "" || f.UbuntuRelease == s.UbuntuRelease {
+			files = append(files, f)
+		}
+	}
+	return files
+}
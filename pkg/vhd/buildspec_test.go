@@ -0,0 +1,132 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPackerTemplatesAreRendered checks that the checked in packer templates are rendered from the build specs
+// of the published VHDs and that every file they upload exists
+func TestPackerTemplatesAreRendered(t *testing.T) {
+	for spec, template := range map[string]string{
+		"vhd-build-spec.yaml":         "vhd-image-builder.json",
+		"windows-vhd-build-spec.yaml": "windows-vhd-builder.json",
+	} {
+		s, err := LoadBuildSpec(filepath.Join("../../vhdbuilder/packer", spec))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rendered, err := RenderPackerTemplate(s)
+		if err != nil {
+			t.Fatalf("unexpected error rendering %s: %v", spec, err)
+		}
+		expected, err := ioutil.ReadFile(filepath.Join("../../vhdbuilder/packer", template))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(rendered, expected) {
+			t.Errorf("%s is not rendered from %s, run: baker vhd template vhdbuilder/packer/%s -o vhdbuilder/packer/%s", template, spec, spec, template)
+		}
+
+		pt, _ := s.PackerTemplate()
+		for _, source := range pt.Sources() {
+			if _, err := os.Stat(filepath.Join("../..", source)); err != nil {
+				t.Errorf("%s uploads %s: %v", spec, source, err)
+			}
+		}
+	}
+}
+
+func TestPackerTemplate(t *testing.T) {
+	s, err := LoadBuildSpec("../../vhdbuilder/packer/vhd-build-spec.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.UbuntuRelease = "16.04"
+	pt, err := s.PackerTemplate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := pt.Builders[0]; b.ImageSKU != "16.04-LTS" || b.StorageAccount == "" || b.SharedImageGalleryDestination != nil {
+		t.Errorf("expected a classic build of 16.04-LTS, got %+v", b)
+	}
+	// 16.04 uploads the sshd_config of 16.04 and copyPackerFiles copies it
+	sources := strings.Join(pt.Sources(), " ")
+	if !strings.Contains(sources, "sshd_config_1604") || strings.Contains(sources, "sshd_config ") {
+		t.Errorf("expected only the sshd_config of 16.04 to be uploaded, got %s", sources)
+	}
+	if install := pt.Provisioners[len(pt.Provisioners)-3].Inline[0]; !strings.Contains(install, "/home/packer/sshd_config_1604:/etc/ssh/sshd_config:644 ") {
+		t.Errorf("expected PACKER_FILES to copy the sshd_config of 16.04, got %s", install)
+	}
+
+	s.UbuntuRelease = "18.04"
+	s.HyperVGeneration = HyperVGenerationV2
+	s.Storage = StorageSharedImageGallery
+	s.SharedImageGallery = &SharedImageGallery{ResourceGroup: "aksimages", GalleryName: "aks", ImageName: "ubuntu-1804-gen2", ImageVersion: "2020.3.24"}
+	if pt, err = s.PackerTemplate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := pt.Builders[0]
+	if b.ImageSKU != "18_04-lts-gen2" || b.StorageAccount != "" || b.ManagedImageName == "" || b.SharedImageGalleryDestination.ImageName != "ubuntu-1804-gen2" {
+		t.Errorf("expected a Shared Image Gallery build of 18_04-lts-gen2, got %+v", b)
+	}
+}
+
+func TestBuildSpecValidate(t *testing.T) {
+	gallery := &SharedImageGallery{ResourceGroup: "aksimages", GalleryName: "aks", ImageName: "ubuntu", ImageVersion: "2020.3.24"}
+	for name, c := range map[string]struct {
+		spec     BuildSpec
+		expected string
+	}{
+		"os sku":            {BuildSpec{OSSKU: "CentOS"}, `osSKU "CentOS"`},
+		"ubuntu release":    {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "20.04"}, `ubuntuRelease "20.04"`},
+		"windows release":   {BuildSpec{OSSKU: OSSKUWindows, UbuntuRelease: "18.04"}, "must not have an ubuntuRelease"},
+		"generation":        {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "18.04", HyperVGeneration: "V3"}, `hyperVGeneration "V3"`},
+		"gen2 of 16.04":     {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "16.04", HyperVGeneration: HyperVGenerationV2}, "only Ubuntu 18.04"},
+		"gen2 of windows":   {BuildSpec{OSSKU: OSSKUWindows, HyperVGeneration: HyperVGenerationV2}, "only Ubuntu 18.04"},
+		"storage":           {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "18.04", HyperVGeneration: HyperVGenerationV1, Storage: "blob"}, `storage "blob"`},
+		"no gallery":        {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "18.04", HyperVGeneration: HyperVGenerationV1, Storage: StorageSharedImageGallery}, "has no sharedImageGallery"},
+		"classic gallery":   {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "18.04", HyperVGeneration: HyperVGenerationV1, Storage: StorageClassic, SharedImageGallery: gallery}, "must not have a sharedImageGallery"},
+		"image version":     {BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "18.04", HyperVGeneration: HyperVGenerationV1, Storage: StorageSharedImageGallery, SharedImageGallery: &SharedImageGallery{ResourceGroup: "aksimages", GalleryName: "aks", ImageName: "ubuntu", ImageVersion: "latest"}}, `imageVersion "latest"`},
+		"no source":         {linuxBuildSpec(&BuildFile{Destination: "/etc/issue", Mode: "644"}), "files[0] has no source"},
+		"upload twice":      {linuxBuildSpec(&BuildFile{Source: "a/etc-issue"}, &BuildFile{Source: "b/etc-issue"}), "uploads /home/packer/etc-issue twice"},
+		"name with space":   {linuxBuildSpec(&BuildFile{Source: "etc-issue", Name: "etc issue"}), "must not contain spaces or colons"},
+		"relative":          {linuxBuildSpec(&BuildFile{Source: "etc-issue", Destination: "etc/issue", Mode: "644"}), `destination "etc/issue"`},
+		"mode":              {linuxBuildSpec(&BuildFile{Source: "etc-issue", Destination: "/etc/issue", Mode: "rw"}), `mode "rw"`},
+		"mode only":         {linuxBuildSpec(&BuildFile{Source: "etc-issue", Mode: "644"}), "has a mode but no destination"},
+		"destination twice": {linuxBuildSpec(&BuildFile{Source: "a", Destination: "/etc/issue", Mode: "644"}, &BuildFile{Source: "b", Destination: "/etc/issue", Mode: "644"}), "destination /etc/issue twice"},
+		"file release":      {linuxBuildSpec(&BuildFile{Source: "sshd_config", UbuntuRelease: "14.04"}), `ubuntuRelease "14.04"`},
+		"windows path":      {BuildSpec{OSSKU: OSSKUWindows, HyperVGeneration: HyperVGenerationV1, Storage: StorageClassic, Files: []*BuildFile{{Source: "a.ps1", Destination: "/tmp/a.ps1"}}}, "expected an absolute Windows path"},
+		"windows mode":      {BuildSpec{OSSKU: OSSKUWindows, HyperVGeneration: HyperVGenerationV1, Storage: StorageClassic, Files: []*BuildFile{{Source: "a.ps1", Destination: `c:\a.ps1`, Mode: "644"}}}, "must not have a name or a mode"},
+	} {
+		if err := c.spec.Validate(); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, c.expected, err)
+		}
+	}
+
+	// files of another release do not conflict
+	s := linuxBuildSpec(
+		&BuildFile{Source: "sshd_config", Destination: "/etc/ssh/sshd_config", Mode: "644", UbuntuRelease: "18.04"},
+		&BuildFile{Source: "sshd_config_1604", Destination: "/etc/ssh/sshd_config", Mode: "644", UbuntuRelease: "16.04"},
+	)
+	if err := s.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseBuildSpec(t *testing.T) {
+	if _, err := ParseBuildSpec([]byte("osSKU: Ubuntu\nubuntuVersion: \"18.04\"\n")); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
+
+func linuxBuildSpec(files ...*BuildFile) BuildSpec {
+	return BuildSpec{OSSKU: OSSKUUbuntu, UbuntuRelease: "18.04", HyperVGeneration: HyperVGenerationV1, Storage: StorageClassic, Files: files}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"sort"
+	"strconv"
+	"unicode"
+)
+
+// ReleaseNotesKernel is the kind of the change of the kernel, release notes entries never have it
+const ReleaseNotesKernel ReleaseNotesEntryKind = "kernel"
+
+// ReleaseNotesChangeType is how a component changed between two VHD builds
+type ReleaseNotesChangeType string
+
+const (
+	// ReleaseNotesAdded is a component only the new VHD has
+	ReleaseNotesAdded ReleaseNotesChangeType = "added"
+	// ReleaseNotesRemoved is a component only the old VHD has
+	ReleaseNotesRemoved ReleaseNotesChangeType = "removed"
+	// ReleaseNotesUpgraded is a component whose newest version is newer on the new VHD
+	ReleaseNotesUpgraded ReleaseNotesChangeType = "upgraded"
+	// ReleaseNotesDowngraded is a component whose newest version is older on the new VHD
+	ReleaseNotesDowngraded ReleaseNotesChangeType = "downgraded"
+	// ReleaseNotesChanged is a component with the same newest version but other versions added or removed, or a
+	// component which got or lost a pinned version
+	ReleaseNotesChanged ReleaseNotesChangeType = "changed"
+)
+
+// ReleaseNotesChange is a component whose versions differ between two VHD builds
+type ReleaseNotesChange struct {
+	Type ReleaseNotesChangeType `json:"type"`
+	Kind ReleaseNotesEntryKind  `json:"kind"`
+	Name string                 `json:"name"`
+	// OldVersions and NewVersions are sorted oldest first, a component without a pinned version has the version ""
+	OldVersions []string `json:"oldVersions"`
+	NewVersions []string `json:"newVersions"`
+}
+
+type releaseNotesKey struct {
+	kind ReleaseNotesEntryKind
+	name string
+}
+
+// DiffReleaseNotes returns the components added, removed or changed from the VHD build of the release notes from to
+// the one of to, the kernel included, ordered by kind and name
+func DiffReleaseNotes(from, to *ReleaseNotes) []*ReleaseNotesChange {
+	versions := func(r *ReleaseNotes) map[releaseNotesKey]map[string]bool {
+		m := map[releaseNotesKey]map[string]bool{}
+		add := func(key releaseNotesKey, version string) {
+			if m[key] == nil {
+				m[key] = map[string]bool{}
+			}
+			m[key][version] = true
+		}
+		for _, e := range r.Entries {
+			add(releaseNotesKey{e.Kind, e.Name}, e.Version)
+		}
+		if r.Kernel != "" {
+			add(releaseNotesKey{ReleaseNotesKernel, r.OS}, r.Kernel)
+		}
+		return m
+	}
+	old, updated := versions(from), versions(to)
+
+	keys := []releaseNotesKey{}
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range updated {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	// the kernel first, then the sections of the release notes
+	order := map[ReleaseNotesEntryKind]int{ReleaseNotesKernel: 0}
+	for i, s := range releaseNotesSections {
+		order[s.kind] = i + 1
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return order[keys[i].kind] < order[keys[j].kind]
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	changes := []*ReleaseNotesChange{}
+	for _, key := range keys {
+		c := &ReleaseNotesChange{Kind: key.kind, Name: key.name, OldVersions: sortedVersions(old[key]), NewVersions: sortedVersions(updated[key])}
+		switch {
+		case len(c.OldVersions) == 0:
+			c.Type = ReleaseNotesAdded
+		case len(c.NewVersions) == 0:
+			c.Type = ReleaseNotesRemoved
+		default:
+			if equalVersions(c.OldVersions, c.NewVersions) {
+				continue
+			}
+			newest, oldest := c.NewVersions[len(c.NewVersions)-1], c.OldVersions[len(c.OldVersions)-1]
+			switch cmp := CompareVersions(newest, oldest); {
+			case newest == "" || oldest == "":
+				// a version was pinned or unpinned
+				c.Type = ReleaseNotesChanged
+			case cmp > 0:
+				c.Type = ReleaseNotesUpgraded
+			case cmp < 0:
+				c.Type = ReleaseNotesDowngraded
+			default:
+				c.Type = ReleaseNotesChanged
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+func sortedVersions(set map[string]bool) []string {
+	versions := make([]string, 0, len(set))
+	for v := range set {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+func equalVersions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareVersions compares two versions of a component as -1, 0 or 1. Runs of digits compare as numbers and
+// everything else as strings, which orders the versions of the release notes whether they are semantic versions
+// or not, e.g. 1.0.9 before 1.0.10 and 5.0.0-1032-azure before 5.0.0-1036-azure
+func CompareVersions(a, b string) int {
+	for a != "" && b != "" {
+		ta, ra := versionToken(a)
+		tb, rb := versionToken(b)
+		na, errA := strconv.ParseUint(ta, 10, 64)
+		nb, errB := strconv.ParseUint(tb, 10, 64)
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && ta != tb:
+			if ta < tb {
+				return -1
+			}
+			return 1
+		}
+		a, b = ra, rb
+	}
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// versionToken splits the leading run of digits or of non-digits off a version
+func versionToken(version string) (string, string) {
+	digits := unicode.IsDigit(rune(version[0]))
+	for i, r := range version {
+		if unicode.IsDigit(r) != digits {
+			return version[:i], version[i:]
+		}
+	}
+	return version, ""
+}
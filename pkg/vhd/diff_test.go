@@ -0,0 +1,74 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffReleaseNotes(t *testing.T) {
+	from, to := loadReleaseNotes(t, "1804/2020.03.11.txt"), loadReleaseNotes(t, "1804/2020.03.24.txt")
+	changes := DiffReleaseNotes(from, to)
+	expected := []*ReleaseNotesChange{{
+		Type:        ReleaseNotesUpgraded,
+		Kind:        ReleaseNotesContainerImage,
+		Name:        "mcr.microsoft.com/oss/kubernetes/autoscaler/cluster-proportional-autoscaler",
+		OldVersions: []string{"1.3.0", "1.3.0_v0.0.5"},
+		NewVersions: []string{"1.3.0", "1.3.0_v0.0.5", "1.7.1"},
+	}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected[0], changes)
+	}
+	if changes := DiffReleaseNotes(to, to); len(changes) != 0 {
+		t.Errorf("expected no changes between the same release notes, got %d", len(changes))
+	}
+
+	from = &ReleaseNotes{OS: "Linux", Kernel: "5.0.0-1032-azure", Entries: []*ReleaseNotesEntry{
+		{Kind: ReleaseNotesPackage, Name: "moby", Version: "3.0.10"},
+		{Kind: ReleaseNotesPackage, Name: "apache2-utils"},
+		{Kind: ReleaseNotesContainerImage, Name: "pause", Version: "1.2.0"},
+		{Kind: ReleaseNotesContainerImage, Name: "pause", Version: "1.3.0"},
+		{Kind: ReleaseNotesContainerImage, Name: "coredns", Version: "1.6.6"},
+	}}
+	to = &ReleaseNotes{OS: "Linux", Kernel: "5.0.0-1036-azure", Entries: []*ReleaseNotesEntry{
+		{Kind: ReleaseNotesPackage, Name: "moby", Version: "3.0.9"},
+		{Kind: ReleaseNotesPackage, Name: "img"},
+		{Kind: ReleaseNotesContainerImage, Name: "pause", Version: "1.3.0"},
+		{Kind: ReleaseNotesContainerImage, Name: "coredns", Version: "1.6.6"},
+	}}
+	actual := map[string]ReleaseNotesChangeType{}
+	for _, c := range DiffReleaseNotes(from, to) {
+		actual[c.Name] = c.Type
+	}
+	if expected := map[string]ReleaseNotesChangeType{
+		"Linux":         ReleaseNotesUpgraded,
+		"moby":          ReleaseNotesDowngraded,
+		"apache2-utils": ReleaseNotesRemoved,
+		"img":           ReleaseNotesAdded,
+		"pause":         ReleaseNotesChanged,
+	}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.9", "1.0.10", -1},
+		{"3.0.10", "3.0.8", 1},
+		{"1.15.10", "1.15.10", 0},
+		{"v1.5.4", "v1.5.3", 1},
+		{"1.3.0", "1.3.0_v0.0.5", -1},
+		{"5.0.0-1032-azure", "5.0.0-1036-azure", -1},
+		{"ciprod02132020", "ciprod03022020", -1},
+		{"", "1.0", -1},
+	} {
+		if actual := CompareVersions(c.a, c.b); actual != c.expected {
+			t.Errorf("expected %d comparing %s and %s, got %d", c.expected, c.a, c.b, actual)
+		}
+	}
+}
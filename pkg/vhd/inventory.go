@@ -0,0 +1,139 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// LinuxInventoryVHDPath is where install-dependencies.sh writes the inventory of a Linux VHD build
+	LinuxInventoryVHDPath = "/opt/azure/vhd-inventory.jsonl"
+	// WindowsInventoryVHDPath is where write-release-notes-windows.ps1 writes the inventory of a Windows VHD build
+	WindowsInventoryVHDPath = `c:\vhd-inventory.jsonl`
+
+	// InventoryProperty is the kind of a record setting the property Name of the build to Value
+	InventoryProperty = "property"
+	// InventoryWarning is the kind of a record with a warning of the build as Value
+	InventoryWarning = "warning"
+)
+
+// utf8BOM starts the files PowerShell writes with -Encoding utf8
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// InventoryRecord is a line of the inventory the VHD builder writes on the VM while it installs components. The kind
+// of a component is a ReleaseNotesEntryKind, Version and SHA256 are optional. A property is one of os, buildStarted,
+// buildCompleted, buildNumber, buildID, commit, featureFlags and kernelVersion, or an OS specific fact of the build
+type InventoryRecord struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// ParseInventory parses an inventory, one JSON record per line
+func ParseInventory(data []byte) ([]*InventoryRecord, error) {
+	records := []*InventoryRecord{}
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record := &InventoryRecord{}
+		if err := json.Unmarshal(line, record); err != nil {
+			return nil, errors.Wrapf(err, "parsing inventory line %d", n)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading inventory")
+	}
+	return records, nil
+}
+
+// ReleaseNotesFromInventory generates the release notes of a VHD build from its inventory
+func ReleaseNotesFromInventory(data []byte) (*ReleaseNotes, error) {
+	records, err := ParseInventory(data)
+	if err != nil {
+		return nil, err
+	}
+
+	order := map[ReleaseNotesEntryKind]int{}
+	for i, s := range releaseNotesSections {
+		order[s.kind] = i
+	}
+	r := &ReleaseNotes{Entries: []*ReleaseNotesEntry{}}
+	for _, record := range records {
+		switch record.Kind {
+		case InventoryProperty:
+			if err := r.setProperty(record.Name, record.Value); err != nil {
+				return nil, err
+			}
+		case InventoryWarning:
+			r.Warnings = append(r.Warnings, record.Value)
+		default:
+			kind := ReleaseNotesEntryKind(record.Kind)
+			if _, ok := order[kind]; !ok {
+				return nil, errors.Errorf("inventory record %s has unknown kind %q", record.Name, record.Kind)
+			}
+			if record.Name == "" {
+				return nil, errors.Errorf("inventory record of kind %s has no name", record.Kind)
+			}
+			r.Entries = append(r.Entries, &ReleaseNotesEntry{Kind: kind, Name: record.Name, Version: record.Version, SHA256: record.SHA256})
+		}
+	}
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		return order[r.Entries[i].Kind] < order[r.Entries[j].Kind]
+	})
+
+	if match := releaseNotesKernelRe.FindStringSubmatch(r.KernelVersion); match != nil {
+		r.Kernel = match[2]
+		if r.OS == "" {
+			r.OS = match[1]
+		}
+	}
+	if r.OS != "Linux" && r.OS != "Windows" {
+		return nil, errors.Errorf("inventory has os %q, expected Linux or Windows", r.OS)
+	}
+	return r, nil
+}
+
+func (r *ReleaseNotes) setProperty(name, value string) error {
+	var err error
+	switch name {
+	case "os":
+		r.OS = value
+	case "buildStarted":
+		r.BuildStarted, err = time.Parse(time.RFC3339, value)
+	case "buildCompleted":
+		r.BuildCompleted, err = time.Parse(time.RFC3339, value)
+	case "buildNumber":
+		r.BuildNumber = value
+	case "buildID":
+		r.BuildID = value
+	case "commit":
+		r.Commit = value
+	case "featureFlags":
+		r.FeatureFlags = value
+	case "kernelVersion":
+		r.KernelVersion = value
+	case "":
+		return errors.New("inventory property has no name")
+	default:
+		if r.Properties == nil {
+			r.Properties = map[string]string{}
+		}
+		r.Properties[name] = value
+	}
+	return errors.Wrapf(err, "parsing inventory property %s", name)
+}
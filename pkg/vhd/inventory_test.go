@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReleaseNotesFromInventory(t *testing.T) {
+	r := loadInventory(t, "testdata/linux-inventory.jsonl")
+	if r.OS != "Linux" || r.Kernel != "5.0.0-1032-azure" {
+		t.Errorf("expected Linux kernel 5.0.0-1032-azure, got %s %s", r.OS, r.Kernel)
+	}
+	if expected := time.Date(2020, time.March, 24, 0, 14, 19, 0, time.UTC); !r.BuildStarted.Equal(expected) {
+		t.Errorf("expected the build to start %v, got %v", expected, r.BuildStarted)
+	}
+	if r.BuildNumber != "20200324.2" || r.Properties["ubuntuRelease"] != "18.04" {
+		t.Errorf("unexpected build %s and properties %v", r.BuildNumber, r.Properties)
+	}
+	if !reflect.DeepEqual(r.Warnings, []string{"75% of /dev/sda1 is used"}) {
+		t.Errorf("unexpected warnings %v", r.Warnings)
+	}
+	// entries are ordered by kind, in install order within a kind
+	var names []string
+	for _, e := range r.Entries[:9] {
+		names = append(names, e.Name)
+	}
+	if expected := []string{"apache2-utils", "jq", "pigz", "socat", "moby", "nvidia-docker2", "bcc-tools", "bpftrace", "azure-cni"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the entries %v, got %v", expected, names)
+	}
+	assertReleaseNotesTextRoundTrip(t, r)
+
+	m, err := r.Manifest()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !m.HasFile("https://acs-mirror.azureedge.net/cni/azure-vnet-cni-linux-amd64-v1.0.33.tgz") || !m.Package("moby").HasVersion("3.0.10") {
+		t.Errorf("expected the manifest to have Azure CNI 1.0.33 and moby 3.0.10")
+	}
+
+	// the components of legacy release notes have the kind and name of the generated ones
+	legacy := loadReleaseNotes(t, "1804/2020.03.24.txt")
+	for _, c := range DiffReleaseNotes(legacy, r) {
+		if c.Name == "moby" || c.Name == "azure-cni" && c.Type != ReleaseNotesChanged {
+			t.Errorf("expected moby to be unchanged and Azure CNI to only drop versions, got %+v", c)
+		}
+	}
+}
+
+func TestReleaseNotesFromWindowsInventory(t *testing.T) {
+	r := loadInventory(t, "testdata/windows-inventory.jsonl")
+	if r.OS != "Windows" || r.Kernel != "17763.1098" {
+		t.Errorf("expected Windows build 17763.1098, got %s %s", r.OS, r.Kernel)
+	}
+	if expected := time.Date(2020, time.March, 26, 7, 12, 44, 0, time.UTC); !r.BuildCompleted.Equal(expected) {
+		t.Errorf("expected the build to complete %v, got %v", expected, r.BuildCompleted)
+	}
+	if r.Properties["vhdID"] != "5a4e0c4b-8a4e-4b0c-9f26-6b8f1d1a0e2c" || r.Properties[`HKLM:SOFTWARE\Policies\Microsoft\Windows\WindowsUpdate\AU\NoAutoUpdate`] != "1" {
+		t.Errorf("unexpected properties %v", r.Properties)
+	}
+	for _, expected := range []ReleaseNotesEntry{
+		{Kind: ReleaseNotesPackage, Name: "docker", Version: "19.03.5"},
+		{Kind: ReleaseNotesWindowsFeature, Name: "Containers"},
+		{Kind: ReleaseNotesHotfix, Name: "KB4537818"},
+		{Kind: ReleaseNotesFile, Name: `c:\akse-cache\win-k8s\v1.17.3-1int.zip`, SHA256: "3D0C9B9E5C1A5E0D4C6A2B8F7E9D1C3B5A7F9E1D3C5B7A9F1E3D5C7B9A1F3E5D"},
+	} {
+		if !hasReleaseNotesEntry(r, expected) {
+			t.Errorf("expected the release notes to have %+v", expected)
+		}
+	}
+	assertReleaseNotesTextRoundTrip(t, r)
+}
+
+func TestReleaseNotesFromInvalidInventory(t *testing.T) {
+	for name, c := range map[string]struct {
+		inventory string
+		expected  string
+	}{
+		"invalid json":  {`{"kind": "package"`, "parsing inventory line 1"},
+		"unknown kind":  {`{"kind": "image", "name": "pause"}`, `unknown kind "image"`},
+		"no name":       {`{"kind": "package"}`, "has no name"},
+		"no os":         {`{"kind": "package", "name": "jq"}`, `inventory has os ""`},
+		"invalid date":  {`{"kind": "property", "name": "buildStarted", "value": "yesterday"}`, "parsing inventory property buildStarted"},
+		"property name": {`{"kind": "property", "value": "Linux"}`, "property has no name"},
+	} {
+		if _, err := ReleaseNotesFromInventory([]byte(c.inventory)); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, c.expected, err)
+		}
+	}
+}
+
+func loadInventory(t *testing.T, path string) *ReleaseNotes {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := ReleaseNotesFromInventory(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return r
+}
+
+// assertReleaseNotesTextRoundTrip checks that the text of release notes parses back to the same release notes
+func assertReleaseNotesTextRoundTrip(t *testing.T, r *ReleaseNotes) {
+	parsed, err := ParseReleaseNotes(r.Text())
+	if err != nil {
+		t.Fatalf("unexpected error parsing the text:\n%s\n%v", r.Text(), err)
+	}
+	if !reflect.DeepEqual(parsed, r) {
+		t.Errorf("expected the text to parse back to the release notes, got:\n%s", r.Text())
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package vhd describes the components the VHD builder caches on the images agent pools boot from.
+package vhd
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// VersionPlaceholder is replaced by each version of a component in its DownloadURL
+	VersionPlaceholder = "${VERSION}"
+	// ManifestVHDPath is where install-dependencies.sh copies the component manifest on the VHD
+	ManifestVHDPath = "/opt/azure/components.json"
+)
+
+// Manifest lists the components cached on a VHD, vhdbuilder/packer/components.json is the manifest
+// install-dependencies.sh builds the Linux VHD from
+type Manifest struct {
+	// Packages are installed from a package repository, they have no DownloadURL
+	Packages []*Component `json:"packages" yaml:"packages"`
+	// Files are archives downloaded to the DownloadLocation of the VHD, the CSE installs them from there
+	Files []*Component `json:"files" yaml:"files"`
+	// ContainerImages are pulled into the container image store, DownloadURL is the image reference
+	ContainerImages []*Component `json:"containerImages" yaml:"containerImages"`
+	// KubernetesBinaries are the images kubelet and kubectl are extracted from, see KubernetesBinaryVersion
+	KubernetesBinaries []*Component `json:"kubernetesBinaries" yaml:"kubernetesBinaries"`
+}
+
+// Component is a component cached in several versions
+type Component struct {
+	Name string `json:"name" yaml:"name"`
+	// DownloadURL is the URL of a file or the reference of a container image with VersionPlaceholder in place of the version
+	DownloadURL string `json:"downloadURL,omitempty" yaml:"downloadURL,omitempty"`
+	// DownloadLocation is the directory of the VHD a file is downloaded to
+	DownloadLocation string   `json:"downloadLocation,omitempty" yaml:"downloadLocation,omitempty"`
+	Versions         []string `json:"versions" yaml:"versions"`
+}
+
+// URL returns the download URL of version
+func (c *Component) URL(version string) string {
+	return strings.Replace(c.DownloadURL, VersionPlaceholder, version, -1)
+}
+
+// URLs returns the download URL of every version
+func (c *Component) URLs() []string {
+	urls := make([]string, 0, len(c.Versions))
+	for _, v := range c.Versions {
+		urls = append(urls, c.URL(v))
+	}
+	return urls
+}
+
+// HasVersion returns true if version is cached
+func (c *Component) HasVersion(version string) bool {
+	for _, v := range c.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// KubernetesBinaryVersion returns the Kubernetes version the kubelet and kubectl extracted from the hyperkube
+// image of a patched version are installed as, e.g. 1.15.10 for 1.15.10-hotfix.20200326. The CSE only
+// downloads kubelet if /usr/local/bin/kubelet-<version> is missing
+func KubernetesBinaryVersion(patchedVersion string) string {
+	v := strings.SplitN(patchedVersion, "_", 2)[0]
+	return strings.SplitN(v, "-", 2)[0]
+}
+
+// LoadManifest reads a JSON or YAML component manifest
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading component manifest")
+	}
+	return ParseManifest(b)
+}
+
+// ParseManifest parses and validates a JSON or YAML component manifest, unknown fields are an error
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, errors.Wrap(err, "parsing component manifest")
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks that every component has a unique name and versions, and that the downloaded ones
+// have a DownloadURL with VersionPlaceholder
+func (m *Manifest) Validate() error {
+	for _, list := range []struct {
+		name       string
+		components []*Component
+		downloaded bool
+	}{
+		{"packages", m.Packages, false},
+		{"files", m.Files, true},
+		{"containerImages", m.ContainerImages, true},
+		{"kubernetesBinaries", m.KubernetesBinaries, true},
+	} {
+		seen := map[string]bool{}
+		for i, c := range list.components {
+			switch {
+			case c == nil || c.Name == "":
+				return errors.Errorf("component manifest %s[%d] has no name", list.name, i)
+			case seen[c.Name]:
+				return errors.Errorf("component manifest %s has component %s twice", list.name, c.Name)
+			case len(c.Versions) == 0:
+				return errors.Errorf("component manifest %s component %s has no versions", list.name, c.Name)
+			case list.downloaded && !strings.Contains(c.DownloadURL, VersionPlaceholder):
+				return errors.Errorf("component manifest %s component %s has no downloadURL with %s", list.name, c.Name, VersionPlaceholder)
+			case !list.downloaded && c.DownloadURL != "":
+				return errors.Errorf("component manifest %s component %s must not have a downloadURL", list.name, c.Name)
+			}
+			seen[c.Name] = true
+		}
+	}
+	for _, c := range m.Files {
+		if c.DownloadLocation == "" {
+			return errors.Errorf("component manifest files component %s has no downloadLocation", c.Name)
+		}
+	}
+	return nil
+}
+
+// Package returns the package named name, nil if the manifest has none
+func (m *Manifest) Package(name string) *Component {
+	return findComponent(m.Packages, name)
+}
+
+// File returns the file named name, nil if the manifest has none
+func (m *Manifest) File(name string) *Component {
+	return findComponent(m.Files, name)
+}
+
+// ContainerImage returns the container image named name, nil if the manifest has none
+func (m *Manifest) ContainerImage(name string) *Component {
+	return findComponent(m.ContainerImages, name)
+}
+
+// HasFile returns true if the file downloaded from url is cached
+func (m *Manifest) HasFile(url string) bool {
+	return hasURL(m.Files, url)
+}
+
+// HasContainerImage returns true if the container image is cached, image is a reference with a tag
+func (m *Manifest) HasContainerImage(image string) bool {
+	return hasURL(m.ContainerImages, image)
+}
+
+// HasKubernetesBinaries returns true if kubelet and kubectl of the Kubernetes version, e.g. 1.15.7, are cached
+func (m *Manifest) HasKubernetesBinaries(version string) bool {
+	for _, c := range m.KubernetesBinaries {
+		for _, v := range c.Versions {
+			if KubernetesBinaryVersion(v) == version {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findComponent(components []*Component, name string) *Component {
+	for _, c := range components {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func hasURL(components []*Component, url string) bool {
+	for _, c := range components {
+		for _, v := range c.Versions {
+			if c.URL(v) == url {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"strings"
+	"testing"
+)
+
+// linuxManifestPath is the manifest install-dependencies.sh builds the Linux VHD from
+const linuxManifestPath = "../../vhdbuilder/packer/components.json"
+
+func TestLoadManifest(t *testing.T) {
+	m, err := LoadManifest(linuxManifestPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moby := m.Package("moby"); moby == nil || len(moby.Versions) != 1 {
+		t.Errorf("expected a single moby version, got %v", moby)
+	}
+	if !m.HasFile("https://acs-mirror.azureedge.net/cni/azure-vnet-cni-linux-amd64-v1.0.33.tgz") {
+		t.Errorf("expected Azure CNI v1.0.33 to be cached")
+	}
+	if !m.HasContainerImage("mcr.microsoft.com/oss/kubernetes/coredns:1.6.6") {
+		t.Errorf("expected coredns 1.6.6 to be cached")
+	}
+	if m.HasContainerImage("mcr.microsoft.com/oss/kubernetes/coredns:0.0.1") {
+		t.Errorf("expected coredns 0.0.1 not to be cached")
+	}
+	for version, expected := range map[string]bool{"1.15.10": true, "1.17.3": true, "1.15.1": false} {
+		if actual := m.HasKubernetesBinaries(version); actual != expected {
+			t.Errorf("expected the kubelet %s to be cached %t, got %t", version, expected, actual)
+		}
+	}
+}
+
+func TestKubernetesBinaryVersion(t *testing.T) {
+	for patched, expected := range map[string]string{
+		"1.17.0":                  "1.17.0",
+		"1.15.7_f0.0.2":           "1.15.7",
+		"1.15.10-hotfix.20200326": "1.15.10",
+		"1.17.3-hotfix-20200326":  "1.17.3",
+	} {
+		if actual := KubernetesBinaryVersion(patched); actual != expected {
+			t.Errorf("expected %s for %s, got %s", expected, patched, actual)
+		}
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+files:
+- name: containerd
+  downloadURL: https://example.com/containerd-${VERSION}.tar.gz
+  downloadLocation: /opt/containerd/downloads
+  versions: ["1.2.4"]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := m.File("containerd").URLs(); len(actual) != 1 || actual[0] != "https://example.com/containerd-1.2.4.tar.gz" {
+		t.Errorf("expected the containerd 1.2.4 URL, got %v", actual)
+	}
+
+	for name, c := range map[string]struct {
+		manifest string
+		expected string
+	}{
+		"unknown field":   {`{"images": []}`, "field images not found"},
+		"duplicate":       {`{"packages": [{"name": "moby", "versions": ["1"]}, {"name": "moby", "versions": ["2"]}]}`, "moby twice"},
+		"no versions":     {`{"packages": [{"name": "moby"}]}`, "no versions"},
+		"no placeholder":  {`{"containerImages": [{"name": "pause", "downloadURL": "pause:1.2.0", "versions": ["1.2.0"]}]}`, "no downloadURL"},
+		"no location":     {`{"files": [{"name": "cni", "downloadURL": "cni-${VERSION}.tgz", "versions": ["1"]}]}`, "no downloadLocation"},
+		"package has URL": {`{"packages": [{"name": "moby", "downloadURL": "moby-${VERSION}", "versions": ["1"]}]}`, "must not have a downloadURL"},
+	} {
+		if _, err := ParseManifest([]byte(c.manifest)); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/helpers"
+)
+
+const (
+	// packerHome is the directory packer uploads the files of a Linux build to
+	packerHome = "/home/packer/"
+	// packerWindowsScriptsDir holds the scripts of a Windows build, relative to the root of the repository
+	packerWindowsScriptsDir = "vhdbuilder/packer/"
+	// packerCreateTime is the Unix time init-variables.sh names the VHD and its storage account after
+	packerCreateTime = "{{user `create_time`}}"
+)
+
+// packerImages are the marketplace images a VHD is built from
+var packerImages = map[OSSKU]map[string]map[HyperVGeneration]packerImage{
+	OSSKUUbuntu: {
+		"16.04": {HyperVGenerationV1: {"Canonical", "UbuntuServer", "16.04-LTS", "latest"}},
+		"18.04": {
+			HyperVGenerationV1: {"Canonical", "UbuntuServer", "18.04-LTS", "latest"},
+			HyperVGenerationV2: {"Canonical", "UbuntuServer", "18_04-lts-gen2", "latest"},
+		},
+	},
+	OSSKUWindows: {
+		"": {HyperVGenerationV1: {"MicrosoftWindowsServer", "WindowsServer", "2019-Datacenter-Core-smalldisk", "17763.864.1911120152"}},
+	},
+}
+
+type packerImage struct {
+	publisher, offer, sku, version string
+}
+
+// PackerTemplate is a packer template building a VHD with the azure-arm builder, the variables the templates
+// reference but do not declare come from the settings.json written by init-variables.sh
+type PackerTemplate struct {
+	Variables    map[string]string    `json:"variables"`
+	Builders     []*PackerBuilder     `json:"builders"`
+	Provisioners []*PackerProvisioner `json:"provisioners"`
+}
+
+// PackerBuilder is an azure-arm builder
+type PackerBuilder struct {
+	Type                          string                    `json:"type"`
+	ClientID                      string                    `json:"client_id"`
+	ClientSecret                  string                    `json:"client_secret"`
+	TenantID                      string                    `json:"tenant_id"`
+	SubscriptionID                string                    `json:"subscription_id"`
+	ResourceGroupName             string                    `json:"resource_group_name,omitempty"`
+	CaptureContainerName          string                    `json:"capture_container_name,omitempty"`
+	CaptureNamePrefix             string                    `json:"capture_name_prefix,omitempty"`
+	StorageAccount                string                    `json:"storage_account,omitempty"`
+	ManagedImageName              string                    `json:"managed_image_name,omitempty"`
+	ManagedImageResourceGroupName string                    `json:"managed_image_resource_group_name,omitempty"`
+	SharedImageGalleryDestination *PackerSharedImageGallery `json:"shared_image_gallery_destination,omitempty"`
+	OSType                        string                    `json:"os_type"`
+	OSDiskSizeGB                  int                       `json:"os_disk_size_gb,omitempty"`
+	ImagePublisher                string                    `json:"image_publisher"`
+	ImageOffer                    string                    `json:"image_offer"`
+	ImageSKU                      string                    `json:"image_sku"`
+	ImageVersion                  string                    `json:"image_version"`
+	Communicator                  string                    `json:"communicator,omitempty"`
+	WinRMUseSSL                   bool                      `json:"winrm_use_ssl,omitempty"`
+	WinRMInsecure                 bool                      `json:"winrm_insecure,omitempty"`
+	WinRMTimeout                  string                    `json:"winrm_timeout,omitempty"`
+	WinRMUsername                 string                    `json:"winrm_username,omitempty"`
+	AzureTags                     map[string]string         `json:"azure_tags"`
+	Location                      string                    `json:"location"`
+	VMSize                        string                    `json:"vm_size"`
+}
+
+// PackerSharedImageGallery is the shared_image_gallery_destination of an azure-arm builder
+type PackerSharedImageGallery struct {
+	ResourceGroup      string   `json:"resource_group"`
+	GalleryName        string   `json:"gallery_name"`
+	ImageName          string   `json:"image_name"`
+	ImageVersion       string   `json:"image_version"`
+	ReplicationRegions []string `json:"replication_regions,omitempty"`
+}
+
+// PackerProvisioner is a shell, powershell, file or windows-restart provisioner
+type PackerProvisioner struct {
+	Type             string   `json:"type"`
+	Inline           []string `json:"inline,omitempty"`
+	Script           string   `json:"script,omitempty"`
+	ElevatedUser     string   `json:"elevated_user,omitempty"`
+	ElevatedPassword string   `json:"elevated_password,omitempty"`
+	EnvironmentVars  []string `json:"environment_vars,omitempty"`
+	Direction        string   `json:"direction,omitempty"`
+	Source           string   `json:"source,omitempty"`
+	Destination      string   `json:"destination,omitempty"`
+	RestartTimeout   string   `json:"restart_timeout,omitempty"`
+}
+
+// RenderPackerTemplate validates a build spec and renders the JSON of its packer template
+func RenderPackerTemplate(s *BuildSpec) ([]byte, error) {
+	t, err := s.PackerTemplate()
+	if err != nil {
+		return nil, err
+	}
+	data, err := helpers.JSONMarshalIndent(t, "", "  ", false)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// PackerTemplate validates a build spec and returns its packer template
+func (s *BuildSpec) PackerTemplate() (*PackerTemplate, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if s.OSSKU == OSSKUWindows {
+		return s.windowsPackerTemplate(), nil
+	}
+	return s.linuxPackerTemplate(), nil
+}
+
+// Sources returns the files of the repository the template uploads or runs, relative to the root of the repository
+func (t *PackerTemplate) Sources() []string {
+	sources := []string{}
+	for _, p := range t.Provisioners {
+		switch {
+		case p.Script != "":
+			sources = append(sources, p.Script)
+		case p.Type == "file" && p.Direction != "download":
+			sources = append(sources, p.Source)
+		}
+	}
+	return sources
+}
+
+func (s *BuildSpec) builder(captureContainerName string) *PackerBuilder {
+	image := packerImages[s.OSSKU][s.UbuntuRelease][s.HyperVGeneration]
+	b := &PackerBuilder{
+		Type:           "azure-arm",
+		ClientID:       "{{user `client_id`}}",
+		ClientSecret:   "{{user `client_secret`}}",
+		TenantID:       "{{user `tenant_id`}}",
+		SubscriptionID: "{{user `subscription_id`}}",
+		OSType:         "Linux",
+		ImagePublisher: image.publisher,
+		ImageOffer:     image.offer,
+		ImageSKU:       image.sku,
+		ImageVersion:   image.version,
+		AzureTags: map[string]string{
+			"os":        "Linux",
+			"now":       packerCreateTime,
+			"createdBy": "aks-vhd-pipeline",
+		},
+		Location: "{{user `location`}}",
+		VMSize:   "{{user `vm_size`}}",
+	}
+	if s.OSSKU == OSSKUWindows {
+		b.OSType = "Windows"
+		b.AzureTags["os"] = "Windows"
+	}
+	if s.Storage == StorageSharedImageGallery {
+		g := s.SharedImageGallery
+		b.ManagedImageName = "aks-" + packerCreateTime
+		b.ManagedImageResourceGroupName = "{{user `managed_image_resource_group_name`}}"
+		b.SharedImageGalleryDestination = &PackerSharedImageGallery{
+			ResourceGroup:      g.ResourceGroup,
+			GalleryName:        g.GalleryName,
+			ImageName:          g.ImageName,
+			ImageVersion:       g.ImageVersion,
+			ReplicationRegions: g.ReplicationRegions,
+		}
+		return b
+	}
+	b.ResourceGroupName = "{{user `resource_group_name`}}"
+	b.CaptureContainerName = captureContainerName
+	b.CaptureNamePrefix = "aks-" + packerCreateTime
+	b.StorageAccount = "{{user `storage_account_name`}}"
+	return b
+}
+
+func (s *BuildSpec) linuxPackerTemplate() *PackerTemplate {
+	b := s.builder("aks-vhds")
+	b.OSDiskSizeGB = 30
+
+	provisioners := []*PackerProvisioner{
+		{
+			Type: "shell",
+			Inline: []string{
+				"sudo mkdir -p /opt/azure/containers",
+				"sudo chown -R $USER /opt/azure/containers",
+			},
+		},
+	}
+	// copyPackerFiles of packer_source.sh copies the files with a destination, PACKER_FILES lists them as
+	// <upload>:<destination>:<mode>
+	copied := []string{}
+	for _, f := range s.BuildFiles() {
+		provisioners = append(provisioners, &PackerProvisioner{
+			Type:        "file",
+			Source:      f.Source,
+			Destination: f.UploadPath(),
+		})
+		if f.Destination != "" {
+			copied = append(copied, fmt.Sprintf("%s:%s:%s", f.UploadPath(), f.Destination, f.Mode))
+		}
+	}
+	provisioners = append(provisioners,
+		&PackerProvisioner{
+			Type: "shell",
+			Inline: []string{
+				"sudo FEATURE_FLAGS={{user `feature_flags`}} BUILD_NUMBER={{user `build_number`}} BUILD_ID={{user `build_id`}} COMMIT={{user `commit`}} " +
+					"PACKER_FILES='" + strings.Join(copied, " ") + "' /bin/bash -ux " + packerHome + "install-dependencies.sh",
+			},
+		},
+		&PackerProvisioner{
+			Type:        "file",
+			Direction:   "download",
+			Source:      LinuxInventoryVHDPath,
+			Destination: "vhd-inventory.jsonl",
+		},
+		&PackerProvisioner{
+			Type: "shell",
+			Inline: []string{
+				"sudo /bin/bash -eux " + packerHome + "cis.sh",
+				"sudo /bin/bash -eux " + packerHome + "cleanup-vhd.sh",
+				"sudo /usr/sbin/waagent -force -deprovision+user && export HISTSIZE=0 && sync || exit 125",
+			},
+		},
+	)
+
+	return &PackerTemplate{
+		Variables: map[string]string{
+			"client_id":       "{{env `AZURE_CLIENT_ID`}}",
+			"client_secret":   "{{env `AZURE_CLIENT_SECRET`}}",
+			"tenant_id":       "{{env `AZURE_TENANT_ID`}}",
+			"subscription_id": "{{env `AZURE_SUBSCRIPTION_ID`}}",
+			"location":        "{{env `AZURE_LOCATION`}}",
+			"vm_size":         "{{env `AZURE_VM_SIZE`}}",
+			"build_number":    "{{env `BUILD_NUMBER`}}",
+			"build_id":        "{{env `BUILD_ID`}}",
+			"commit":          "{{env `GIT_VERSION`}}",
+			"feature_flags":   "{{env `FEATURE_FLAGS`}}",
+		},
+		Builders:     []*PackerBuilder{b},
+		Provisioners: provisioners,
+	}
+}
+
+func (s *BuildSpec) windowsPackerTemplate() *PackerTemplate {
+	b := s.builder("aks-vhds-windows-ws2019")
+	b.Communicator = "winrm"
+	b.WinRMUseSSL = true
+	b.WinRMInsecure = true
+	b.WinRMTimeout = "10m"
+	b.WinRMUsername = "packer"
+
+	script := func(name string, environmentVars ...string) *PackerProvisioner {
+		return &PackerProvisioner{
+			Type:             "powershell",
+			Script:           packerWindowsScriptsDir + name,
+			ElevatedUser:     "packer",
+			ElevatedPassword: "{{.WinRMPassword}}",
+			EnvironmentVars:  environmentVars,
+		}
+	}
+	restart := &PackerProvisioner{Type: "windows-restart", RestartTimeout: "10m"}
+
+	provisioners := []*PackerProvisioner{
+		script("configure-windows-vhd.ps1", "ProvisioningPhase=1"),
+		restart,
+		restart,
+		script("configure-windows-vhd.ps1", "ProvisioningPhase=2"),
+		restart,
+	}
+	for _, f := range s.BuildFiles() {
+		provisioners = append(provisioners, &PackerProvisioner{
+			Type:        "file",
+			Direction:   "upload",
+			Source:      f.Source,
+			Destination: f.Destination,
+		})
+	}
+	provisioners = append(provisioners,
+		script("write-release-notes-windows.ps1",
+			"BUILD_BRANCH={{user `build_branch`}}",
+			"BUILD_COMMIT={{user `build_commit`}}",
+			"BUILD_ID={{user `build_id`}}",
+			"BUILD_NUMBER={{user `build_number`}}",
+			"BUILD_REPO={{user `build_repo`}}",
+		),
+		&PackerProvisioner{
+			Type:        "file",
+			Direction:   "download",
+			Source:      WindowsInventoryVHDPath,
+			Destination: "vhd-inventory.jsonl",
+		},
+		&PackerProvisioner{
+			Type: "powershell",
+			Inline: []string{
+				"& $env:SystemRoot\\System32\\Sysprep\\Sysprep.exe /oobe /generalize /mode:vm /quiet /quit",
+				"Stop-Service WindowsAzureGuestAgent",
+				"Stop-Service WindowsAzureNetAgentSvc",
+				"Stop-Service RdAgent",
+				"Stop-Service WindowsAzureTelemetryService",
+				"& sc.exe delete WindowsAzureGuestAgent",
+				"& sc.exe delete WindowsAzureNetAgentSvc",
+				"& sc.exe delete RdAgent",
+				"& sc.exe delete WindowsAzureTelemetryService",
+				"Get-ChildItem c:\\WindowsAzure -Force | Sort-Object -Property FullName -Descending | ForEach-Object { try { Remove-Item -Path $_.FullName -Force -Recurse -ErrorAction SilentlyContinue; } catch { } }",
+				"while($true) { $imageState = Get-ItemProperty HKLM:\\SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Setup\\State | Select ImageState; if($imageState.ImageState -ne 'IMAGE_STATE_GENERALIZE_RESEAL_TO_OOBE') { Write-Output $imageState.ImageState; Start-Sleep -s 10  } else { break } }",
+				"Remove-Item -Path WSMan:\\Localhost\\listener\\listener* -Recurse",
+			},
+		},
+	)
+
+	return &PackerTemplate{
+		Variables: map[string]string{
+			"build_branch":    "{{env `GIT_BRANCH`}}",
+			"build_commit":    "{{env `GIT_VERSION`}}",
+			"build_id":        "{{env `BUILD_ID`}}",
+			"build_number":    "{{env `BUILD_NUMBER`}}",
+			"build_repo":      "{{env `GIT_REPO`}}",
+			"client_id":       "{{env `AZURE_CLIENT_ID`}}",
+			"client_secret":   "{{env `AZURE_CLIENT_SECRET`}}",
+			"tenant_id":       "{{env `AZURE_TENANT_ID`}}",
+			"subscription_id": "{{env `AZURE_SUBSCRIPTION_ID`}}",
+			"location":        "{{env `AZURE_LOCATION`}}",
+			"vm_size":         "{{env `AZURE_VM_SIZE`}}",
+		},
+		Builders:     []*PackerBuilder{b},
+		Provisioners: provisioners,
+	}
+}
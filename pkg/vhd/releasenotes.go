@@ -0,0 +1,394 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// releaseNotesLegacyComponentsHeader starts the packages and files of release notes written by
+	// install-dependencies.sh before the release notes were generated from the inventory
+	releaseNotesLegacyComponentsHeader = "Components downloaded in this VHD build"
+	// releaseNotesPropertiesHeader starts the build properties of the release notes
+	releaseNotesPropertiesHeader = "Build properties:"
+	// releaseNotesKernelHeader is followed by the kernel version of the VHD
+	releaseNotesKernelHeader = "Using kernel:"
+	// releaseNotesWarningPrefix starts a warning of the build, e.g. the disk usage
+	releaseNotesWarningPrefix = "WARNING: "
+	// releaseNotesDateLayout is the layout of the date command the build dates are written with
+	releaseNotesDateLayout = "Mon Jan _2 15:04:05 MST 2006"
+	// releaseNotesHyperkubeImage is the image the VHD builder extracts kubelet and kubectl from, the release notes
+	// only list the extracted versions
+	releaseNotesHyperkubeImage = "mcr.microsoft.com/oss/kubernetes/hyperkube:v" + VersionPlaceholder
+)
+
+var (
+	// releaseNotesItemRe matches an entry of the release notes
+	releaseNotesItemRe = regexp.MustCompile(`^  - (.+)$`)
+	// releaseNotesVersionRe matches an entry written as "<name> version <version>", e.g. containerd version 1.2.4
+	releaseNotesVersionRe = regexp.MustCompile(`^(.+) version (\S+)$`)
+	// releaseNotesVPrefixRe matches a legacy component written as "<name> v<version>", e.g. moby v3.0.10
+	releaseNotesVPrefixRe = regexp.MustCompile(`^(\S+) v(\d\S*)$`)
+	// releaseNotesSHA256Re matches a file written as "<name> sha256 <hash>"
+	releaseNotesSHA256Re = regexp.MustCompile(`^(.+) sha256 ([0-9a-fA-F]{64})$`)
+	// releaseNotesBinaryRe matches kubelet and kubectl in the /usr/local/bin listing of legacy release notes
+	releaseNotesBinaryRe = regexp.MustCompile(`/usr/local/bin/(kubelet|kubectl)-(\S+)$`)
+	// releaseNotesKernelRe matches the kernel release of /proc/version or the build of Windows
+	releaseNotesKernelRe = regexp.MustCompile(`^(Linux|Windows) version (\S+)`)
+)
+
+// ReleaseNotesEntryKind is the section of the release notes an entry is listed in
+type ReleaseNotesEntryKind string
+
+const (
+	// ReleaseNotesPackage is installed from a package repository or a Windows installer
+	ReleaseNotesPackage ReleaseNotesEntryKind = "package"
+	// ReleaseNotesFile is a downloaded file, a Windows file has a SHA256 instead of a version
+	ReleaseNotesFile ReleaseNotesEntryKind = "file"
+	// ReleaseNotesContainerImage is a pulled container image named after its repository
+	ReleaseNotesContainerImage ReleaseNotesEntryKind = "containerImage"
+	// ReleaseNotesKubernetesBinary is kubelet or kubectl extracted from the hyperkube image
+	ReleaseNotesKubernetesBinary ReleaseNotesEntryKind = "kubernetesBinary"
+	// ReleaseNotesWindowsFeature is an installed Windows feature
+	ReleaseNotesWindowsFeature ReleaseNotesEntryKind = "windowsFeature"
+	// ReleaseNotesWindowsCapability is an installed Windows capability
+	ReleaseNotesWindowsCapability ReleaseNotesEntryKind = "windowsCapability"
+	// ReleaseNotesHotfix is an installed Windows QFE named after its KB
+	ReleaseNotesHotfix ReleaseNotesEntryKind = "hotfix"
+	// ReleaseNotesWindowsUpdate is an installed Windows update named after its title
+	ReleaseNotesWindowsUpdate ReleaseNotesEntryKind = "windowsUpdate"
+)
+
+// releaseNotesSections are the headers of the sections of the release notes in the order they are written
+var releaseNotesSections = []struct {
+	kind   ReleaseNotesEntryKind
+	header string
+}{
+	{ReleaseNotesPackage, "Packages installed:"},
+	{ReleaseNotesFile, "Files downloaded:"},
+	{ReleaseNotesContainerImage, "Docker images pre-pulled:"},
+	{ReleaseNotesKubernetesBinary, "Kubernetes binaries:"},
+	{ReleaseNotesWindowsFeature, "Windows features installed:"},
+	{ReleaseNotesWindowsCapability, "Windows capabilities installed:"},
+	{ReleaseNotesHotfix, "Hotfixes installed:"},
+	{ReleaseNotesWindowsUpdate, "Windows updates installed:"},
+}
+
+// releaseNotesLegacyComponents are the files and versioned components of legacy release notes keyed by the name
+// install-dependencies.sh wrote them with, the other legacy components are packages
+var releaseNotesLegacyComponents = map[string]struct {
+	kind ReleaseNotesEntryKind
+	name string
+}{
+	"moby":       {ReleaseNotesPackage, "moby"},
+	"Azure CNI":  {ReleaseNotesFile, "azure-cni"},
+	"CNI plugin": {ReleaseNotesFile, "cni-plugins"},
+	"containerd": {ReleaseNotesFile, "containerd"},
+	"bpftrace":   {ReleaseNotesFile, "bpftrace"},
+	"img":        {ReleaseNotesFile, "img"},
+}
+
+// ReleaseNotes are the structured release notes of a Linux or Windows VHD build. They are generated from the
+// inventory of the build, see ReleaseNotesFromInventory, and published as JSON and as the text of Text, which
+// vhdbuilder/release-notes keeps for every published VHD
+type ReleaseNotes struct {
+	// OS is Linux or Windows
+	OS             string    `json:"os"`
+	BuildStarted   time.Time `json:"buildStarted"`
+	BuildCompleted time.Time `json:"buildCompleted"`
+	BuildNumber    string    `json:"buildNumber"`
+	BuildID        string    `json:"buildID"`
+	Commit         string    `json:"commit"`
+	FeatureFlags   string    `json:"featureFlags,omitempty"`
+	// Kernel is the kernel release, e.g. 5.0.0-1032-azure, or the Windows build, e.g. 17763.1098, and KernelVersion
+	// the /proc/version or the Windows version it is read from
+	Kernel        string `json:"kernel"`
+	KernelVersion string `json:"kernelVersion"`
+	// Entries are ordered by kind and listed in install order within a kind, a component cached in several
+	// versions has an entry per version
+	Entries []*ReleaseNotesEntry `json:"entries"`
+	// Properties are the OS specific facts of the build, e.g. the VHD ID of a Windows VHD
+	Properties map[string]string `json:"properties,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+}
+
+// ReleaseNotesEntry is a package, file, container image, Kubernetes binary or Windows update of the release notes
+type ReleaseNotesEntry struct {
+	Kind ReleaseNotesEntryKind `json:"kind"`
+	Name string                `json:"name"`
+	// Version is empty for an entry installed without a pinned version
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+}
+
+// ParseReleaseNotes parses the text of release notes, generated by Text or written by install-dependencies.sh
+// before the release notes were generated
+func ParseReleaseNotes(data []byte) (*ReleaseNotes, error) {
+	r := &ReleaseNotes{Entries: []*ReleaseNotesEntry{}}
+	var section ReleaseNotesEntryKind
+	sections := 0
+	legacy, properties, kernel := false, false, false
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if kernel {
+			kernel = false
+			r.KernelVersion = line
+			if match := releaseNotesKernelRe.FindStringSubmatch(line); match != nil {
+				r.OS, r.Kernel = match[1], match[2]
+			}
+			continue
+		}
+		if match := releaseNotesItemRe.FindStringSubmatch(line); match != nil && (section != "" || properties) {
+			switch {
+			case properties:
+				if kv := strings.SplitN(match[1], ": ", 2); len(kv) == 2 {
+					if r.Properties == nil {
+						r.Properties = map[string]string{}
+					}
+					r.Properties[kv[0]] = kv[1]
+				}
+			case legacy:
+				r.Entries = append(r.Entries, parseLegacyReleaseNotesEntry(match[1]))
+			default:
+				r.Entries = append(r.Entries, parseReleaseNotesEntry(section, match[1]))
+			}
+			continue
+		}
+		if match := releaseNotesBinaryRe.FindStringSubmatch(line); match != nil {
+			r.Entries = append(r.Entries, &ReleaseNotesEntry{Kind: ReleaseNotesKubernetesBinary, Name: match[1], Version: match[2]})
+			continue
+		}
+		if kind, ok := releaseNotesSectionKind(line); ok {
+			section, legacy, properties = kind, false, false
+			sections++
+			continue
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(line, releaseNotesLegacyComponentsHeader):
+			section, legacy, properties = ReleaseNotesPackage, true, false
+			sections++
+		case line == releaseNotesPropertiesHeader:
+			section, legacy, properties = "", false, true
+		case line == releaseNotesKernelHeader:
+			kernel = true
+		case strings.HasPrefix(line, releaseNotesWarningPrefix):
+			r.Warnings = append(r.Warnings, strings.TrimPrefix(line, releaseNotesWarningPrefix))
+		case strings.HasPrefix(line, "Starting build on "):
+			r.BuildStarted, err = parseReleaseNotesDate(strings.TrimPrefix(line, "Starting build on "))
+		case strings.HasPrefix(line, "Install completed successfully on "):
+			r.BuildCompleted, err = parseReleaseNotesDate(strings.TrimPrefix(line, "Install completed successfully on "))
+		case strings.HasPrefix(line, "VSTS Build NUMBER:"):
+			r.BuildNumber = strings.TrimSpace(strings.TrimPrefix(line, "VSTS Build NUMBER:"))
+		case strings.HasPrefix(line, "VSTS Build ID:"):
+			r.BuildID = strings.TrimSpace(strings.TrimPrefix(line, "VSTS Build ID:"))
+		case strings.HasPrefix(line, "Commit:"):
+			r.Commit = strings.TrimSpace(strings.TrimPrefix(line, "Commit:"))
+		case strings.HasPrefix(line, "Feature flags:"):
+			r.FeatureFlags = strings.TrimSpace(strings.TrimPrefix(line, "Feature flags:"))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading release notes")
+	}
+	if sections == 0 {
+		return nil, errors.New("release notes have no components")
+	}
+	return r, nil
+}
+
+// Text returns the release notes as text, ParseReleaseNotes parses it back
+func (r *ReleaseNotes) Text() []byte {
+	var b bytes.Buffer
+	if !r.BuildStarted.IsZero() {
+		fmt.Fprintf(&b, "Starting build on  %s\n", r.BuildStarted.UTC().Format(releaseNotesDateLayout))
+	}
+	for _, s := range releaseNotesSections {
+		written := false
+		for _, e := range r.Entries {
+			if e.Kind != s.kind {
+				continue
+			}
+			if !written {
+				fmt.Fprintln(&b, s.header)
+				written = true
+			}
+			fmt.Fprintf(&b, "  - %s\n", e.text())
+		}
+	}
+	if len(r.Properties) > 0 {
+		keys := make([]string, 0, len(r.Properties))
+		for k := range r.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(&b, releaseNotesPropertiesHeader)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  - %s: %s\n", k, r.Properties[k])
+		}
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "%s%s\n", releaseNotesWarningPrefix, w)
+	}
+	if r.KernelVersion != "" {
+		fmt.Fprintf(&b, "%s\n%s\n", releaseNotesKernelHeader, r.KernelVersion)
+	}
+	if !r.BuildCompleted.IsZero() {
+		fmt.Fprintf(&b, "Install completed successfully on  %s\n", r.BuildCompleted.UTC().Format(releaseNotesDateLayout))
+	}
+	fmt.Fprintf(&b, "VSTS Build NUMBER: %s\n", r.BuildNumber)
+	fmt.Fprintf(&b, "VSTS Build ID: %s\n", r.BuildID)
+	fmt.Fprintf(&b, "Commit: %s\n", r.Commit)
+	fmt.Fprintf(&b, "Feature flags: %s\n", r.FeatureFlags)
+	return b.Bytes()
+}
+
+func (e *ReleaseNotesEntry) text() string {
+	switch {
+	case e.Kind == ReleaseNotesContainerImage:
+		return e.Name + ":" + e.Version
+	case e.SHA256 != "":
+		return e.Name + " sha256 " + e.SHA256
+	case e.Version != "":
+		return e.Name + " version " + e.Version
+	default:
+		return e.Name
+	}
+}
+
+func releaseNotesSectionKind(header string) (ReleaseNotesEntryKind, bool) {
+	for _, s := range releaseNotesSections {
+		if s.header == header {
+			return s.kind, true
+		}
+	}
+	return "", false
+}
+
+func parseReleaseNotesDate(s string) (time.Time, error) {
+	t, err := time.Parse(releaseNotesDateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "parsing release notes date %q", s)
+	}
+	return t, nil
+}
+
+func parseReleaseNotesEntry(kind ReleaseNotesEntryKind, item string) *ReleaseNotesEntry {
+	if kind == ReleaseNotesContainerImage {
+		repository, tag := splitImageReference(item)
+		return &ReleaseNotesEntry{Kind: kind, Name: repository, Version: tag}
+	}
+	if match := releaseNotesSHA256Re.FindStringSubmatch(item); match != nil {
+		return &ReleaseNotesEntry{Kind: kind, Name: match[1], SHA256: match[2]}
+	}
+	if match := releaseNotesVersionRe.FindStringSubmatch(item); match != nil {
+		return &ReleaseNotesEntry{Kind: kind, Name: match[1], Version: match[2]}
+	}
+	return &ReleaseNotesEntry{Kind: kind, Name: item}
+}
+
+// parseLegacyReleaseNotesEntry returns the package or file of a legacy component, e.g. a file azure-cni
+// for "Azure CNI version 1.0.33"
+func parseLegacyReleaseNotesEntry(item string) *ReleaseNotesEntry {
+	name, version := item, ""
+	for _, re := range []*regexp.Regexp{releaseNotesVersionRe, releaseNotesVPrefixRe} {
+		if match := re.FindStringSubmatch(item); match != nil {
+			name, version = match[1], match[2]
+			break
+		}
+	}
+	if c, ok := releaseNotesLegacyComponents[name]; ok {
+		return &ReleaseNotesEntry{Kind: c.kind, Name: c.name, Version: version}
+	}
+	return &ReleaseNotesEntry{Kind: ReleaseNotesPackage, Name: name, Version: version}
+}
+
+// splitImageReference returns the repository and the tag of an image reference, latest if it has no tag
+func splitImageReference(image string) (string, string) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		// the colon is the port of the registry
+		return image, "latest"
+	}
+	return image[:i], image[i+1:]
+}
+
+// releaseNotesManifestFiles are the files of vhdbuilder/packer/components.json, release notes only have their versions
+var releaseNotesManifestFiles = map[string]Component{
+	"azure-cni": {
+		Name:             "azure-cni",
+		DownloadURL:      "https://acs-mirror.azureedge.net/cni/azure-vnet-cni-linux-amd64-v" + VersionPlaceholder + ".tgz",
+		DownloadLocation: "/opt/cni/downloads",
+	},
+	"cni-plugins": {
+		Name:             "cni-plugins",
+		DownloadURL:      "https://acs-mirror.azureedge.net/cni/cni-plugins-amd64-v" + VersionPlaceholder + ".tgz",
+		DownloadLocation: "/opt/cni/downloads",
+	},
+	"containerd": {
+		Name:             "containerd",
+		DownloadURL:      "https://storage.googleapis.com/cri-containerd-release/cri-containerd-" + VersionPlaceholder + ".linux-amd64.tar.gz",
+		DownloadLocation: "/opt/containerd/downloads",
+	},
+}
+
+// ManifestFromReleaseNotes parses release notes and returns the component manifest of the VHD, see Manifest
+func ManifestFromReleaseNotes(data []byte) (*Manifest, error) {
+	r, err := ParseReleaseNotes(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Manifest()
+}
+
+// Manifest returns the component manifest of a VHD built before the manifest existed. It has the versioned
+// packages, the files of vhdbuilder/packer/components.json, the container images named after their repository,
+// an image without tag has the version latest, and the hyperkube images kubelet was extracted from
+func (r *ReleaseNotes) Manifest() (*Manifest, error) {
+	m := &Manifest{}
+	add := func(list *[]*Component, template Component, version string) {
+		c := findComponent(*list, template.Name)
+		if c == nil {
+			c = &Component{Name: template.Name, DownloadURL: template.DownloadURL, DownloadLocation: template.DownloadLocation}
+			*list = append(*list, c)
+		}
+		c.Versions = append(c.Versions, version)
+	}
+	for _, e := range r.Entries {
+		switch e.Kind {
+		case ReleaseNotesPackage:
+			if e.Version != "" {
+				add(&m.Packages, Component{Name: e.Name}, e.Version)
+			}
+		case ReleaseNotesFile:
+			if c, ok := releaseNotesManifestFiles[e.Name]; ok && e.Version != "" {
+				add(&m.Files, c, e.Version)
+			}
+		case ReleaseNotesContainerImage:
+			add(&m.ContainerImages, Component{Name: e.Name, DownloadURL: e.Name + ":" + VersionPlaceholder}, e.Version)
+		case ReleaseNotesKubernetesBinary:
+			if e.Name == "kubelet" {
+				add(&m.KubernetesBinaries, Component{Name: "hyperkube", DownloadURL: releaseNotesHyperkubeImage}, e.Version)
+			}
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
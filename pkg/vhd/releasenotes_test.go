@@ -0,0 +1,149 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vhd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// releaseNotesDir holds the release notes of the published Linux VHDs
+const releaseNotesDir = "../../vhdbuilder/release-notes/AKSUbuntu"
+
+func TestParseReleaseNotes(t *testing.T) {
+	r := loadReleaseNotes(t, "1804/2020.03.24.txt")
+	if expected := time.Date(2020, time.March, 24, 0, 14, 19, 0, time.UTC); !r.BuildStarted.Equal(expected) {
+		t.Errorf("expected the build to start %v, got %v", expected, r.BuildStarted)
+	}
+	if expected := time.Date(2020, time.March, 24, 0, 42, 5, 0, time.UTC); !r.BuildCompleted.Equal(expected) {
+		t.Errorf("expected the build to complete %v, got %v", expected, r.BuildCompleted)
+	}
+	if r.BuildNumber != "20200324.2" || r.BuildID != "29684488" || r.Commit != "53731c07d0ad1cd35a938940d034d8456eda58ec" || r.FeatureFlags != "" {
+		t.Errorf("unexpected build %s %s %s %q", r.BuildNumber, r.BuildID, r.Commit, r.FeatureFlags)
+	}
+	if r.OS != "Linux" || r.Kernel != "5.0.0-1032-azure" {
+		t.Errorf("expected Linux kernel 5.0.0-1032-azure, got %s %s", r.OS, r.Kernel)
+	}
+	if !reflect.DeepEqual(r.Warnings, []string{"75% of /dev/sda1 is used"}) {
+		t.Errorf("unexpected warnings %v", r.Warnings)
+	}
+	for _, expected := range []ReleaseNotesEntry{
+		{Kind: ReleaseNotesPackage, Name: "apache2-utils"},
+		{Kind: ReleaseNotesPackage, Name: "moby", Version: "3.0.10"},
+		{Kind: ReleaseNotesFile, Name: "azure-cni", Version: "1.0.33"},
+		{Kind: ReleaseNotesContainerImage, Name: "busybox", Version: "latest"},
+		{Kind: ReleaseNotesKubernetesBinary, Name: "kubectl", Version: "1.17.3"},
+	} {
+		if !hasReleaseNotesEntry(r, expected) {
+			t.Errorf("expected the release notes to have %+v", expected)
+		}
+	}
+
+	// every release notes file of the repository parses
+	files, err := filepath.Glob(releaseNotesDir + "/*/*.txt")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expected release notes in %s: %v", releaseNotesDir, err)
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r, err := ParseReleaseNotes(b)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", file, err)
+			continue
+		}
+		if r.BuildStarted.IsZero() || r.BuildCompleted.IsZero() || r.BuildNumber == "" || r.Kernel == "" {
+			t.Errorf("%s: expected the build and the kernel, got %+v", file, r)
+		}
+	}
+
+	if _, err := ParseReleaseNotes([]byte("Starting build on  yesterday\nDocker images pre-pulled:\n")); err == nil {
+		t.Errorf("expected an error for an invalid build date")
+	}
+}
+
+func TestManifestFromReleaseNotes(t *testing.T) {
+	b, err := ioutil.ReadFile(releaseNotesDir + "/1804/2020.03.24.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := ManifestFromReleaseNotes(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := m.Package("moby").Versions; !reflect.DeepEqual(actual, []string{"3.0.10"}) {
+		t.Errorf("expected moby 3.0.10, got %v", actual)
+	}
+	if !m.HasFile("https://storage.googleapis.com/cri-containerd-release/cri-containerd-1.2.4.linux-amd64.tar.gz") {
+		t.Errorf("expected containerd 1.2.4 to be cached")
+	}
+	for _, image := range []string{
+		"mcr.microsoft.com/oss/kubernetes/hyperkube:v1.17.3_f0.0.1",
+		"busybox:latest",
+		"mcr.microsoft.com/oss/virtual-kubelet/virtual-kubelet:latest",
+	} {
+		if !m.HasContainerImage(image) {
+			t.Errorf("expected %s to be cached", image)
+		}
+	}
+	if !m.HasKubernetesBinaries("1.17.3") || m.HasKubernetesBinaries("1.17.2") {
+		t.Errorf("expected only the listed kubelet versions to be cached")
+	}
+
+	// the files of the release notes are downloaded as the files of the component manifest
+	manifest, err := LoadManifest(linuxManifestPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range manifest.Files {
+		actual := m.File(c.Name)
+		if actual == nil || actual.DownloadURL != c.DownloadURL || actual.DownloadLocation != c.DownloadLocation {
+			t.Errorf("expected the release notes file %s to match the component manifest, got %+v", c.Name, actual)
+		}
+	}
+
+	if _, err := ManifestFromReleaseNotes([]byte("  - moby v3.0.10\n")); err == nil {
+		t.Errorf("expected an error for release notes without images")
+	}
+}
+
+func TestSplitImageReference(t *testing.T) {
+	for image, expected := range map[string][2]string{
+		"busybox":                         {"busybox", "latest"},
+		"nginx:1.13.12-alpine":            {"nginx", "1.13.12-alpine"},
+		"localhost:5000/pause":            {"localhost:5000/pause", "latest"},
+		"localhost:5000/pause:1.2.0":      {"localhost:5000/pause", "1.2.0"},
+		"mcr.microsoft.com/k8s/pause:1.0": {"mcr.microsoft.com/k8s/pause", "1.0"},
+	} {
+		if repository, tag := splitImageReference(image); repository != expected[0] || tag != expected[1] {
+			t.Errorf("expected %v for %s, got %s and %s", expected, image, repository, tag)
+		}
+	}
+}
+
+func loadReleaseNotes(t *testing.T, name string) *ReleaseNotes {
+	b, err := ioutil.ReadFile(filepath.Join(releaseNotesDir, name))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := ParseReleaseNotes(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return r
+}
+
+func hasReleaseNotesEntry(r *ReleaseNotes, expected ReleaseNotesEntry) bool {
+	for _, e := range r.Entries {
+		if *e == expected {
+			return true
+		}
+	}
+	return false
+}
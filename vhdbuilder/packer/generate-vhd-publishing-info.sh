@@ -17,6 +17,13 @@ do
     fi
 done
 
+# generated by make -f packer.mk generate-release-notes after the VHD build
+release_notes_filepath="release-notes.json"
+if [ ! -f ${release_notes_filepath} ]; then
+    echo "${release_notes_filepath} was not found!"
+    exit 1
+fi
+
 start_date=$(date +"%Y-%m-%dT00:00Z" -d "-1 day")
 expiry_date=$(date +"%Y-%m-%dT00:00Z" -d "+1 year")
 sas_token=$(az storage container generate-sas --name vhds --permissions lr --connection-string ${CLASSIC_SA_CONNECTION_STRING} --start ${start_date} --expiry ${expiry_date} | tr -d '"')
@@ -30,7 +37,8 @@ cat <<EOF > vhd-publishing-info.json
     "vhd_url" : "$vhd_url",
     "os_name" : "$OS_NAME",
     "sku_name" : "$sku_name",
-    "offer_name" : "$OFFER_NAME"
+    "offer_name" : "$OFFER_NAME",
+    "release_notes" : $(cat ${release_notes_filepath})
 }
 EOF
 
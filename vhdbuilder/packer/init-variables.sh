@@ -58,6 +58,7 @@ cat <<EOF > vhdbuilder/packer/settings.json
   "client_secret": "${CLIENT_SECRET}",
   "tenant_id":      "${TENANT_ID}",
   "resource_group_name": "${AZURE_RESOURCE_GROUP_NAME}",
+  "managed_image_resource_group_name": "${AZURE_RESOURCE_GROUP_NAME}",
   "location": "${AZURE_LOCATION}",
   "storage_account_name": "${STORAGE_ACCOUNT_NAME}",
   "vm_size": "${AZURE_VM_SIZE}",
@@ -1,117 +1,91 @@
 <#
     .SYNOPSIS
-        Produces a release notes file for a Windows VHD
+        Produces the inventory the release notes of a Windows VHD are generated from
 
     .DESCRIPTION
-        Produces a release notes file for a Windows VHD
+        Produces the inventory the release notes of a Windows VHD are generated from, one JSON record per line.
+        The release notes are generated with: baker release-notes generate vhd-inventory.jsonl
 #>
 
 $ErrorActionPreference = "Stop"
 
-$releaseNotesFilePath = "c:\release-notes.txt"
+$inventoryFilePath = "c:\vhd-inventory.jsonl"
 
-function Log($Message) {
-    # Write-Output $Message
-    $Message | Tee-Object -FilePath $releaseNotesFilePath -Append
+function Write-InventoryRecord($Record) {
+    $Record | ConvertTo-Json -Compress | Out-File -FilePath $inventoryFilePath -Append -Encoding utf8
 }
 
-Log "Build Number: $env:BUILD_NUMBER"
-Log "Build Id:     $env:BUILD_ID"
-Log "Build Repo:   $env:BUILD_REPO"
-Log "Build Branch: $env:BUILD_BRANCH"
-Log "Commit:       $env:BUILD_COMMIT"
-Log ""
+function Add-Component($Kind, $Name, $Version, $Sha256) {
+    $record = [ordered]@{ kind = $Kind; name = "$Name" }
+    if ($Version) { $record.version = "$Version" }
+    if ($Sha256) { $record.sha256 = "$Sha256" }
+    Write-InventoryRecord $record
+}
 
-$vhdId = Get-Content 'c:\vhd-id.txt'
-LOG ("VHD ID:      $vhdId")
-LOG ""
+function Add-Property($Name, $Value) {
+    Write-InventoryRecord ([ordered]@{ kind = "property"; name = $Name; value = "$Value" })
+}
 
-Log "System Info"
-$systemInfo = Get-ItemProperty -Path 'HKLM:SOFTWARE\Microsoft\Windows NT\CurrentVersion'
-Log ("`t{0,-14} : {1}" -f "OS Name", $systemInfo.ProductName)
-LOG ("`t{0,-14} : {1}" -f "OS Version", "$($systemInfo.CurrentBuildNumber).$($systemInfo.UBR)")
-LOG ("`t{0,-14} : {1}" -f "OS InstallType", $systemInfo.InstallationType)
-Log ""
+Remove-Item -Path $inventoryFilePath -ErrorAction Ignore
 
-$allowedSecurityProtocols = [System.Net.ServicePointManager]::SecurityProtocol
-Log "Allowed security protocols: $allowedSecurityProtocols"
-Log ""
+Add-Property "os" "Windows"
+Add-Property "buildNumber" $env:BUILD_NUMBER
+Add-Property "buildID" $env:BUILD_ID
+Add-Property "buildRepo" $env:BUILD_REPO
+Add-Property "buildBranch" $env:BUILD_BRANCH
+Add-Property "commit" $env:BUILD_COMMIT
+Add-Property "vhdID" (Get-Content 'c:\vhd-id.txt')
+# the VM the VHD is built on was installed when the build started
+Add-Property "buildStarted" ((Get-CimInstance Win32_OperatingSystem).InstallDate.ToUniversalTime().ToString("yyyy-MM-dd'T'HH:mm:ss'Z'"))
 
-Log "Installed Features"
+$systemInfo = Get-ItemProperty -Path 'HKLM:SOFTWARE\Microsoft\Windows NT\CurrentVersion'
+Add-Property "kernelVersion" ("Windows version {0}.{1} ({2})" -f $systemInfo.CurrentBuildNumber, $systemInfo.UBR, $systemInfo.ProductName)
+Add-Property "osInstallType" $systemInfo.InstallationType
+Add-Property "securityProtocols" ([System.Net.ServicePointManager]::SecurityProtocol)
+
+# installed features cannot be enumerated on client skus
 if ($systemInfo.InstallationType -ne 'client') {
-    Log (Get-WindowsFeature | Where-Object Installed)
-}
-else {
-    LOG "`t<Cannot enumerate installed features on client skus>"
+    foreach ($feature in (Get-WindowsFeature | Where-Object Installed)) {
+        Add-Component "windowsFeature" $feature.Name
+    }
 }
-Log ""
-
 
-Log "Installed Packages"
-$packages = Get-WindowsCapability -Online | Where-Object { $_.State -eq 'Installed' }
-foreach ($package in $packages) {
-    Log ("`t{0}" -f $package.Name)
+foreach ($capability in (Get-WindowsCapability -Online | Where-Object { $_.State -eq 'Installed' })) {
+    Add-Component "windowsCapability" $capability.Name
 }
-Log ""
 
-Log "Installed QFEs"
-$qfes = Get-HotFix
-foreach ($qfe in $qfes) {
-    $link = "http://support.microsoft.com/?kbid={0}" -f ($qfe.HotFixID.Replace("KB", ""))
-    LOG ("`t{0,-9} : {1, -15} : {2}" -f $qfe.HotFixID, $Qfe.Description, $link)
+foreach ($qfe in Get-HotFix) {
+    Add-Component "hotfix" $qfe.HotFixID
 }
-Log ""
 
-Log "Installed Updates"
 $updateSession = New-Object -ComObject Microsoft.Update.Session
 $updateSearcher = $UpdateSession.CreateUpdateSearcher()
-$updates = $updateSearcher.Search("IsInstalled=1").Updates
-foreach ($update in $updates) {
-    LOG ("`t{0}" -f $update.Title)
+foreach ($update in $updateSearcher.Search("IsInstalled=1").Updates) {
+    Add-Component "windowsUpdate" $update.Title
 }
-LOG ""
-
-LOG "Windows Update Registry Settings"
-LOG "`thttps://docs.microsoft.com/en-us/windows/deployment/update/waas-wu-settings"
 
+# https://docs.microsoft.com/en-us/windows/deployment/update/waas-wu-settings
 $wuRegistryKeys = @(
     "HKLM:SOFTWARE\Policies\Microsoft\Windows\WindowsUpdate",
     "HKLM:SOFTWARE\Policies\Microsoft\Windows\WindowsUpdate\AU"
 )
-
 foreach ($key in $wuRegistryKeys) {
-    Log ("`t{0}" -f $key)
     Get-Item -Path $key |
     Select-Object -ExpandProperty property |
     ForEach-Object {
-        Log ("`t`t{0} : {1}" -f $_, (Get-ItemProperty -Path $key -Name $_).$_)
+        Add-Property ("{0}\{1}" -f $key, $_) (Get-ItemProperty -Path $key -Name $_).$_
     }
 }
-Log ""
 
 if (Test-Path 'C:\Program Files\Docker\') {
-    Log "Docker Info"
-    $dockerVersion = (docker --version) | Out-String
-    Log ("Version: {0}" -f $dockerVersion)
-    Log "Images:"
-    LOG (docker images --format='{{json .}}' | ConvertFrom-Json | Format-Table Repository, Tag, ID)
-}
-Log ""
-
-Log "Cached Files:"
-$displayObjects = @()
-foreach ($file in [IO.Directory]::GetFiles('c:\akse-cache', '*', [IO.SearchOption]::AllDirectories))
-{
-    $attributes = Get-Item $file
-    $hash = Get-FileHash $file -Algorithm SHA256
-    $displayObjects += New-Object psobject -property @{
-        File = $file;
-        SizeBytes = $attributes.Length;
-        Sha256 = $hash.Hash
+    Add-Component "package" "docker" (docker version --format '{{.Server.Version}}')
+    foreach ($image in (docker images --format='{{json .}}' | ConvertFrom-Json)) {
+        Add-Component "containerImage" $image.Repository $image.Tag
     }
 }
 
-Log ($displayObjects | Format-Table -Property File, Sha256, SizeBytes | Out-String -Width 4096)
+foreach ($file in [IO.Directory]::GetFiles('c:\akse-cache', '*', [IO.SearchOption]::AllDirectories)) {
+    Add-Component "file" $file -Sha256 ((Get-FileHash $file -Algorithm SHA256).Hash)
+}
 
-# Ensure proper encoding is set for release notes file
-[IO.File]::ReadAllText($releaseNotesFilePath) | Out-File -Encoding utf8 $releaseNotesFilePath
+Add-Property "buildCompleted" ((Get-Date).ToUniversalTime().ToString("yyyy-MM-dd'T'HH:mm:ss'Z'"))